@@ -0,0 +1,159 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/tibuski/goAirfocus/airfocus"
+)
+
+// accessChangeRow is one planned or applied permission change as shown in the UI
+type accessChangeRow struct {
+	airfocus.AccessChange
+	UserName string // Name of the user whose permission changes
+	Error    string // Friendly error message if applying the change failed
+	Skipped  bool   // The change was not sent because a change it requires failed
+}
+
+// accessCopyPlan is a planned copy of one user's access onto another
+type accessCopyPlan struct {
+	client  *airfocus.Client
+	Source  airfocus.User
+	Target  airfocus.User
+	Changes []airfocus.AccessChange
+}
+
+// planAccessCopy reads the source and target users from the form and plans
+// the copy. It writes an error response and returns false on failure.
+func (s *Server) planAccessCopy(w http.ResponseWriter, r *http.Request) (accessCopyPlan, bool) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return accessCopyPlan{}, false
+	}
+
+	sourceID := r.FormValue("source_user_id")
+	targetID := r.FormValue("target_user_id")
+
+	if sourceID == "" || targetID == "" {
+		http.Error(w, "Source user and target user are required", http.StatusBadRequest)
+		return accessCopyPlan{}, false
+	}
+	if sourceID == targetID {
+		http.Error(w, "Source and target user must differ", http.StatusBadRequest)
+		return accessCopyPlan{}, false
+	}
+
+	client, ok := s.sessionClient(w, r)
+	if !ok {
+		return accessCopyPlan{}, false
+	}
+	plan := accessCopyPlan{client: client}
+	var err error
+	if plan.Source, err = plan.client.GetUser(r.Context(), sourceID); err != nil {
+		s.renderError(w, err, "Failed to retrieve source user")
+		return accessCopyPlan{}, false
+	}
+	if plan.Target, err = plan.client.GetUser(r.Context(), targetID); err != nil {
+		s.renderError(w, err, "Failed to retrieve target user")
+		return accessCopyPlan{}, false
+	}
+	if plan.Changes, err = plan.client.PlanAccessCopy(r.Context(), sourceID, targetID); err != nil {
+		s.renderError(w, err, "Failed to plan access copy")
+		return accessCopyPlan{}, false
+	}
+	return plan, true
+}
+
+// handleAccessCopyPreviewHTMX shows the permission changes a copy of access
+// from one user to another would make, without changing anything.
+func (s *Server) handleAccessCopyPreviewHTMX(w http.ResponseWriter, r *http.Request) {
+	plan, ok := s.planAccessCopy(w, r)
+	if !ok {
+		return
+	}
+
+	rows := make([]accessChangeRow, len(plan.Changes))
+	for i, change := range plan.Changes {
+		rows[i] = accessChangeRow{AccessChange: change, UserName: plan.Target.FullName}
+	}
+	s.renderAccessCopy(w, plan, rows, false)
+}
+
+// handleAccessCopyApplyHTMX copies access from one user to another and
+// reports the outcome of every change. The plan is computed again and
+// applying is refused if it differs from the previewed one.
+func (s *Server) handleAccessCopyApplyHTMX(w http.ResponseWriter, r *http.Request) {
+	plan, ok := s.planAccessCopy(w, r)
+	if !ok {
+		return
+	}
+	if r.FormValue("plan_digest") != planDigest(plan.Changes) {
+		s.renderError(w, errPlanChanged, "Access copy plan changed")
+		return
+	}
+
+	log.Printf("%s copying access from user %s to user %s: %d changes", s.actor(r), plan.Source.UserID, plan.Target.UserID, len(plan.Changes))
+
+	results := plan.client.ApplyAccessChanges(r.Context(), plan.Changes)
+	rows := make([]accessChangeRow, len(results))
+	for i, result := range results {
+		rows[i] = resultRow(result, plan.Target.FullName)
+	}
+	s.renderAccessCopy(w, plan, rows, true)
+}
+
+// resultRow turns the outcome of an access change into a row for display
+func resultRow(result airfocus.AccessChangeResult, userName string) accessChangeRow {
+	row := accessChangeRow{AccessChange: result.AccessChange, UserName: userName}
+	if result.Err != nil {
+		row.Skipped = errors.Is(result.Err, airfocus.ErrSkipped)
+		log.Printf("Access change on %s %s for user %s failed: %v", result.TargetType, result.TargetID, result.UserID, result.Err)
+		_, row.Error = errorResponse(result.Err, fmt.Sprintf("Failed to update %s", result.TargetType))
+	}
+	return row
+}
+
+// countFailed returns the number of rows whose change failed
+func countFailed(rows []accessChangeRow) int {
+	failed := 0
+	for _, row := range rows {
+		if row.Error != "" {
+			failed++
+		}
+	}
+	return failed
+}
+
+// planDigest identifies a planned set of access changes, such as an
+// offboarding plan, so that applying it can check that it is still the plan
+// the operator previewed
+func planDigest(plan interface{}) string {
+	data, err := json.Marshal(plan)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// renderAccessCopy renders the access copy preview or results
+func (s *Server) renderAccessCopy(w http.ResponseWriter, plan accessCopyPlan, rows []accessChangeRow, applied bool) {
+	data := map[string]interface{}{
+		"Source":   plan.Source,
+		"Target":   plan.Target,
+		"Rows":     rows,
+		"Applied":  applied,
+		"Failed":   countFailed(rows),
+		"ShowUser": false,
+		"Digest":   planDigest(plan.Changes),
+	}
+	if err := s.templates.ExecuteTemplate(w, "access_copy_partial.html", data); err != nil {
+		log.Printf("Error executing template: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	}
+}
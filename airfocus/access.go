@@ -0,0 +1,145 @@
+package airfocus
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Target types of an AccessChange
+const (
+	TargetWorkspace = "workspace"
+	TargetGroup     = "group"
+)
+
+// AccessChange describes a change to one user's explicit permission on a
+// workspace or workspace group
+type AccessChange struct {
+	UserID     string     `json:"userId"`     // User whose permission changes
+	TargetType string     `json:"targetType"` // TargetWorkspace or TargetGroup
+	TargetID   string     `json:"targetId"`   // ID of the workspace or group
+	TargetName string     `json:"targetName"` // Name of the workspace or group
+	From       Permission `json:"from"`       // Current explicit permission, empty if none
+	To         Permission `json:"to"`         // New explicit permission, empty to revoke
+
+	// Requires is a change earlier in the same list that must succeed first,
+	// e.g. handing "full" to a successor before the owner's "full" is revoked
+	Requires *AccessChange `json:"requires,omitempty"`
+}
+
+// AccessChangeResult is the outcome of applying a single AccessChange
+type AccessChangeResult struct {
+	AccessChange
+	Err error // nil if the change was applied
+}
+
+// PlanAccessCopy compares the explicit workspace and group permissions of
+// two users and returns the changes that give targetUserID at least the
+// access sourceUserID has. Permissions the target already holds at the same
+// or a higher level are left alone, so copying never downgrades anyone.
+// Nothing is changed; pass the plan to ApplyAccessChanges to carry it out.
+func (c *Client) PlanAccessCopy(ctx context.Context, sourceUserID, targetUserID string) ([]AccessChange, error) {
+	if sourceUserID == targetUserID {
+		return nil, fmt.Errorf("cannot copy access of user %s onto itself", sourceUserID)
+	}
+	for _, userID := range []string{sourceUserID, targetUserID} {
+		if _, err := c.GetUser(ctx, userID); err != nil {
+			return nil, err
+		}
+	}
+
+	groups, err := c.ListWorkspaceGroups(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list workspace groups: %w", err)
+	}
+	workspaces, err := c.ListWorkspaces(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list workspaces: %w", err)
+	}
+
+	var changes []AccessChange
+	for _, group := range groups {
+		if change, ok := copyChange(group.Embedded.Permissions, sourceUserID, targetUserID); ok {
+			change.TargetType, change.TargetID, change.TargetName = TargetGroup, group.ID, group.Name
+			changes = append(changes, change)
+		}
+	}
+	for _, ws := range workspaces {
+		if change, ok := copyChange(ws.Embedded.Permissions, sourceUserID, targetUserID); ok {
+			change.TargetType, change.TargetID, change.TargetName = TargetWorkspace, ws.ID, ws.Name
+			changes = append(changes, change)
+		}
+	}
+
+	sortAccessChanges(changes)
+	return changes, nil
+}
+
+// copyChange returns the change needed on one permissions map, if any
+func copyChange(permissions map[string]string, sourceUserID, targetUserID string) (AccessChange, bool) {
+	source, ok := permissions[sourceUserID]
+	if !ok {
+		return AccessChange{}, false
+	}
+	current := Permission(permissions[targetUserID])
+	if current.rank() >= Permission(source).rank() {
+		return AccessChange{}, false
+	}
+	return AccessChange{UserID: targetUserID, From: current, To: Permission(source)}, true
+}
+
+// ApplyAccessChanges carries out the changes one by one and reports the
+// outcome of each. A failed change does not stop the remaining ones, except
+// those that require it: they are skipped with an error wrapping ErrSkipped.
+func (c *Client) ApplyAccessChanges(ctx context.Context, changes []AccessChange) []AccessChangeResult {
+	results := make([]AccessChangeResult, len(changes))
+	applied := make(map[string]bool, len(changes))
+	for i, change := range changes {
+		var err error
+		if change.Requires != nil && !applied[change.Requires.key()] {
+			err = fmt.Errorf("%w: %s %s for user %s was not applied", ErrSkipped, change.Requires.TargetType, change.Requires.TargetName, change.Requires.UserID)
+		} else {
+			err = c.applyAccessChange(ctx, change)
+		}
+		if err == nil {
+			applied[change.key()] = true
+		}
+		results[i] = AccessChangeResult{AccessChange: change, Err: err}
+	}
+	return results
+}
+
+// key identifies the change within a list of changes
+func (change AccessChange) key() string {
+	return change.TargetType + "/" + change.TargetID + "/" + change.UserID + "/" + string(change.To)
+}
+
+// applyAccessChange carries out a single change
+func (c *Client) applyAccessChange(ctx context.Context, change AccessChange) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	switch {
+	case change.TargetType == TargetWorkspace && change.To == "":
+		return c.RemoveWorkspacePermission(ctx, change.TargetID, change.UserID)
+	case change.TargetType == TargetWorkspace:
+		return c.SetWorkspacePermission(ctx, change.TargetID, change.UserID, change.To)
+	case change.TargetType == TargetGroup && change.To == "":
+		return c.RemoveGroupPermission(ctx, change.TargetID, change.UserID)
+	case change.TargetType == TargetGroup:
+		return c.SetGroupPermission(ctx, change.TargetID, change.UserID, change.To)
+	default:
+		return fmt.Errorf("unknown target type %q", change.TargetType)
+	}
+}
+
+// sortAccessChanges orders changes with groups before workspaces, then by name
+func sortAccessChanges(changes []AccessChange) {
+	sort.SliceStable(changes, func(i, j int) bool {
+		if changes[i].TargetType != changes[j].TargetType {
+			return changes[i].TargetType == TargetGroup
+		}
+		return strings.ToLower(changes[i].TargetName) < strings.ToLower(changes[j].TargetName)
+	})
+}
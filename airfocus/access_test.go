@@ -0,0 +1,81 @@
+package airfocus_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/tibuski/goAirfocus/airfocus"
+)
+
+func TestPlanAccessCopy(t *testing.T) {
+	client, _ := newTestClient(t)
+
+	tests := []struct {
+		name   string
+		source string
+		target string
+		want   []airfocus.AccessChange
+	}{
+		{"grants and upgrades", "u-bob", "u-carol", []airfocus.AccessChange{
+			{UserID: "u-carol", TargetType: "group", TargetID: "g-product", TargetName: "Product", To: "write"},
+			{UserID: "u-carol", TargetType: "workspace", TargetID: "w-android", TargetName: "Android App", To: "write"},
+			{UserID: "u-carol", TargetType: "workspace", TargetID: "w-roadmap", TargetName: "Roadmap", From: "read", To: "write"},
+			{UserID: "u-carol", TargetType: "workspace", TargetID: "w-sandbox", TargetName: "Sandbox", To: "full"},
+		}},
+		{"never downgrades", "u-carol", "u-bob", []airfocus.AccessChange{
+			{UserID: "u-bob", TargetType: "group", TargetID: "g-mobile", TargetName: "Mobile", To: "comment"},
+			{UserID: "u-bob", TargetType: "workspace", TargetID: "w-ios", TargetName: "iOS App", To: "comment"},
+		}},
+		{"nothing to copy", "u-erin", "u-bob", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			changes, err := client.PlanAccessCopy(context.Background(), tt.source, tt.target)
+			if err != nil {
+				t.Fatalf("PlanAccessCopy: %v", err)
+			}
+			if !reflect.DeepEqual(changes, tt.want) {
+				t.Errorf("got %+v\nwant %+v", changes, tt.want)
+			}
+		})
+	}
+
+	if _, err := client.PlanAccessCopy(context.Background(), "u-bob", "u-nobody"); !errors.Is(err, airfocus.ErrNotFound) {
+		t.Errorf("unknown target: error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestApplyAccessChanges(t *testing.T) {
+	client, _ := newTestClient(t)
+	ctx := context.Background()
+
+	changes, err := client.PlanAccessCopy(ctx, "u-bob", "u-carol")
+	if err != nil {
+		t.Fatalf("PlanAccessCopy: %v", err)
+	}
+	// A change on a workspace that no longer exists must fail on its own
+	changes = append(changes, airfocus.AccessChange{UserID: "u-carol", TargetType: "workspace", TargetID: "w-deleted", To: "read"})
+
+	results := client.ApplyAccessChanges(ctx, changes)
+	if len(results) != len(changes) {
+		t.Fatalf("got %d results, want %d", len(results), len(changes))
+	}
+	for _, result := range results[:len(results)-1] {
+		if result.Err != nil {
+			t.Errorf("change on %s failed: %v", result.TargetID, result.Err)
+		}
+	}
+	if err := results[len(results)-1].Err; !errors.Is(err, airfocus.ErrNotFound) {
+		t.Errorf("change on deleted workspace: error = %v, want ErrNotFound", err)
+	}
+
+	again, err := client.PlanAccessCopy(ctx, "u-bob", "u-carol")
+	if err != nil {
+		t.Fatalf("PlanAccessCopy: %v", err)
+	}
+	if len(again) != 0 {
+		t.Errorf("plan after applying still has changes: %+v", again)
+	}
+}
@@ -1,12 +1,11 @@
 package airfocus
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
-	"io"
 	"net/http"
+	"net/url"
 	"sort"
 	"strings"
 	"sync"
@@ -14,13 +13,23 @@ import (
 )
 
 const (
-	baseURL = "https://app.airfocus.com/api"
+	// DefaultBaseURL is the Airfocus API endpoint used when no WithBaseURL option is given
+	DefaultBaseURL = "https://app.airfocus.com/api"
+
+	// DefaultCacheTTL is how long fetched data is cached when no WithCacheTTL option is given
+	DefaultCacheTTL = 5 * time.Minute
 )
 
 // Client represents an Airfocus API client
 type Client struct {
 	apiKey     string       // The API key used for authentication
+	baseURL    string       // Base URL of the Airfocus API, without trailing slash
 	httpClient *http.Client // HTTP client for making requests
+	pageSize   int          // Number of items requested per page from search endpoints
+
+	maxRetries     int           // Number of retries for failed requests
+	retryBaseDelay time.Duration // Initial backoff delay between retries
+	limiter        *rateLimiter  // Client-side request rate limit, nil for none
 
 	// Cache fields for storing frequently accessed data
 	cache struct {
@@ -29,18 +38,75 @@ type Client struct {
 		fields          []FieldWithWorkspaceNames // Cached list of fields
 		workspaceGroups []WorkspaceGroup          // Cached list of workspace groups
 		lastUpdate      time.Time                 // Timestamp of last cache update
+		incomplete      []string                  // Lists whose last fetch returned fewer items than reported
+		team            *TeamLicenseInfo          // Cached team license information
+		teamUpdate      time.Time                 // Timestamp of last team update
+		staleSince      time.Time                 // When the served data was fetched, if it is fallback data after a failed refresh
 	}
 	cacheMutex sync.RWMutex  // Mutex for thread-safe cache access
 	cacheTTL   time.Duration // Time-to-live for cached data
+	fallback   FallbackFunc  // Source of data when a refresh fails, nil for none
+}
+
+// Option configures optional Client settings
+type Option func(*Client)
+
+// WithBaseURL overrides the Airfocus API base URL, e.g. for an EU tenant, a proxy or a test server
+func WithBaseURL(baseURL string) Option {
+	return func(c *Client) {
+		if baseURL != "" {
+			c.baseURL = strings.TrimRight(baseURL, "/")
+		}
+	}
+}
+
+// WithHTTPClient sets the HTTP client used for all API requests
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		if httpClient != nil {
+			c.httpClient = httpClient
+		}
+	}
+}
+
+// WithCacheTTL sets how long fetched users, workspaces, fields and groups are cached
+func WithCacheTTL(ttl time.Duration) Option {
+	return func(c *Client) {
+		c.cacheTTL = ttl
+	}
+}
+
+// WithPageSize sets the number of items requested per page from search endpoints
+func WithPageSize(pageSize int) Option {
+	return func(c *Client) {
+		if pageSize > 0 {
+			c.pageSize = pageSize
+		}
+	}
 }
 
-// NewClient creates a new Airfocus API client with the given API key
-func NewClient(apiKey string) *Client {
-	return &Client{
+// NewClient creates a new Airfocus API client with the given API key and options
+func NewClient(apiKey string, opts ...Option) *Client {
+	c := &Client{
 		apiKey:     apiKey,
+		baseURL:    DefaultBaseURL,
 		httpClient: &http.Client{},
-		cacheTTL:   5 * time.Minute, // Cache data for 5 minutes
+		pageSize:   DefaultPageSize,
+		cacheTTL:   DefaultCacheTTL,
+
+		maxRetries:     DefaultMaxRetries,
+		retryBaseDelay: DefaultRetryBaseDelay,
+		limiter:        newRateLimiter(DefaultRequestsPerSecond, DefaultRateLimitBurst),
+	}
+	for _, opt := range opts {
+		opt(c)
 	}
+	return c
+}
+
+// BaseURL returns the Airfocus API base URL used by this client
+func (c *Client) BaseURL() string {
+	return c.baseURL
 }
 
 // --- Workspace Search Query Structs ---
@@ -87,7 +153,8 @@ type WorkspaceGroup struct {
 		Workspaces  []Workspace       `json:"workspaces,omitempty"`  // List of workspaces in this group
 		Permissions map[string]string `json:"permissions,omitempty"` // Map of user IDs to their permissions for the group
 	} `json:"_embedded,omitempty"`
-	CurrentPermission Permission `json:"currentPermission,omitempty"` // Current user's permission for this group
+	CurrentPermission Permission         `json:"currentPermission,omitempty"` // Current user's permission for this group
+	PermissionTrace   []PermissionSource `json:"permissionTrace,omitempty"`   // How CurrentPermission was derived
 }
 
 // WorkspaceGroupSearchQuery represents the query parameters for workspace group search
@@ -171,11 +238,14 @@ type Workspace struct {
 	Order     int    `json:"order"`     // Display order
 	TeamID    string `json:"teamId"`    // ID of the team this workspace belongs to
 	Embedded  struct {
-		Permissions map[string]string `json:"permissions"` // Map of user IDs to their permissions
+		Permissions map[string]string `json:"permissions"`        // Map of user IDs to their permissions
+		Statuses    []ItemStatus      `json:"statuses,omitempty"` // Item statuses available in the workspace
 	} `json:"_embedded,omitempty"`
-	GroupID           string     `json:"groupId,omitempty"`           // ID of the group this workspace belongs to
-	GroupName         string     `json:"groupName,omitempty"`         // Name of the group this workspace belongs to
-	CurrentPermission Permission `json:"currentPermission,omitempty"` // Current user's permission for this workspace
+	GroupID           string             `json:"groupId,omitempty"`           // ID of the group this workspace belongs to
+	GroupName         string             `json:"groupName,omitempty"`         // Name of the group this workspace belongs to
+	DefaultPermission string             `json:"defaultPermission,omitempty"` // Permission every team member has on the workspace, if any
+	CurrentPermission Permission         `json:"currentPermission,omitempty"` // Current user's permission for this workspace
+	PermissionTrace   []PermissionSource `json:"permissionTrace,omitempty"`   // How CurrentPermission was derived
 }
 
 // WorkspaceResponse represents the response from the workspace search API
@@ -212,37 +282,13 @@ func (c *Client) GetWorkspaceIDByName(ctx context.Context, name string) (Workspa
 		},
 	}
 
-	jsonData, err := json.Marshal(query)
-	if err != nil {
-		return WorkspaceResult{}, fmt.Errorf("failed to marshal workspace search query: %w", err)
-	}
-
-	req, err := http.NewRequestWithContext(ctx, "POST", baseURL+"/workspaces/search", bytes.NewBuffer(jsonData))
-	if err != nil {
-		return WorkspaceResult{}, fmt.Errorf("failed to create workspace search request: %w", err)
-	}
-
-	req.Header.Set("Authorization", "Bearer "+c.apiKey)
-	req.Header.Set("Content-Type", "application/json")
-
-	resp, err := c.httpClient.Do(req)
-	if err != nil {
-		return WorkspaceResult{}, fmt.Errorf("failed to send workspace search request: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return WorkspaceResult{}, fmt.Errorf("airfocus API workspace search failed with status %d: %s", resp.StatusCode, string(body))
-	}
-
 	var result WorkspaceResponse
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return WorkspaceResult{}, fmt.Errorf("failed to decode workspace search response: %w", err)
+	if err := c.doJSON(ctx, "POST", "/workspaces/search", query, &result); err != nil {
+		return WorkspaceResult{}, fmt.Errorf("failed to search workspaces: %w", err)
 	}
 
 	if len(result.Items) == 0 {
-		return WorkspaceResult{}, fmt.Errorf("no workspace found with name: %s", name)
+		return WorkspaceResult{}, fmt.Errorf("no workspace found with name %s: %w", name, ErrNotFound)
 	}
 
 	// Return both ID and alias of the first matching workspace
@@ -260,13 +306,14 @@ type FieldSearchQuery struct {
 
 // Field represents an Airfocus field
 type Field struct {
-	ID          string `json:"id"`          // Unique identifier for the field
-	Name        string `json:"name"`        // Field name
-	Description string `json:"description"` // Field description
-	Type        string `json:"type"`        // Field type
-	CreatedAt   string `json:"createdAt"`   // Creation timestamp
-	UpdatedAt   string `json:"updatedAt"`   // Last update timestamp
-	IsTeamField bool   `json:"isTeamField"` // Whether this is a team-wide field
+	ID          string          `json:"id"`                 // Unique identifier for the field
+	Name        string          `json:"name"`               // Field name
+	Description string          `json:"description"`        // Field description
+	Type        FieldType       `json:"type"`               // Field type
+	Settings    json.RawMessage `json:"settings,omitempty"` // Type-specific configuration, see TypedSettings
+	CreatedAt   string          `json:"createdAt"`          // Creation timestamp
+	UpdatedAt   string          `json:"updatedAt"`          // Last update timestamp
+	IsTeamField bool            `json:"isTeamField"`        // Whether this is a team-wide field
 	Embedded    struct {
 		Workspaces []struct {
 			WorkspaceID string `json:"workspaceId"` // ID of the workspace this field belongs to
@@ -295,27 +342,9 @@ type FieldSearchResponse struct {
 
 // GetWorkspaceByID retrieves a workspace by its ID
 func (c *Client) GetWorkspaceByID(ctx context.Context, workspaceID string) (Workspace, error) {
-	req, err := http.NewRequestWithContext(ctx, "GET", fmt.Sprintf("%s/workspaces/%s", baseURL, workspaceID), nil)
-	if err != nil {
-		return Workspace{}, fmt.Errorf("failed to create get workspace by ID request: %w", err)
-	}
-
-	req.Header.Set("Authorization", "Bearer "+c.apiKey)
-
-	resp, err := c.httpClient.Do(req)
-	if err != nil {
-		return Workspace{}, fmt.Errorf("failed to send get workspace by ID request: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return Workspace{}, fmt.Errorf("airfocus API get workspace by ID failed with status %d: %s", resp.StatusCode, string(body))
-	}
-
 	var workspace Workspace
-	if err := json.NewDecoder(resp.Body).Decode(&workspace); err != nil {
-		return Workspace{}, fmt.Errorf("failed to decode get workspace by ID response: %w", err)
+	if err := c.doJSON(ctx, "GET", "/workspaces/"+url.PathEscape(workspaceID), nil, &workspace); err != nil {
+		return Workspace{}, fmt.Errorf("failed to get workspace by ID: %w", err)
 	}
 
 	return workspace, nil
@@ -499,6 +528,14 @@ func (c *Client) GetWorkspaceUsers(ctx context.Context, workspaceID string) ([]W
 		}
 	}
 
+	// Sort by name so callers get a stable order regardless of map iteration
+	sort.Slice(workspaceUsers, func(i, j int) bool {
+		if workspaceUsers[i].FullName != workspaceUsers[j].FullName {
+			return strings.ToLower(workspaceUsers[i].FullName) < strings.ToLower(workspaceUsers[j].FullName)
+		}
+		return workspaceUsers[i].UserID < workspaceUsers[j].UserID
+	})
+
 	return workspaceUsers, nil
 }
 
@@ -578,39 +615,8 @@ func (c *Client) GetUserWorkspaces(ctx context.Context, userID string) ([]UserWo
 		return nil, fmt.Errorf("failed to get workspace groups: %w", err)
 	}
 
-	// Create a map of group IDs to their names for quick lookup
-	groupMap := make(map[string]string)
-	for _, group := range groups {
-		groupMap[group.ID] = group.Name
-	}
-
-	// Create a map of group IDs to their parent IDs for path construction
-	groupParentMap := make(map[string]string)
-	for _, group := range groups {
-		if group.ParentID != "" {
-			groupParentMap[group.ID] = group.ParentID
-		}
-	}
-
-	// Helper function to get the full group path
-	getFullGroupPath := func(groupID string) string {
-		if groupID == "" {
-			return ""
-		}
-
-		var path []string
-		currentID := groupID
-		for currentID != "" {
-			if name, ok := groupMap[currentID]; ok {
-				path = append([]string{name}, path...)
-				currentID = groupParentMap[currentID]
-			} else {
-				break
-			}
-		}
-
-		return strings.Join(path, " > ")
-	}
+	// Resolve the full path of every group once
+	paths := groupPaths(groups)
 
 	var userWorkspaces []UserWorkspaceAccess
 
@@ -619,7 +625,7 @@ func (c *Client) GetUserWorkspaces(ctx context.Context, userID string) ([]UserWo
 		// Check if the user ID exists in the workspace's permissions map
 		if permission, ok := workspace.Embedded.Permissions[userID]; ok {
 			// Get the full group path for this workspace
-			groupPath := getFullGroupPath(workspace.GroupID)
+			groupPath := paths[workspace.GroupID]
 
 			// User has a specific permission for this workspace
 			userWorkspaces = append(userWorkspaces, UserWorkspaceAccess{
@@ -636,6 +642,31 @@ func (c *Client) GetUserWorkspaces(ctx context.Context, userID string) ([]UserWo
 	return userWorkspaces, nil
 }
 
+// groupPaths returns the full path of every group (e.g., "Parent Group > Child Group"), keyed by group ID
+func groupPaths(groups []WorkspaceGroup) map[string]string {
+	groupMap := make(map[string]WorkspaceGroup, len(groups))
+	for _, group := range groups {
+		groupMap[group.ID] = group
+	}
+
+	paths := make(map[string]string, len(groups))
+	for _, group := range groups {
+		var path []string
+		visited := make(map[string]bool)
+		for currentID := group.ID; currentID != "" && !visited[currentID]; {
+			current, ok := groupMap[currentID]
+			if !ok {
+				break
+			}
+			visited[currentID] = true
+			path = append([]string{current.Name}, path...)
+			currentID = current.ParentID
+		}
+		paths[group.ID] = strings.Join(path, " > ")
+	}
+	return paths
+}
+
 // WorkspaceUserStats represents statistics about users in a workspace
 type WorkspaceUserStats struct {
 	TotalUsers   int `json:"totalUsers"`   // Total number of users
@@ -668,8 +699,8 @@ func (c *Client) GetWorkspaceUserStats(ctx context.Context, workspaceID string)
 	return stats, nil
 }
 
-// fetchWorkspaceGroups retrieves and caches the list of workspace groups
-func (c *Client) fetchWorkspaceGroups(ctx context.Context) ([]WorkspaceGroup, error) {
+// fetchWorkspaceGroups retrieves all pages of workspace groups
+func (c *Client) fetchWorkspaceGroups(ctx context.Context) ([]WorkspaceGroup, bool, error) {
 	query := WorkspaceGroupSearchQuery{
 		Sort: struct {
 			Type      string `json:"type"`
@@ -680,36 +711,13 @@ func (c *Client) fetchWorkspaceGroups(ctx context.Context) ([]WorkspaceGroup, er
 		},
 	}
 
-	queryJSON, err := json.Marshal(query)
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal workspace group search query: %w", err)
-	}
-
-	req, err := http.NewRequestWithContext(ctx, "POST", baseURL+"/workspaces/groups/search", bytes.NewBuffer(queryJSON))
-	if err != nil {
-		return nil, fmt.Errorf("failed to create workspace group search request: %w", err)
-	}
-
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+c.apiKey)
-
-	resp, err := c.httpClient.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("failed to send workspace group search request: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("airfocus API workspace group search failed with status %d: %s", resp.StatusCode, string(body))
-	}
-
-	var result WorkspaceGroupResponse
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return nil, fmt.Errorf("failed to decode workspace group search response: %w", err)
-	}
-
-	return result.Items, nil
+	return paginate(ctx, c.pageSize, func(g WorkspaceGroup) string { return g.ID }, func(ctx context.Context, offset, limit int) ([]WorkspaceGroup, int, error) {
+		var result WorkspaceGroupResponse
+		if err := c.doJSON(ctx, "POST", pagedURL("/workspaces/groups/search", offset, limit), query, &result); err != nil {
+			return nil, 0, fmt.Errorf("failed to search workspace groups: %w", err)
+		}
+		return result.Items, result.TotalItems, nil
+	})
 }
 
 // RefreshCacheIfNeeded checks if the cache is stale and refreshes it if necessary.
@@ -731,56 +739,56 @@ func (c *Client) RefreshCacheIfNeeded(ctx context.Context) error {
 		return nil
 	}
 
-	// Fetch all data in parallel
-	var wg sync.WaitGroup
-	var errChan = make(chan error, 4) // Increased to 4 for workspace groups
+	// Fetch all data in parallel into locals, so a failed refresh leaves the previous cache intact
+	var (
+		wg         sync.WaitGroup
+		errChan    = make(chan error, 4) // One slot per fetch
+		users      []User
+		workspaces []Workspace
+		fields     []Field
+		groups     []WorkspaceGroup
+
+		workspacesComplete, fieldsComplete, groupsComplete bool
+	)
 
 	// Fetch users
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
-		users, err := c.fetchUsers(ctx)
-		if err != nil {
+		var err error
+		if users, err = c.fetchUsers(ctx); err != nil {
 			errChan <- fmt.Errorf("failed to fetch users: %w", err)
-			return
 		}
-		c.cache.users = users
 	}()
 
 	// Fetch workspaces
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
-		workspaces, err := c.fetchWorkspaces(ctx)
-		if err != nil {
+		var err error
+		if workspaces, workspacesComplete, err = c.fetchWorkspaces(ctx); err != nil {
 			errChan <- fmt.Errorf("failed to fetch workspaces: %w", err)
-			return
 		}
-		c.cache.workspaces = workspaces
 	}()
 
 	// Fetch fields
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
-		fields, err := c.fetchFields(ctx)
-		if err != nil {
+		var err error
+		if fields, fieldsComplete, err = c.fetchFields(ctx); err != nil {
 			errChan <- fmt.Errorf("failed to fetch fields: %w", err)
-			return
 		}
-		c.cache.fields = fields
 	}()
 
 	// Fetch workspace groups
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
-		groups, err := c.fetchWorkspaceGroups(ctx)
-		if err != nil {
+		var err error
+		if groups, groupsComplete, err = c.fetchWorkspaceGroups(ctx); err != nil {
 			errChan <- fmt.Errorf("failed to fetch workspace groups: %w", err)
-			return
 		}
-		c.cache.workspaceGroups = groups
 	}()
 
 	// Wait for all fetches to complete
@@ -790,42 +798,58 @@ func (c *Client) RefreshCacheIfNeeded(ctx context.Context) error {
 	// Check for any errors
 	for err := range errChan {
 		if err != nil {
+			if c.useFallbackLocked(ctx, err) {
+				return nil
+			}
 			return err
 		}
 	}
 
+	// Remember which lists came back shorter than the API's reported total
+	var incomplete []string
+	if !workspacesComplete {
+		incomplete = append(incomplete, "workspaces")
+	}
+	if !fieldsComplete {
+		incomplete = append(incomplete, "fields")
+	}
+	if !groupsComplete {
+		incomplete = append(incomplete, "workspace groups")
+	}
+
+	c.cache.users = users
+	c.cache.workspaces = workspaces
+	c.cache.fields = withWorkspaceNames(fields, workspaces)
+	c.cache.workspaceGroups = groups
+	c.cache.incomplete = incomplete
 	c.cache.lastUpdate = time.Now()
+	c.cache.staleSince = time.Time{}
 	return nil
 }
 
-// fetchUsers retrieves and caches the list of users
-func (c *Client) fetchUsers(ctx context.Context) ([]User, error) {
-	req, err := http.NewRequestWithContext(ctx, "GET", baseURL+"/team/users", nil)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create list users request: %w", err)
-	}
-	req.Header.Set("Authorization", "Bearer "+c.apiKey)
-
-	resp, err := c.httpClient.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("failed to send list users request: %w", err)
-	}
-	defer resp.Body.Close()
+// IncompleteResults returns the names of the cached lists ("workspaces",
+// "fields", "workspace groups") whose last fetch returned fewer items than
+// the API reported, so callers can warn that a result may be truncated.
+func (c *Client) IncompleteResults() []string {
+	c.cacheMutex.RLock()
+	defer c.cacheMutex.RUnlock()
 
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("airfocus API list users failed with status %d: %s", resp.StatusCode, string(body))
-	}
+	incomplete := make([]string, len(c.cache.incomplete))
+	copy(incomplete, c.cache.incomplete)
+	return incomplete
+}
 
+// fetchUsers retrieves and caches the list of users
+func (c *Client) fetchUsers(ctx context.Context) ([]User, error) {
 	var users []User
-	if err := json.NewDecoder(resp.Body).Decode(&users); err != nil {
-		return nil, fmt.Errorf("failed to decode list users response: %w", err)
+	if err := c.doJSON(ctx, "GET", "/team/users", nil, &users); err != nil {
+		return nil, fmt.Errorf("failed to list users: %w", err)
 	}
 	return users, nil
 }
 
-// fetchWorkspaces retrieves and caches the list of workspaces
-func (c *Client) fetchWorkspaces(ctx context.Context) ([]Workspace, error) {
+// fetchWorkspaces retrieves all pages of workspaces
+func (c *Client) fetchWorkspaces(ctx context.Context) ([]Workspace, bool, error) {
 	query := WorkspaceSearchQuery{
 		Sort: WorkspaceSearchSort{
 			Type: "name",
@@ -837,93 +861,40 @@ func (c *Client) fetchWorkspaces(ctx context.Context) ([]Workspace, error) {
 		Filter:   nil,
 	}
 
-	reqBody, err := json.Marshal(query)
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal list workspaces query: %w", err)
-	}
-
-	req, err := http.NewRequestWithContext(ctx, "POST", baseURL+"/workspaces/search", bytes.NewBuffer(reqBody))
-	if err != nil {
-		return nil, fmt.Errorf("failed to create list workspaces request: %w", err)
-	}
-
-	req.Header.Set("Authorization", "Bearer "+c.apiKey)
-	req.Header.Set("Content-Type", "application/json")
-
-	resp, err := c.httpClient.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("failed to send list workspaces request: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("airfocus API list workspaces failed with status %d: %s", resp.StatusCode, string(body))
-	}
-
-	var result WorkspaceResponse
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return nil, fmt.Errorf("failed to decode list workspaces response: %w", err)
-	}
-
-	return result.Items, nil
-}
-
-// fetchFields retrieves and caches the list of fields
-func (c *Client) fetchFields(ctx context.Context) ([]FieldWithWorkspaceNames, error) {
-	// Create a map of workspace IDs to names for quick lookup
-	workspaceMap := make(map[string]string)
-
-	// Check if we have workspaces in cache, if not fetch them directly
-	if len(c.cache.workspaces) == 0 {
-		workspaces, err := c.fetchWorkspaces(ctx)
-		if err != nil {
-			return nil, fmt.Errorf("failed to fetch workspaces for field mapping: %w", err)
-		}
-		for _, ws := range workspaces {
-			workspaceMap[ws.ID] = ws.Name
-		}
-	} else {
-		for _, ws := range c.cache.workspaces {
-			workspaceMap[ws.ID] = ws.Name
+	return paginate(ctx, c.pageSize, func(ws Workspace) string { return ws.ID }, func(ctx context.Context, offset, limit int) ([]Workspace, int, error) {
+		var result WorkspaceResponse
+		if err := c.doJSON(ctx, "POST", pagedURL("/workspaces/search", offset, limit), query, &result); err != nil {
+			return nil, 0, fmt.Errorf("failed to list workspaces: %w", err)
 		}
-	}
+		return result.Items, result.TotalItems, nil
+	})
+}
 
+// fetchFields retrieves all pages of fields
+func (c *Client) fetchFields(ctx context.Context) ([]Field, bool, error) {
 	query := FieldSearchQuery{
 		WorkspaceIDs: nil,
 	}
 
-	queryJSON, err := json.Marshal(query)
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal field search query: %w", err)
-	}
-
-	req, err := http.NewRequestWithContext(ctx, "POST", baseURL+"/fields/search", bytes.NewBuffer(queryJSON))
-	if err != nil {
-		return nil, fmt.Errorf("failed to create field search request: %w", err)
-	}
-
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+c.apiKey)
-
-	resp, err := c.httpClient.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("failed to send field search request: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("airfocus API field search request failed with status: %d, body: %s", resp.StatusCode, string(body))
-	}
+	return paginate(ctx, c.pageSize, func(f Field) string { return f.ID }, func(ctx context.Context, offset, limit int) ([]Field, int, error) {
+		var searchResp FieldSearchResponse
+		if err := c.doJSON(ctx, "POST", pagedURL("/fields/search", offset, limit), query, &searchResp); err != nil {
+			return nil, 0, fmt.Errorf("failed to search fields: %w", err)
+		}
+		return searchResp.Items, searchResp.TotalItems, nil
+	})
+}
 
-	var searchResp FieldSearchResponse
-	if err := json.NewDecoder(resp.Body).Decode(&searchResp); err != nil {
-		return nil, fmt.Errorf("failed to decode field search response: %w", err)
+// withWorkspaceNames resolves the names of the workspaces each field belongs to
+func withWorkspaceNames(fields []Field, workspaces []Workspace) []FieldWithWorkspaceNames {
+	// Create a map of workspace IDs to names for quick lookup
+	workspaceMap := make(map[string]string)
+	for _, ws := range workspaces {
+		workspaceMap[ws.ID] = ws.Name
 	}
 
-	fieldsWithNames := make([]FieldWithWorkspaceNames, len(searchResp.Items))
-	for i, field := range searchResp.Items {
+	fieldsWithNames := make([]FieldWithWorkspaceNames, len(fields))
+	for i, field := range fields {
 		fieldsWithNames[i] = FieldWithWorkspaceNames{
 			Field: field,
 		}
@@ -949,13 +920,14 @@ func (c *Client) fetchFields(ctx context.Context) ([]FieldWithWorkspaceNames, er
 		}
 	}
 
-	return fieldsWithNames, nil
+	return fieldsWithNames
 }
 
 // Permission represents the access level to a workspace or workspace group.
 type Permission string
 
 const (
+	PermissionNone    Permission = "none" // No access; never sent to the API
 	PermissionRead    Permission = "read"
 	PermissionComment Permission = "comment"
 	PermissionWrite   Permission = "write"
@@ -977,12 +949,15 @@ func (c *Client) GetUser(ctx context.Context, userID string) (User, error) {
 			return user, nil
 		}
 	}
-	return User{}, fmt.Errorf("user with ID %s not found", userID)
+	return User{}, fmt.Errorf("user with ID %s: %w", userID, ErrNotFound)
 }
 
 // GetUserGroupAccess retrieves workspace groups the user has access to,
-// populating their current permission and embedding relevant workspaces with user permissions.
-// This function properly handles hierarchical group permissions.
+// populating their current permission and embedding the workspaces the user
+// can access with their permissions. Groups the user cannot access are only
+// included when they hold an accessible workspace, with PermissionNone.
+// Permissions are resolved with EffectivePermission and EffectiveGroupPermission;
+// PermissionTrace records how each was derived.
 func (c *Client) GetUserGroupAccess(ctx context.Context, userID string) ([]WorkspaceGroup, error) {
 	// Fetch all workspace groups
 	groups, err := c.ListWorkspaceGroups(ctx)
@@ -996,102 +971,31 @@ func (c *Client) GetUserGroupAccess(ctx context.Context, userID string) ([]Works
 		return nil, fmt.Errorf("failed to list all workspaces: %w", err)
 	}
 
-	// Create maps for quick lookup
-	groupMap := make(map[string]WorkspaceGroup)
-	groupParentMap := make(map[string]string)
-	workspacesByGroupID := make(map[string][]Workspace)
-
-	// Build lookup maps
-	for _, group := range groups {
-		groupMap[group.ID] = group
-		if group.ParentID != "" {
-			groupParentMap[group.ID] = group.ParentID
-		}
-	}
-
 	// Group workspaces by their GroupID
+	workspacesByGroupID := make(map[string][]Workspace)
 	for _, ws := range allWorkspaces {
 		workspacesByGroupID[ws.GroupID] = append(workspacesByGroupID[ws.GroupID], ws)
 	}
 
-	// Helper function to get the highest permission from a set of permissions
-	getHighestPermission := func(currentPerm Permission, newPerm Permission) Permission {
-		pOrder := map[Permission]int{
-			PermissionRead:    1,
-			PermissionComment: 2,
-			PermissionWrite:   3,
-			PermissionFull:    4,
-		}
-		if pOrder[newPerm] > pOrder[currentPerm] {
-			return newPerm
-		}
-		return currentPerm
-	}
-
-	// Helper function to calculate effective permission for a group
-	calculateGroupPermission := func(groupID string) Permission {
-		effectivePermission := PermissionRead // Start with lowest permission
-
-		// Traverse up the group hierarchy
-		currentGroupID := groupID
-		for currentGroupID != "" {
-			if group, ok := groupMap[currentGroupID]; ok {
-				// Check explicit permission for the user in this group
-				if permStr, ok := group.Embedded.Permissions[userID]; ok {
-					effectivePermission = getHighestPermission(effectivePermission, Permission(permStr))
-				}
-				// Check default team permission for this group
-				if group.DefaultPermission != "" {
-					effectivePermission = getHighestPermission(effectivePermission, Permission(group.DefaultPermission))
-				}
-				currentGroupID = groupParentMap[currentGroupID] // Move up the hierarchy
-			} else {
-				break // Group not found, stop traversing
-			}
-		}
-
-		return effectivePermission
-	}
-
-	// Helper function to calculate effective permission for a workspace
-	calculateWorkspacePermission := func(workspace Workspace) Permission {
-		effectivePermission := PermissionRead // Start with lowest permission
-
-		// 1. Check explicit permission for the specific user in the workspace settings
-		if permStr, ok := workspace.Embedded.Permissions[userID]; ok {
-			effectivePermission = getHighestPermission(effectivePermission, Permission(permStr))
-		}
-
-		// 2. Check group hierarchy permissions (if workspace belongs to a group)
-		if workspace.GroupID != "" {
-			groupPermission := calculateGroupPermission(workspace.GroupID)
-			effectivePermission = getHighestPermission(effectivePermission, groupPermission)
-		}
-
-		return effectivePermission
-	}
-
 	var userAccessibleGroups []WorkspaceGroup
 
 	// Process each group
 	for _, group := range groups {
 		// Calculate the user's effective permission for this group
-		groupPermission := calculateGroupPermission(group.ID)
-
-		// Only include groups the user has some permission for
-		if groupPermission != "" {
-			group.CurrentPermission = groupPermission
-
-			// Populate workspaces for this group
-			if wsList, ok := workspacesByGroupID[group.ID]; ok {
-				var workspacesWithUserPermissions []Workspace
-				for _, ws := range wsList {
-					ws.CurrentPermission = calculateWorkspacePermission(ws)
-					workspacesWithUserPermissions = append(workspacesWithUserPermissions, ws)
-				}
-				group.Embedded.Workspaces = workspacesWithUserPermissions
+		group.CurrentPermission, group.PermissionTrace = EffectiveGroupPermission(userID, group.ID, groups)
+
+		// Populate the workspaces of this group the user can access
+		var accessibleWorkspaces []Workspace
+		for _, ws := range workspacesByGroupID[group.ID] {
+			ws.CurrentPermission, ws.PermissionTrace = EffectivePermission(userID, ws, groups)
+			if ws.CurrentPermission != PermissionNone {
+				accessibleWorkspaces = append(accessibleWorkspaces, ws)
 			}
+		}
+		group.Embedded.Workspaces = accessibleWorkspaces
 
+		// Only include groups the user has access to, or that hold a workspace the user has access to
+		if group.CurrentPermission != PermissionNone || len(accessibleWorkspaces) > 0 {
 			userAccessibleGroups = append(userAccessibleGroups, group)
 		}
 	}
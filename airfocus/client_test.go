@@ -0,0 +1,214 @@
+package airfocus_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/tibuski/goAirfocus/airfocus"
+	"github.com/tibuski/goAirfocus/airfocus/fake"
+)
+
+// newTestClient starts a fake API with the default fixtures and returns a client pointed at it
+func newTestClient(t *testing.T) (*airfocus.Client, *fake.Server) {
+	t.Helper()
+	srv := fake.NewServer(fake.DefaultFixtures())
+	t.Cleanup(srv.Close)
+	return airfocus.NewClient(fake.APIKey, airfocus.WithBaseURL(srv.URL)), srv
+}
+
+func TestListWorkspacesGroupMapping(t *testing.T) {
+	client, _ := newTestClient(t)
+
+	workspaces, err := client.ListWorkspaces(context.Background())
+	if err != nil {
+		t.Fatalf("ListWorkspaces: %v", err)
+	}
+
+	got := make(map[string][2]string)
+	for _, ws := range workspaces {
+		got[ws.ID] = [2]string{ws.GroupID, ws.GroupName}
+	}
+
+	tests := []struct {
+		name        string
+		workspaceID string
+		wantGroupID string
+		wantGroup   string
+	}{
+		{"group name already set", "w-roadmap", "g-product", "Product"},
+		{"group name looked up from group ID", "w-budget", "g-finance", "Finance"},
+		{"nested group", "w-ios", "g-mobile", "Mobile"},
+		{"group taken from embedded group workspaces", "w-android", "g-mobile", "Mobile"},
+		{"ungrouped", "w-sandbox", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g, ok := got[tt.workspaceID]
+			if !ok {
+				t.Fatalf("workspace %s not listed", tt.workspaceID)
+			}
+			if g[0] != tt.wantGroupID || g[1] != tt.wantGroup {
+				t.Errorf("group = (%q, %q), want (%q, %q)", g[0], g[1], tt.wantGroupID, tt.wantGroup)
+			}
+		})
+	}
+}
+
+func TestGetUserWorkspaces(t *testing.T) {
+	client, _ := newTestClient(t)
+
+	type access struct {
+		permission string
+		groupPath  string
+	}
+	tests := []struct {
+		userID string
+		want   map[string]access
+	}{
+		{"u-alice", map[string]access{
+			"w-budget":  {"full", "Finance"},
+			"w-ios":     {"full", "Product > Mobile"},
+			"w-roadmap": {"full", "Product"},
+		}},
+		{"u-bob", map[string]access{
+			"w-android": {"write", "Product > Mobile"},
+			"w-roadmap": {"write", "Product"},
+			"w-sandbox": {"full", ""},
+		}},
+		{"u-carol", map[string]access{
+			"w-ios":     {"comment", "Product > Mobile"},
+			"w-roadmap": {"read", "Product"},
+		}},
+		{"u-erin", map[string]access{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.userID, func(t *testing.T) {
+			workspaces, err := client.GetUserWorkspaces(context.Background(), tt.userID)
+			if err != nil {
+				t.Fatalf("GetUserWorkspaces: %v", err)
+			}
+			got := make(map[string]access)
+			for _, ws := range workspaces {
+				got[ws.WorkspaceID] = access{ws.Permission, ws.GroupPath}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetUserGroupAccess(t *testing.T) {
+	client, _ := newTestClient(t)
+
+	tests := []struct {
+		userID         string
+		wantGroups     map[string]airfocus.Permission
+		wantWorkspaces map[string]airfocus.Permission
+	}{
+		{
+			userID: "u-bob",
+			wantGroups: map[string]airfocus.Permission{
+				"g-mobile":  airfocus.PermissionWrite, // inherited from Product
+				"g-product": airfocus.PermissionWrite,
+			},
+			wantWorkspaces: map[string]airfocus.Permission{
+				"w-android": airfocus.PermissionWrite,
+				"w-ios":     airfocus.PermissionWrite,
+				"w-roadmap": airfocus.PermissionWrite,
+			},
+		},
+		{
+			userID: "u-carol",
+			wantGroups: map[string]airfocus.Permission{
+				"g-mobile":  airfocus.PermissionComment,
+				"g-product": airfocus.PermissionRead, // group default
+			},
+			wantWorkspaces: map[string]airfocus.Permission{
+				"w-android": airfocus.PermissionComment,
+				"w-ios":     airfocus.PermissionComment,
+				"w-roadmap": airfocus.PermissionRead,
+			},
+		},
+		{
+			userID: "u-alice",
+			wantGroups: map[string]airfocus.Permission{
+				"g-finance": airfocus.PermissionFull,
+				"g-mobile":  airfocus.PermissionRead, // Product default
+				"g-product": airfocus.PermissionRead,
+			},
+			wantWorkspaces: map[string]airfocus.Permission{
+				"w-budget":  airfocus.PermissionFull,
+				"w-android": airfocus.PermissionRead,
+				"w-ios":     airfocus.PermissionFull,
+				"w-roadmap": airfocus.PermissionFull,
+			},
+		},
+		{
+			// Finance has no default permission, so it is hidden from users without a grant
+			userID: "u-erin",
+			wantGroups: map[string]airfocus.Permission{
+				"g-mobile":  airfocus.PermissionRead,
+				"g-product": airfocus.PermissionRead,
+			},
+			wantWorkspaces: map[string]airfocus.Permission{
+				"w-android": airfocus.PermissionRead,
+				"w-ios":     airfocus.PermissionRead,
+				"w-roadmap": airfocus.PermissionRead,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.userID, func(t *testing.T) {
+			groups, err := client.GetUserGroupAccess(context.Background(), tt.userID)
+			if err != nil {
+				t.Fatalf("GetUserGroupAccess: %v", err)
+			}
+			gotGroups := make(map[string]airfocus.Permission)
+			gotWorkspaces := make(map[string]airfocus.Permission)
+			for _, g := range groups {
+				gotGroups[g.ID] = g.CurrentPermission
+				for _, ws := range g.Embedded.Workspaces {
+					gotWorkspaces[ws.ID] = ws.CurrentPermission
+				}
+			}
+			if !reflect.DeepEqual(gotGroups, tt.wantGroups) {
+				t.Errorf("groups = %v, want %v", gotGroups, tt.wantGroups)
+			}
+			if !reflect.DeepEqual(gotWorkspaces, tt.wantWorkspaces) {
+				t.Errorf("workspaces = %v, want %v", gotWorkspaces, tt.wantWorkspaces)
+			}
+		})
+	}
+}
+
+func TestGetWorkspaceUsers(t *testing.T) {
+	client, _ := newTestClient(t)
+
+	users, err := client.GetWorkspaceUsers(context.Background(), "w-budget")
+	if err != nil {
+		t.Fatalf("GetWorkspaceUsers: %v", err)
+	}
+	want := []airfocus.WorkspaceUser{
+		{UserID: "u-alice", FullName: "Alice Admin", Email: "alice@example.com", Permission: "full"},
+		{UserID: "u-ghost", FullName: "Unknown User", Permission: "read"},
+	}
+	if !reflect.DeepEqual(users, want) {
+		t.Errorf("got %+v, want %+v", users, want)
+	}
+}
+
+func TestCacheIsReused(t *testing.T) {
+	client, srv := newTestClient(t)
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		if _, err := client.ListUsers(ctx); err != nil {
+			t.Fatalf("ListUsers: %v", err)
+		}
+	}
+	if n := srv.Requests("GET", "/team/users"); n != 1 {
+		t.Errorf("GET /team/users requested %d times, want 1", n)
+	}
+}
@@ -0,0 +1,52 @@
+package airfocus
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Sentinel errors for common API failures. Errors returned by the client
+// wrap them, so callers can check with errors.Is.
+var (
+	ErrUnauthorized = errors.New("airfocus: invalid or missing API key")       // 401 responses
+	ErrForbidden    = errors.New("airfocus: API key lacks permission")         // 403 responses
+	ErrNotFound     = errors.New("airfocus: not found")                        // 404 responses and failed lookups
+	ErrRateLimited  = errors.New("airfocus: rate limited by the Airfocus API") // 429 responses
+
+	ErrInvalidPermission = errors.New("airfocus: invalid permission level")                 // Rejected before sending a request
+	ErrSkipped           = errors.New("airfocus: skipped because a required change failed") // Not sent; see AccessChange.Requires
+)
+
+// APIError is returned when the Airfocus API answers with a non-success
+// status. Use errors.As to inspect it, or errors.Is with the sentinel
+// errors above to classify it.
+type APIError struct {
+	StatusCode int           // HTTP status code of the response
+	Method     string        // HTTP method of the request
+	Endpoint   string        // Request path relative to the base URL
+	Body       string        // Response body, truncated to a few kilobytes
+	RetryAfter time.Duration // Delay requested by the API via Retry-After, if any
+}
+
+// Error implements the error interface
+func (e *APIError) Error() string {
+	return fmt.Sprintf("airfocus API %s %s failed with status %d: %s", e.Method, e.Endpoint, e.StatusCode, e.Body)
+}
+
+// Unwrap returns the sentinel error matching the status code, if any
+func (e *APIError) Unwrap() error {
+	switch e.StatusCode {
+	case http.StatusUnauthorized:
+		return ErrUnauthorized
+	case http.StatusForbidden:
+		return ErrForbidden
+	case http.StatusNotFound:
+		return ErrNotFound
+	case http.StatusTooManyRequests:
+		return ErrRateLimited
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,58 @@
+package airfocus_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/tibuski/goAirfocus/airfocus"
+	"github.com/tibuski/goAirfocus/airfocus/fake"
+)
+
+func TestSentinelErrors(t *testing.T) {
+	srv := fake.NewServer(fake.DefaultFixtures())
+	defer srv.Close()
+
+	valid := airfocus.NewClient(fake.APIKey, airfocus.WithBaseURL(srv.URL))
+	invalid := airfocus.NewClient("wrong-key", airfocus.WithBaseURL(srv.URL))
+	ctx := context.Background()
+
+	tests := []struct {
+		name string
+		call func() error
+		want error
+	}{
+		{"invalid API key", func() error { _, err := invalid.ListUsers(ctx); return err }, airfocus.ErrUnauthorized},
+		{"unknown workspace", func() error { _, err := valid.GetWorkspaceByID(ctx, "w-missing"); return err }, airfocus.ErrNotFound},
+		{"unknown user", func() error { _, err := valid.GetUser(ctx, "u-missing"); return err }, airfocus.ErrNotFound},
+		{"no workspace by name", func() error { _, err := valid.GetWorkspaceIDByName(ctx, "Nope"); return err }, airfocus.ErrNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.call()
+			if !errors.Is(err, tt.want) {
+				t.Errorf("error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestAPIErrorUnwrap(t *testing.T) {
+	tests := []struct {
+		status int
+		want   error
+	}{
+		{http.StatusUnauthorized, airfocus.ErrUnauthorized},
+		{http.StatusForbidden, airfocus.ErrForbidden},
+		{http.StatusNotFound, airfocus.ErrNotFound},
+		{http.StatusTooManyRequests, airfocus.ErrRateLimited},
+		{http.StatusInternalServerError, nil},
+	}
+	for _, tt := range tests {
+		err := &airfocus.APIError{StatusCode: tt.status}
+		if got := err.Unwrap(); got != tt.want {
+			t.Errorf("status %d: Unwrap() = %v, want %v", tt.status, got, tt.want)
+		}
+	}
+}
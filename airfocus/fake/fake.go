@@ -0,0 +1,358 @@
+// Package fake provides an in-memory stand-in for the Airfocus API, serving
+// tenant data from JSON fixtures. It is meant for tests and local development:
+//
+//	srv := fake.NewServer(fake.DefaultFixtures())
+//	defer srv.Close()
+//	client := airfocus.NewClient(fake.APIKey, airfocus.WithBaseURL(srv.URL))
+package fake
+
+import (
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/tibuski/goAirfocus/airfocus"
+)
+
+// APIKey is the bearer token accepted by servers created with NewServer
+const APIKey = "fake-api-key"
+
+//go:embed fixtures/*.json
+var fixturesFS embed.FS
+
+// Fixtures holds the tenant data served by the fake API
+type Fixtures struct {
+	Users      []airfocus.User           // Served by GET /team/users
+	Workspaces []airfocus.Workspace      // Served by POST /workspaces/search and GET /workspaces/{id}, changed by PUT and DELETE /workspaces/{id}/permissions/{userId}
+	Groups     []airfocus.WorkspaceGroup // Served by POST /workspaces/groups/search, changed by PUT and DELETE /workspaces/groups/{id}/permissions/{userId}
+	Fields     []airfocus.Field          // Served by POST /fields/search
+	Items      []airfocus.Item           // Served by POST /workspaces/{id}/items/search and GET /workspaces/{id}/items/{itemId}
+	Team       json.RawMessage           // Served by GET /team
+}
+
+// DefaultFixtures returns the built-in sample tenant. It panics if the
+// embedded fixtures are invalid, which would be a programming error.
+func DefaultFixtures() Fixtures {
+	sub, err := fs.Sub(fixturesFS, "fixtures")
+	if err != nil {
+		panic(err)
+	}
+	f, err := LoadFixtures(sub)
+	if err != nil {
+		panic(err)
+	}
+	return f
+}
+
+// LoadFixtures reads users.json, workspaces.json, groups.json, fields.json,
+// items.json and team.json from fsys. Missing files leave the matching data empty.
+func LoadFixtures(fsys fs.FS) (Fixtures, error) {
+	var f Fixtures
+	files := []struct {
+		name string
+		dst  interface{}
+	}{
+		{"users.json", &f.Users},
+		{"workspaces.json", &f.Workspaces},
+		{"groups.json", &f.Groups},
+		{"fields.json", &f.Fields},
+		{"items.json", &f.Items},
+		{"team.json", &f.Team},
+	}
+	for _, file := range files {
+		data, err := fs.ReadFile(fsys, file.name)
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			return Fixtures{}, fmt.Errorf("failed to read fixture %s: %w", file.name, err)
+		}
+		if err := json.Unmarshal(data, file.dst); err != nil {
+			return Fixtures{}, fmt.Errorf("failed to decode fixture %s: %w", file.name, err)
+		}
+	}
+	return f, nil
+}
+
+// Server is a running fake Airfocus API
+type Server struct {
+	*httptest.Server
+
+	mu          sync.Mutex
+	fixtures    Fixtures
+	requests    map[string]int // Number of requests per "METHOD /path"
+	maxPageSize int            // Upper bound on the limit honoured by search endpoints, 0 for none
+}
+
+// NewServer starts a fake Airfocus API serving the given fixtures. Requests
+// must carry "Authorization: Bearer " + APIKey.
+func NewServer(f Fixtures) *Server {
+	s := &Server{
+		fixtures: f,
+		requests: make(map[string]int),
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	return s
+}
+
+// SetMaxPageSize caps the number of items search endpoints return per page,
+// like the real API does, so pagination can be exercised with small fixtures.
+func (s *Server) SetMaxPageSize(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.maxPageSize = n
+}
+
+// Requests returns how many times the given method and path were requested
+func (s *Server) Requests(method, path string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests[method+" "+path]
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.requests[r.Method+" "+r.URL.Path]++
+
+	if r.Header.Get("Authorization") != "Bearer "+APIKey {
+		writeError(w, http.StatusUnauthorized, "invalid API key")
+		return
+	}
+
+	path := r.URL.Path
+	switch {
+	case r.Method == http.MethodGet && path == "/team":
+		s.handleTeam(w)
+	case r.Method == http.MethodGet && path == "/team/users":
+		writeJSON(w, s.fixtures.Users)
+	case r.Method == http.MethodPost && path == "/workspaces/search":
+		s.handleWorkspaceSearch(w, r)
+	case r.Method == http.MethodPost && path == "/workspaces/groups/search":
+		items, total := page(r, s.fixtures.Groups, s.maxPageSize)
+		writeJSON(w, airfocus.WorkspaceGroupResponse{Items: items, TotalItems: total})
+	case r.Method == http.MethodPost && path == "/fields/search":
+		items, total := page(r, s.fixtures.Fields, s.maxPageSize)
+		writeJSON(w, airfocus.FieldSearchResponse{Items: items, TotalItems: total})
+	case strings.HasPrefix(path, "/workspaces/"):
+		s.handleWorkspacePath(w, r, strings.Split(strings.TrimPrefix(path, "/workspaces/"), "/"))
+	default:
+		writeError(w, http.StatusNotFound, "no such endpoint")
+	}
+}
+
+func (s *Server) handleTeam(w http.ResponseWriter) {
+	if s.fixtures.Team == nil {
+		writeError(w, http.StatusNotFound, "team not found")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(s.fixtures.Team)
+}
+
+func (s *Server) handleWorkspaceSearch(w http.ResponseWriter, r *http.Request) {
+	var query airfocus.WorkspaceSearchQuery
+	if err := json.NewDecoder(r.Body).Decode(&query); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid search query")
+		return
+	}
+
+	items := []airfocus.Workspace{}
+	for _, ws := range s.fixtures.Workspaces {
+		if ws.Archived && !query.Archived {
+			continue
+		}
+		if query.Filter != nil && query.Filter.Type == "name" && !matchText(ws.Name, query.Filter) {
+			continue
+		}
+		items = append(items, ws)
+	}
+	items, total := page(r, items, s.maxPageSize)
+	writeJSON(w, airfocus.WorkspaceResponse{Items: items, TotalItems: total})
+}
+
+// handleWorkspacePath serves the endpoints below /workspaces/{id}
+func (s *Server) handleWorkspacePath(w http.ResponseWriter, r *http.Request, parts []string) {
+	switch {
+	case r.Method == http.MethodGet && len(parts) == 1:
+		s.handleGetWorkspace(w, parts[0])
+	case r.Method == http.MethodPost && len(parts) == 3 && parts[1] == "items" && parts[2] == "search":
+		s.handleItemSearch(w, r, parts[0])
+	case r.Method == http.MethodGet && len(parts) == 3 && parts[1] == "items":
+		s.handleGetItem(w, parts[0], parts[2])
+	case len(parts) == 3 && parts[1] == "permissions":
+		s.handleWorkspacePermission(w, r, parts[0], parts[2])
+	case len(parts) == 4 && parts[0] == "groups" && parts[2] == "permissions":
+		s.handleGroupPermission(w, r, parts[1], parts[3])
+	default:
+		writeError(w, http.StatusNotFound, "no such endpoint")
+	}
+}
+
+func (s *Server) handleItemSearch(w http.ResponseWriter, r *http.Request, workspaceID string) {
+	if _, ok := s.workspace(workspaceID); !ok {
+		writeError(w, http.StatusNotFound, "workspace not found")
+		return
+	}
+
+	var query airfocus.ItemSearchQuery
+	if err := json.NewDecoder(r.Body).Decode(&query); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid search query")
+		return
+	}
+
+	items := []airfocus.Item{}
+	for _, item := range s.fixtures.Items {
+		if item.WorkspaceID != workspaceID || (item.Archived && !query.Archived) {
+			continue
+		}
+		if query.Filter != nil && query.Filter.Type == "name" && !matchText(item.Name, (*airfocus.WorkspaceSearchFilter)(query.Filter)) {
+			continue
+		}
+		items = append(items, item)
+	}
+	items, total := page(r, items, s.maxPageSize)
+	writeJSON(w, airfocus.ItemSearchResponse{Items: items, TotalItems: total})
+}
+
+func (s *Server) handleGetItem(w http.ResponseWriter, workspaceID, itemID string) {
+	for _, item := range s.fixtures.Items {
+		if item.WorkspaceID == workspaceID && item.ID == itemID {
+			writeJSON(w, item)
+			return
+		}
+	}
+	writeError(w, http.StatusNotFound, "item not found")
+}
+
+// handleWorkspacePermission serves PUT and DELETE /workspaces/{id}/permissions/{userId}
+func (s *Server) handleWorkspacePermission(w http.ResponseWriter, r *http.Request, workspaceID, userID string) {
+	for i := range s.fixtures.Workspaces {
+		ws := &s.fixtures.Workspaces[i]
+		if ws.ID == workspaceID {
+			if updated, ok := updatePermissions(w, r, ws.Embedded.Permissions, userID); ok {
+				ws.Embedded.Permissions = updated
+			}
+			return
+		}
+	}
+	writeError(w, http.StatusNotFound, "workspace not found")
+}
+
+// handleGroupPermission serves PUT and DELETE /workspaces/groups/{id}/permissions/{userId}
+func (s *Server) handleGroupPermission(w http.ResponseWriter, r *http.Request, groupID, userID string) {
+	for i := range s.fixtures.Groups {
+		group := &s.fixtures.Groups[i]
+		if group.ID == groupID {
+			if updated, ok := updatePermissions(w, r, group.Embedded.Permissions, userID); ok {
+				group.Embedded.Permissions = updated
+			}
+			return
+		}
+	}
+	writeError(w, http.StatusNotFound, "group not found")
+}
+
+// updatePermissions applies a permission request to a copy of permissions and
+// writes the response. It reports false if the request was rejected.
+func updatePermissions(w http.ResponseWriter, r *http.Request, permissions map[string]string, userID string) (map[string]string, bool) {
+	updated := make(map[string]string, len(permissions)+1)
+	for id, p := range permissions {
+		updated[id] = p
+	}
+
+	switch r.Method {
+	case http.MethodPut:
+		var body airfocus.PermissionUpdate
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil || !body.Permission.Valid() {
+			writeError(w, http.StatusBadRequest, "invalid permission")
+			return nil, false
+		}
+		updated[userID] = string(body.Permission)
+	case http.MethodDelete:
+		if _, ok := updated[userID]; !ok {
+			writeError(w, http.StatusNotFound, "permission not found")
+			return nil, false
+		}
+		delete(updated, userID)
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return nil, false
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+	return updated, true
+}
+
+// workspace looks up a workspace fixture by ID
+func (s *Server) workspace(id string) (airfocus.Workspace, bool) {
+	for _, ws := range s.fixtures.Workspaces {
+		if ws.ID == id {
+			return ws, true
+		}
+	}
+	return airfocus.Workspace{}, false
+}
+
+func (s *Server) handleGetWorkspace(w http.ResponseWriter, id string) {
+	ws, ok := s.workspace(id)
+	if !ok {
+		writeError(w, http.StatusNotFound, "workspace not found")
+		return
+	}
+	writeJSON(w, ws)
+}
+
+// page applies the offset and limit query parameters to items and returns
+// the requested slice together with the total number of items
+func page[T any](r *http.Request, items []T, maxPageSize int) ([]T, int) {
+	total := len(items)
+	offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+	if err != nil || limit <= 0 {
+		limit = total
+	}
+	if maxPageSize > 0 && limit > maxPageSize {
+		limit = maxPageSize
+	}
+	if offset < 0 || offset > total {
+		offset = total
+	}
+	end := offset + limit
+	if end > total {
+		end = total
+	}
+	return append([]T{}, items[offset:end]...), total
+}
+
+// matchText applies a workspace name filter the way the search endpoint does
+func matchText(name string, filter *airfocus.WorkspaceSearchFilter) bool {
+	text := filter.Text
+	if !filter.CaseSensitive {
+		name = strings.ToLower(name)
+		text = strings.ToLower(text)
+	}
+	if filter.Mode == "equal" {
+		return name == text
+	}
+	return strings.Contains(name, text)
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"message": message})
+}
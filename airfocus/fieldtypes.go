@@ -0,0 +1,244 @@
+package airfocus
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FieldType identifies the kind of a field and how its values are stored
+type FieldType string
+
+const (
+	FieldTypeText     FieldType = "text"
+	FieldTypeNumber   FieldType = "number"
+	FieldTypeDate     FieldType = "date"
+	FieldTypeSelect   FieldType = "select"
+	FieldTypePeople   FieldType = "people"
+	FieldTypeFormula  FieldType = "formula"
+	FieldTypeCheckbox FieldType = "checkbox"
+)
+
+// FieldSettings is the type-specific configuration of a field. The concrete
+// type is one of *TextSettings, *NumberSettings, *DateSettings,
+// *SelectSettings, *PeopleSettings, *FormulaSettings, *CheckboxSettings or
+// *UnknownSettings.
+type FieldSettings interface {
+	FieldType() FieldType
+}
+
+// TextSettings configures a text field
+type TextSettings struct {
+	Multiline bool `json:"multiline"`           // Whether the field accepts multiple lines
+	MaxLength *int `json:"maxLength,omitempty"` // Maximum number of characters, if limited
+}
+
+// NumberSettings configures a number field
+type NumberSettings struct {
+	Min       *float64 `json:"min,omitempty"` // Lowest allowed value, if limited
+	Max       *float64 `json:"max,omitempty"` // Highest allowed value, if limited
+	Precision int      `json:"precision"`     // Number of decimal places shown
+	Unit      string   `json:"unit"`          // Unit shown next to values (e.g., "days", "€")
+}
+
+// DateSettings configures a date field
+type DateSettings struct {
+	IncludeTime bool `json:"includeTime"` // Whether values carry a time of day
+}
+
+// SelectOption is one choice of a select field
+type SelectOption struct {
+	ID    string `json:"id"`    // Unique identifier for the option
+	Name  string `json:"name"`  // Option label
+	Color string `json:"color"` // Option color
+	Order int    `json:"order"` // Display order
+}
+
+// SelectSettings configures a select field
+type SelectSettings struct {
+	Multiple bool           `json:"multiple"` // Whether several options can be selected
+	Options  []SelectOption `json:"options"`  // Available options
+}
+
+// Option returns the option with the given ID
+func (s *SelectSettings) Option(id string) (SelectOption, bool) {
+	for _, option := range s.Options {
+		if option.ID == id {
+			return option, true
+		}
+	}
+	return SelectOption{}, false
+}
+
+// PeopleSettings configures a people field
+type PeopleSettings struct {
+	Multiple bool `json:"multiple"` // Whether several people can be selected
+}
+
+// FormulaSettings configures a formula field
+type FormulaSettings struct {
+	Expression string    `json:"expression"` // Formula expression
+	ResultType FieldType `json:"resultType"` // Type of the computed value (e.g., "number")
+}
+
+// CheckboxSettings configures a checkbox field
+type CheckboxSettings struct{}
+
+// UnknownSettings holds the configuration of a field type this package does not model
+type UnknownSettings struct {
+	Type FieldType       // Field type as reported by the API
+	Raw  json.RawMessage // Original settings JSON
+}
+
+func (*TextSettings) FieldType() FieldType     { return FieldTypeText }
+func (*NumberSettings) FieldType() FieldType   { return FieldTypeNumber }
+func (*DateSettings) FieldType() FieldType     { return FieldTypeDate }
+func (*SelectSettings) FieldType() FieldType   { return FieldTypeSelect }
+func (*PeopleSettings) FieldType() FieldType   { return FieldTypePeople }
+func (*FormulaSettings) FieldType() FieldType  { return FieldTypeFormula }
+func (*CheckboxSettings) FieldType() FieldType { return FieldTypeCheckbox }
+func (s *UnknownSettings) FieldType() FieldType {
+	return s.Type
+}
+
+// TypedSettings decodes the field's settings into the struct matching its type
+func (f *Field) TypedSettings() (FieldSettings, error) {
+	var settings FieldSettings
+	switch f.Type {
+	case FieldTypeText:
+		settings = &TextSettings{}
+	case FieldTypeNumber:
+		settings = &NumberSettings{}
+	case FieldTypeDate:
+		settings = &DateSettings{}
+	case FieldTypeSelect:
+		settings = &SelectSettings{}
+	case FieldTypePeople:
+		settings = &PeopleSettings{}
+	case FieldTypeFormula:
+		settings = &FormulaSettings{}
+	case FieldTypeCheckbox:
+		settings = &CheckboxSettings{}
+	default:
+		return &UnknownSettings{Type: f.Type, Raw: f.Settings}, nil
+	}
+
+	if len(f.Settings) > 0 && string(f.Settings) != "null" {
+		if err := json.Unmarshal(f.Settings, settings); err != nil {
+			return nil, fmt.Errorf("failed to decode %s settings of field %s: %w", f.Type, f.ID, err)
+		}
+	}
+	return settings, nil
+}
+
+// FieldValue is a decoded item field value. The concrete type is one of
+// TextValue, NumberValue, DateValue, SelectValue, PeopleValue, CheckboxValue
+// or RawValue.
+type FieldValue interface {
+	String() string
+}
+
+// TextValue is the value of a text field
+type TextValue string
+
+// NumberValue is the value of a number field
+type NumberValue float64
+
+// DateValue is the value of a date field
+type DateValue time.Time
+
+// SelectValue holds the selected options of a select field
+type SelectValue []SelectOption
+
+// PeopleValue holds the user IDs selected in a people field
+type PeopleValue []string
+
+// CheckboxValue is the value of a checkbox field
+type CheckboxValue bool
+
+// RawValue is the undecoded value of a field type this package does not model
+type RawValue json.RawMessage
+
+func (v TextValue) String() string   { return string(v) }
+func (v NumberValue) String() string { return strconv.FormatFloat(float64(v), 'f', -1, 64) }
+func (v DateValue) String() string   { return time.Time(v).Format("2006-01-02") }
+func (v PeopleValue) String() string { return strings.Join(v, ", ") }
+func (v RawValue) String() string    { return string(v) }
+func (v CheckboxValue) String() string {
+	return strconv.FormatBool(bool(v))
+}
+func (v SelectValue) String() string {
+	names := make([]string, len(v))
+	for i, option := range v {
+		names[i] = option.Name
+	}
+	return strings.Join(names, ", ")
+}
+
+// Decode converts the raw value into the Go type matching the field's type.
+// Select option IDs are resolved to options using the field's settings;
+// unknown IDs are kept as options named after their ID. A nil FieldValue is
+// returned when a text, number or date field has no value.
+func (v ItemFieldValue) Decode(field Field) (FieldValue, error) {
+	settings, err := field.TypedSettings()
+	if err != nil {
+		return nil, err
+	}
+
+	switch s := settings.(type) {
+	case *TextSettings:
+		if v.Text == nil {
+			return nil, nil
+		}
+		return TextValue(*v.Text), nil
+	case *NumberSettings:
+		if v.Number == nil {
+			return nil, nil
+		}
+		return NumberValue(*v.Number), nil
+	case *DateSettings:
+		if v.Date == nil {
+			return nil, nil
+		}
+		return parseDateValue(*v.Date)
+	case *SelectSettings:
+		selected := make(SelectValue, 0, len(v.Selection))
+		for _, id := range v.Selection {
+			option, ok := s.Option(id)
+			if !ok {
+				option = SelectOption{ID: id, Name: id}
+			}
+			selected = append(selected, option)
+		}
+		return selected, nil
+	case *PeopleSettings:
+		return PeopleValue(v.UserIDs), nil
+	case *CheckboxSettings:
+		return CheckboxValue(v.Checked != nil && *v.Checked), nil
+	case *FormulaSettings:
+		// Formulas carry their computed value in the property of their result type
+		switch {
+		case v.Number != nil:
+			return NumberValue(*v.Number), nil
+		case v.Text != nil:
+			return TextValue(*v.Text), nil
+		case v.Date != nil:
+			return parseDateValue(*v.Date)
+		}
+		return RawValue(v.Raw), nil
+	default:
+		return RawValue(v.Raw), nil
+	}
+}
+
+// parseDateValue parses a date given as RFC 3339 timestamp or plain date
+func parseDateValue(value string) (FieldValue, error) {
+	for _, layout := range []string{time.RFC3339, "2006-01-02"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return DateValue(t), nil
+		}
+	}
+	return nil, fmt.Errorf("invalid date value %q", value)
+}
@@ -0,0 +1,117 @@
+package airfocus_test
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/tibuski/goAirfocus/airfocus"
+)
+
+func TestTypedSettings(t *testing.T) {
+	tests := []struct {
+		name  string
+		field airfocus.Field
+		want  airfocus.FieldSettings
+	}{
+		{"number", airfocus.Field{Type: airfocus.FieldTypeNumber, Settings: json.RawMessage(`{"min":0,"max":10,"unit":"pts"}`)},
+			&airfocus.NumberSettings{Min: ptr(0.0), Max: ptr(10.0), Unit: "pts"}},
+		{"select", airfocus.Field{Type: airfocus.FieldTypeSelect, Settings: json.RawMessage(`{"multiple":true,"options":[{"id":"a","name":"A"}]}`)},
+			&airfocus.SelectSettings{Multiple: true, Options: []airfocus.SelectOption{{ID: "a", Name: "A"}}}},
+		{"formula", airfocus.Field{Type: airfocus.FieldTypeFormula, Settings: json.RawMessage(`{"expression":"effort * 2","resultType":"number"}`)},
+			&airfocus.FormulaSettings{Expression: "effort * 2", ResultType: airfocus.FieldTypeNumber}},
+		{"missing settings", airfocus.Field{Type: airfocus.FieldTypeDate}, &airfocus.DateSettings{}},
+		{"unknown type", airfocus.Field{Type: "rating", Settings: json.RawMessage(`{"stars":5}`)},
+			&airfocus.UnknownSettings{Type: "rating", Raw: json.RawMessage(`{"stars":5}`)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.field.TypedSettings()
+			if err != nil {
+				t.Fatalf("TypedSettings: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+
+	invalid := airfocus.Field{ID: "f-bad", Type: airfocus.FieldTypeNumber, Settings: json.RawMessage(`{"min":"low"}`)}
+	if _, err := invalid.TypedSettings(); err == nil {
+		t.Error("expected an error for malformed settings")
+	}
+}
+
+func TestDecodeFieldValue(t *testing.T) {
+	selectField := airfocus.Field{Type: airfocus.FieldTypeSelect, Settings: json.RawMessage(`{"options":[{"id":"opt-high","name":"High"}]}`)}
+
+	tests := []struct {
+		name  string
+		field airfocus.Field
+		value string
+		want  airfocus.FieldValue
+	}{
+		{"text", airfocus.Field{Type: airfocus.FieldTypeText}, `{"text":"hello"}`, airfocus.TextValue("hello")},
+		{"number", airfocus.Field{Type: airfocus.FieldTypeNumber}, `{"number":2.5}`, airfocus.NumberValue(2.5)},
+		{"date", airfocus.Field{Type: airfocus.FieldTypeDate}, `{"date":"2024-05-01"}`,
+			airfocus.DateValue(time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC))},
+		{"select with unknown option", selectField, `{"selection":["opt-high","opt-gone"]}`,
+			airfocus.SelectValue{{ID: "opt-high", Name: "High"}, {ID: "opt-gone", Name: "opt-gone"}}},
+		{"people", airfocus.Field{Type: airfocus.FieldTypePeople}, `{"userIds":["u-bob"]}`, airfocus.PeopleValue{"u-bob"}},
+		{"checkbox", airfocus.Field{Type: airfocus.FieldTypeCheckbox}, `{"checked":true}`, airfocus.CheckboxValue(true)},
+		{"formula", airfocus.Field{Type: airfocus.FieldTypeFormula}, `{"number":16}`, airfocus.NumberValue(16)},
+		{"unknown type", airfocus.Field{Type: "rating"}, `{"stars":4}`, airfocus.RawValue(`{"stars":4}`)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var value airfocus.ItemFieldValue
+			if err := json.Unmarshal([]byte(tt.value), &value); err != nil {
+				t.Fatalf("Unmarshal: %v", err)
+			}
+			got, err := value.Decode(tt.field)
+			if err != nil {
+				t.Fatalf("Decode: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodeItemFieldsFromAPI(t *testing.T) {
+	client, _ := newTestClient(t)
+	ctx := context.Background()
+
+	fields, err := client.ListFields(ctx)
+	if err != nil {
+		t.Fatalf("ListFields: %v", err)
+	}
+	byID := make(map[string]airfocus.Field)
+	for _, f := range fields {
+		byID[f.ID] = f.Field
+	}
+
+	item, err := client.GetItem(ctx, "w-roadmap", "i-login")
+	if err != nil {
+		t.Fatalf("GetItem: %v", err)
+	}
+	got := make(map[string]string)
+	for fieldID, value := range item.Fields {
+		decoded, err := value.Decode(byID[fieldID])
+		if err != nil {
+			t.Fatalf("Decode %s: %v", fieldID, err)
+		}
+		got[fieldID] = decoded.String()
+	}
+	want := map[string]string{"f-priority": "High", "f-effort": "8"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
@@ -0,0 +1,157 @@
+package airfocus
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+)
+
+// ItemStatus represents a status an item can have in a workspace
+type ItemStatus struct {
+	ID       string `json:"id"`       // Unique identifier for the status
+	Name     string `json:"name"`     // Status name
+	Color    string `json:"color"`    // Status color
+	Category string `json:"category"` // Status category (e.g., "draft", "active", "done")
+	Order    int    `json:"order"`    // Display order
+}
+
+// ItemFieldValue represents the value of a field on an item. The API only
+// sets the properties that apply to the field's type; Raw keeps the original
+// JSON for types without a dedicated property. Use Decode to get a value
+// typed according to the field.
+type ItemFieldValue struct {
+	Text      *string         `json:"text,omitempty"`      // Value of text fields
+	Number    *float64        `json:"number,omitempty"`    // Value of number fields
+	Date      *string         `json:"date,omitempty"`      // Value of date fields
+	Selection []string        `json:"selection,omitempty"` // Selected option IDs of select fields
+	UserIDs   []string        `json:"userIds,omitempty"`   // Selected user IDs of people fields
+	Checked   *bool           `json:"checked,omitempty"`   // Value of checkbox fields
+	Raw       json.RawMessage `json:"-"`                   // Original JSON value
+}
+
+// UnmarshalJSON decodes a field value while keeping the raw JSON
+func (v *ItemFieldValue) UnmarshalJSON(data []byte) error {
+	type plain ItemFieldValue
+	var decoded plain
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return err
+	}
+	*v = ItemFieldValue(decoded)
+	v.Raw = append(json.RawMessage(nil), data...)
+	return nil
+}
+
+// Item represents an item (e.g., a feature or task) in a workspace
+type Item struct {
+	ID              string                    `json:"id"`               // Unique identifier for the item
+	WorkspaceID     string                    `json:"workspaceId"`      // ID of the workspace the item belongs to
+	Name            string                    `json:"name"`             // Item name
+	StatusID        string                    `json:"statusId"`         // ID of the item's status
+	Color           string                    `json:"color"`            // Item color
+	Archived        bool                      `json:"archived"`         // Whether the item is archived
+	AssigneeUserIDs []string                  `json:"assigneeUserIds"`  // IDs of the users assigned to the item
+	Order           int                       `json:"order"`            // Display order
+	CreatedAt       string                    `json:"createdAt"`        // Creation timestamp
+	LastUpdatedAt   string                    `json:"lastUpdatedAt"`    // Last update timestamp
+	Fields          map[string]ItemFieldValue `json:"fields"`           // Field values keyed by field ID
+	Status          *ItemStatus               `json:"status,omitempty"` // Resolved status, if known
+}
+
+// ItemSearchFilter represents the filter options for item search
+type ItemSearchFilter struct {
+	Type          string `json:"type"`          // Type of filter (e.g., "name")
+	Mode          string `json:"mode"`          // Filter mode (e.g., "contain")
+	Text          string `json:"text"`          // Filter text
+	CaseSensitive bool   `json:"caseSensitive"` // Whether the filter is case-sensitive
+}
+
+// ItemSearchQuery represents the query parameters for item search
+type ItemSearchQuery struct {
+	Archived bool              `json:"archived"`         // Whether to include archived items
+	Filter   *ItemSearchFilter `json:"filter,omitempty"` // Optional filter
+}
+
+// ItemNameQuery returns a query matching items whose name contains text
+func ItemNameQuery(text string) ItemSearchQuery {
+	if text == "" {
+		return ItemSearchQuery{}
+	}
+	return ItemSearchQuery{
+		Filter: &ItemSearchFilter{
+			Type: "name",
+			Mode: "contain",
+			Text: text,
+		},
+	}
+}
+
+// ItemSearchResponse represents the response from the item search API
+type ItemSearchResponse struct {
+	Items      []Item `json:"items"`      // List of items
+	TotalItems int    `json:"totalItems"` // Total number of items
+}
+
+// ItemSearchResult holds all items matching a search
+type ItemSearchResult struct {
+	Items    []Item // Matching items with their status resolved
+	Complete bool   // False when the API returned fewer items than it reported
+}
+
+// SearchItems retrieves all items of a workspace matching query, walking all pages
+func (c *Client) SearchItems(ctx context.Context, workspaceID string, query ItemSearchQuery) (ItemSearchResult, error) {
+	path := "/workspaces/" + url.PathEscape(workspaceID) + "/items/search"
+
+	items, complete, err := paginate(ctx, c.pageSize, func(item Item) string { return item.ID }, func(ctx context.Context, offset, limit int) ([]Item, int, error) {
+		var result ItemSearchResponse
+		if err := c.doJSON(ctx, "POST", pagedURL(path, offset, limit), query, &result); err != nil {
+			return nil, 0, fmt.Errorf("failed to search items: %w", err)
+		}
+		return result.Items, result.TotalItems, nil
+	})
+	if err != nil {
+		return ItemSearchResult{}, err
+	}
+
+	statuses, err := c.workspaceStatuses(ctx, workspaceID)
+	if err != nil {
+		return ItemSearchResult{}, err
+	}
+	for i := range items {
+		items[i].Status = statuses[items[i].StatusID]
+	}
+
+	return ItemSearchResult{Items: items, Complete: complete}, nil
+}
+
+// GetItem retrieves a single item of a workspace by its ID
+func (c *Client) GetItem(ctx context.Context, workspaceID, itemID string) (Item, error) {
+	var item Item
+	path := "/workspaces/" + url.PathEscape(workspaceID) + "/items/" + url.PathEscape(itemID)
+	if err := c.doJSON(ctx, "GET", path, nil, &item); err != nil {
+		return Item{}, fmt.Errorf("failed to get item: %w", err)
+	}
+
+	statuses, err := c.workspaceStatuses(ctx, workspaceID)
+	if err != nil {
+		return Item{}, err
+	}
+	item.Status = statuses[item.StatusID]
+
+	return item, nil
+}
+
+// workspaceStatuses returns the statuses of a workspace keyed by their ID
+func (c *Client) workspaceStatuses(ctx context.Context, workspaceID string) (map[string]*ItemStatus, error) {
+	workspace, err := c.GetWorkspaceByID(ctx, workspaceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get workspace statuses: %w", err)
+	}
+
+	statuses := make(map[string]*ItemStatus, len(workspace.Embedded.Statuses))
+	for i := range workspace.Embedded.Statuses {
+		status := workspace.Embedded.Statuses[i]
+		statuses[status.ID] = &status
+	}
+	return statuses, nil
+}
@@ -0,0 +1,88 @@
+package airfocus_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/tibuski/goAirfocus/airfocus"
+	"github.com/tibuski/goAirfocus/airfocus/fake"
+)
+
+func TestSearchItems(t *testing.T) {
+	srv := fake.NewServer(fake.DefaultFixtures())
+	defer srv.Close()
+	srv.SetMaxPageSize(2)
+	client := airfocus.NewClient(fake.APIKey, airfocus.WithBaseURL(srv.URL), airfocus.WithPageSize(2))
+
+	tests := []struct {
+		name        string
+		workspaceID string
+		query       airfocus.ItemSearchQuery
+		want        map[string]string // item ID to status name
+	}{
+		{"all items across pages", "w-roadmap", airfocus.ItemSearchQuery{}, map[string]string{
+			"i-login":    "In Progress",
+			"i-export":   "Backlog",
+			"i-darkmode": "Done",
+		}},
+		{"including archived", "w-roadmap", airfocus.ItemSearchQuery{Archived: true}, map[string]string{
+			"i-login":    "In Progress",
+			"i-export":   "Backlog",
+			"i-darkmode": "Done",
+			"i-legacy":   "Done",
+		}},
+		{"name filter", "w-roadmap", airfocus.ItemNameQuery("EXPORT"), map[string]string{
+			"i-export": "Backlog",
+		}},
+		{"workspace without items", "w-sandbox", airfocus.ItemSearchQuery{}, map[string]string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := client.SearchItems(context.Background(), tt.workspaceID, tt.query)
+			if err != nil {
+				t.Fatalf("SearchItems: %v", err)
+			}
+			if !result.Complete {
+				t.Error("result marked incomplete")
+			}
+			got := make(map[string]string)
+			for _, item := range result.Items {
+				status := ""
+				if item.Status != nil {
+					status = item.Status.Name
+				}
+				got[item.ID] = status
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetItem(t *testing.T) {
+	client, _ := newTestClient(t)
+
+	item, err := client.GetItem(context.Background(), "w-roadmap", "i-login")
+	if err != nil {
+		t.Fatalf("GetItem: %v", err)
+	}
+	if item.Status == nil || item.Status.Category != "active" {
+		t.Errorf("status = %+v, want the active In Progress status", item.Status)
+	}
+	if want := []string{"u-bob", "u-carol"}; !reflect.DeepEqual(item.AssigneeUserIDs, want) {
+		t.Errorf("assignees = %v, want %v", item.AssigneeUserIDs, want)
+	}
+	if effort := item.Fields["f-effort"].Number; effort == nil || *effort != 8 {
+		t.Errorf("effort = %v, want 8", effort)
+	}
+	if priority := item.Fields["f-priority"]; !reflect.DeepEqual(priority.Selection, []string{"opt-high"}) || len(priority.Raw) == 0 {
+		t.Errorf("priority = %+v, want selection [opt-high] with raw JSON", priority)
+	}
+
+	if _, err := client.GetItem(context.Background(), "w-roadmap", "i-missing"); !errors.Is(err, airfocus.ErrNotFound) {
+		t.Errorf("error = %v, want ErrNotFound", err)
+	}
+}
@@ -0,0 +1,109 @@
+package airfocus
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// MatrixWorkspace is a row of an AccessMatrix
+type MatrixWorkspace struct {
+	ID        string // Workspace ID
+	Name      string // Workspace name
+	GroupPath string // Full path of the workspace's group, empty if ungrouped
+}
+
+// AccessMatrix holds the effective permission of every user on every workspace
+type AccessMatrix struct {
+	Workspaces  []MatrixWorkspace // Rows, ordered by group path and name
+	Users       []User            // Columns, ordered by name
+	Permissions [][]Permission    // Permissions[row][column], PermissionNone where the user has no access
+}
+
+// AccessMatrix builds a workspace-by-user matrix of effective permissions
+// from the cached users, workspaces and groups.
+func (c *Client) AccessMatrix(ctx context.Context) (AccessMatrix, error) {
+	users, err := c.ListUsers(ctx)
+	if err != nil {
+		return AccessMatrix{}, fmt.Errorf("failed to list users: %w", err)
+	}
+	workspaces, err := c.ListWorkspaces(ctx)
+	if err != nil {
+		return AccessMatrix{}, fmt.Errorf("failed to list workspaces: %w", err)
+	}
+	groups, err := c.ListWorkspaceGroups(ctx)
+	if err != nil {
+		return AccessMatrix{}, fmt.Errorf("failed to list workspace groups: %w", err)
+	}
+
+	paths := groupPaths(groups)
+	sort.SliceStable(workspaces, func(i, j int) bool {
+		pi, pj := strings.ToLower(paths[workspaces[i].GroupID]), strings.ToLower(paths[workspaces[j].GroupID])
+		if pi != pj {
+			return pi < pj
+		}
+		return strings.ToLower(workspaces[i].Name) < strings.ToLower(workspaces[j].Name)
+	})
+	sort.SliceStable(users, func(i, j int) bool {
+		return strings.ToLower(users[i].FullName) < strings.ToLower(users[j].FullName)
+	})
+
+	m := AccessMatrix{
+		Workspaces:  make([]MatrixWorkspace, len(workspaces)),
+		Users:       users,
+		Permissions: make([][]Permission, len(workspaces)),
+	}
+	for i, ws := range workspaces {
+		m.Workspaces[i] = MatrixWorkspace{ID: ws.ID, Name: ws.Name, GroupPath: paths[ws.GroupID]}
+		m.Permissions[i] = make([]Permission, len(users))
+		for j, user := range users {
+			m.Permissions[i][j], _ = EffectivePermission(user.UserID, ws, groups)
+		}
+	}
+	return m, nil
+}
+
+// Rows returns the matrix as a table: a header row with the workspace
+// columns followed by one column per user, then one row per workspace.
+// Users are labelled "Full Name <email>"; disabled users keep their grants
+// and are marked "(disabled)" so they stand out in reviews.
+func (m AccessMatrix) Rows() [][]string {
+	header := []string{"Workspace ID", "Workspace", "Group Path"}
+	for _, user := range m.Users {
+		label := user.FullName
+		if user.Email != "" {
+			label += " <" + user.Email + ">"
+		}
+		if user.Disabled {
+			label += " (disabled)"
+		}
+		header = append(header, label)
+	}
+
+	rows := [][]string{header}
+	for i, ws := range m.Workspaces {
+		row := []string{ws.ID, ws.Name, ws.GroupPath}
+		for _, permission := range m.Permissions[i] {
+			row = append(row, string(permission))
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+// WriteCSV writes the matrix as CSV
+func (m AccessMatrix) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	if err := cw.WriteAll(m.Rows()); err != nil {
+		return fmt.Errorf("failed to write CSV: %w", err)
+	}
+	return nil
+}
+
+// WriteXLSX writes the matrix as an Excel workbook with a single sheet
+func (m AccessMatrix) WriteXLSX(w io.Writer) error {
+	return writeXLSX(w, "Access Matrix", m.Rows())
+}
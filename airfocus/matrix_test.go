@@ -0,0 +1,99 @@
+package airfocus_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestAccessMatrix(t *testing.T) {
+	client, _ := newTestClient(t)
+
+	matrix, err := client.AccessMatrix(context.Background())
+	if err != nil {
+		t.Fatalf("AccessMatrix: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := matrix.WriteCSV(&buf); err != nil {
+		t.Fatalf("WriteCSV: %v", err)
+	}
+	rows, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("failed to read CSV: %v", err)
+	}
+
+	wantHeader := "Workspace ID,Workspace,Group Path,Alice Admin <alice@example.com>,Bob Editor <bob@example.com>,Carol Contributor <carol@example.com>,Dave Disabled <dave@example.com> (disabled),Erin Pending <erin@example.com>"
+	if got := strings.Join(rows[0], ","); got != wantHeader {
+		t.Errorf("header = %q, want %q", got, wantHeader)
+	}
+
+	want := map[string]string{
+		"w-android": "w-android,Android App,Product > Mobile,read,write,comment,read,read",
+		"w-budget":  "w-budget,Budget,Finance,full,none,none,none,none",
+		"w-roadmap": "w-roadmap,Roadmap,Product,full,write,read,read,read",
+	}
+	for _, row := range rows[1:] {
+		if w, ok := want[row[0]]; ok {
+			if got := strings.Join(row, ","); got != w {
+				t.Errorf("row = %q, want %q", got, w)
+			}
+			delete(want, row[0])
+		}
+	}
+	for id := range want {
+		t.Errorf("matrix has no row for %s", id)
+	}
+}
+
+func TestAccessMatrixXLSX(t *testing.T) {
+	client, _ := newTestClient(t)
+
+	matrix, err := client.AccessMatrix(context.Background())
+	if err != nil {
+		t.Fatalf("AccessMatrix: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := matrix.WriteXLSX(&buf); err != nil {
+		t.Fatalf("WriteXLSX: %v", err)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("workbook is not a zip archive: %v", err)
+	}
+	parts := make(map[string]string)
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatalf("failed to open %s: %v", f.Name, err)
+		}
+		content, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatalf("failed to read %s: %v", f.Name, err)
+		}
+		parts[f.Name] = string(content)
+	}
+
+	for _, name := range []string{"[Content_Types].xml", "_rels/.rels", "xl/workbook.xml", "xl/_rels/workbook.xml.rels", "xl/worksheets/sheet1.xml"} {
+		if _, ok := parts[name]; !ok {
+			t.Errorf("workbook is missing %s", name)
+		}
+	}
+	sheet := parts["xl/worksheets/sheet1.xml"]
+	for _, want := range []string{
+		`<c r="A1" t="inlineStr"><is><t xml:space="preserve">Workspace ID</t></is></c>`,
+		`<t xml:space="preserve">Alice Admin &lt;alice@example.com&gt;</t>`,
+		`<c r="H1" t="inlineStr">`,
+		`<t xml:space="preserve">Product &gt; Mobile</t>`,
+	} {
+		if !strings.Contains(sheet, want) {
+			t.Errorf("sheet does not contain %s", want)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package airfocus
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// OffboardingPlan lists the changes that remove all of a user's explicit access
+type OffboardingPlan struct {
+	UserID      string         // User being offboarded
+	SuccessorID string         // User receiving "full" where needed, empty for none
+	Changes     []AccessChange // Grants to the successor first, then revocations
+	Orphaned    []string       // Workspaces and groups left without a "full" holder
+}
+
+// PlanOffboarding returns the changes that revoke every explicit workspace
+// and group permission of userID. Where the user is the only holder of
+// "full", successorID (if not empty) is granted "full" first so the workspace
+// or group keeps an owner, and the revocation requires that grant; without a
+// successor those are listed as Orphaned.
+// Nothing is changed; pass the plan's changes to ApplyAccessChanges.
+func (c *Client) PlanOffboarding(ctx context.Context, userID, successorID string) (OffboardingPlan, error) {
+	if userID == successorID {
+		return OffboardingPlan{}, fmt.Errorf("user %s cannot be their own successor", userID)
+	}
+	if _, err := c.GetUser(ctx, userID); err != nil {
+		return OffboardingPlan{}, err
+	}
+	if successorID != "" {
+		if _, err := c.GetUser(ctx, successorID); err != nil {
+			return OffboardingPlan{}, err
+		}
+	}
+
+	groups, err := c.ListWorkspaceGroups(ctx)
+	if err != nil {
+		return OffboardingPlan{}, fmt.Errorf("failed to list workspace groups: %w", err)
+	}
+	workspaces, err := c.ListWorkspaces(ctx)
+	if err != nil {
+		return OffboardingPlan{}, fmt.Errorf("failed to list workspaces: %w", err)
+	}
+
+	plan := OffboardingPlan{UserID: userID, SuccessorID: successorID}
+	var grants, revocations []AccessChange
+	add := func(targetType, targetID, targetName string, permissions map[string]string) {
+		current, ok := permissions[userID]
+		if !ok {
+			return
+		}
+		target := AccessChange{TargetType: targetType, TargetID: targetID, TargetName: targetName}
+		revocation := target
+		revocation.UserID, revocation.From = userID, Permission(current)
+
+		if Permission(current) == PermissionFull && !hasOtherFullHolder(permissions, userID) {
+			if successorID == "" {
+				plan.Orphaned = append(plan.Orphaned, targetName)
+			} else if successor := Permission(permissions[successorID]); successor != PermissionFull {
+				grant := target
+				grant.UserID, grant.From, grant.To = successorID, successor, PermissionFull
+				grants = append(grants, grant)
+				revocation.Requires = &grant
+			}
+		}
+
+		revocations = append(revocations, revocation)
+	}
+
+	for _, group := range groups {
+		add(TargetGroup, group.ID, group.Name, group.Embedded.Permissions)
+	}
+	for _, ws := range workspaces {
+		add(TargetWorkspace, ws.ID, ws.Name, ws.Embedded.Permissions)
+	}
+
+	sortAccessChanges(grants)
+	sortAccessChanges(revocations)
+	plan.Changes = append(grants, revocations...)
+	sort.Slice(plan.Orphaned, func(i, j int) bool {
+		return strings.ToLower(plan.Orphaned[i]) < strings.ToLower(plan.Orphaned[j])
+	})
+	return plan, nil
+}
+
+// hasOtherFullHolder reports whether anyone but userID holds "full" in permissions
+func hasOtherFullHolder(permissions map[string]string, userID string) bool {
+	for id, permission := range permissions {
+		if id != userID && Permission(permission) == PermissionFull {
+			return true
+		}
+	}
+	return false
+}
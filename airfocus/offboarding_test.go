@@ -0,0 +1,121 @@
+package airfocus_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/tibuski/goAirfocus/airfocus"
+)
+
+func TestPlanOffboarding(t *testing.T) {
+	client, _ := newTestClient(t)
+
+	tests := []struct {
+		name         string
+		userID       string
+		successorID  string
+		wantChanges  []airfocus.AccessChange
+		wantOrphaned []string
+	}{
+		{
+			name:   "without successor",
+			userID: "u-alice",
+			wantChanges: []airfocus.AccessChange{
+				{UserID: "u-alice", TargetType: "group", TargetID: "g-finance", TargetName: "Finance", From: "full"},
+				{UserID: "u-alice", TargetType: "workspace", TargetID: "w-budget", TargetName: "Budget", From: "full"},
+				{UserID: "u-alice", TargetType: "workspace", TargetID: "w-ios", TargetName: "iOS App", From: "full"},
+				{UserID: "u-alice", TargetType: "workspace", TargetID: "w-roadmap", TargetName: "Roadmap", From: "full"},
+			},
+			wantOrphaned: []string{"Budget", "Finance", "iOS App", "Roadmap"},
+		},
+		{
+			name:        "successor takes over full first",
+			userID:      "u-alice",
+			successorID: "u-bob",
+			wantChanges: []airfocus.AccessChange{
+				{UserID: "u-bob", TargetType: "group", TargetID: "g-finance", TargetName: "Finance", To: "full"},
+				{UserID: "u-bob", TargetType: "workspace", TargetID: "w-budget", TargetName: "Budget", To: "full"},
+				{UserID: "u-bob", TargetType: "workspace", TargetID: "w-ios", TargetName: "iOS App", To: "full"},
+				{UserID: "u-bob", TargetType: "workspace", TargetID: "w-roadmap", TargetName: "Roadmap", From: "write", To: "full"},
+				{UserID: "u-alice", TargetType: "group", TargetID: "g-finance", TargetName: "Finance", From: "full",
+					Requires: &airfocus.AccessChange{UserID: "u-bob", TargetType: "group", TargetID: "g-finance", TargetName: "Finance", To: "full"}},
+				{UserID: "u-alice", TargetType: "workspace", TargetID: "w-budget", TargetName: "Budget", From: "full",
+					Requires: &airfocus.AccessChange{UserID: "u-bob", TargetType: "workspace", TargetID: "w-budget", TargetName: "Budget", To: "full"}},
+				{UserID: "u-alice", TargetType: "workspace", TargetID: "w-ios", TargetName: "iOS App", From: "full",
+					Requires: &airfocus.AccessChange{UserID: "u-bob", TargetType: "workspace", TargetID: "w-ios", TargetName: "iOS App", To: "full"}},
+				{UserID: "u-alice", TargetType: "workspace", TargetID: "w-roadmap", TargetName: "Roadmap", From: "full",
+					Requires: &airfocus.AccessChange{UserID: "u-bob", TargetType: "workspace", TargetID: "w-roadmap", TargetName: "Roadmap", From: "write", To: "full"}},
+			},
+		},
+		{
+			name:   "no full permissions",
+			userID: "u-carol",
+			wantChanges: []airfocus.AccessChange{
+				{UserID: "u-carol", TargetType: "group", TargetID: "g-mobile", TargetName: "Mobile", From: "comment"},
+				{UserID: "u-carol", TargetType: "workspace", TargetID: "w-ios", TargetName: "iOS App", From: "comment"},
+				{UserID: "u-carol", TargetType: "workspace", TargetID: "w-roadmap", TargetName: "Roadmap", From: "read"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan, err := client.PlanOffboarding(context.Background(), tt.userID, tt.successorID)
+			if err != nil {
+				t.Fatalf("PlanOffboarding: %v", err)
+			}
+			if !reflect.DeepEqual(plan.Changes, tt.wantChanges) {
+				t.Errorf("changes = %+v\nwant %+v", plan.Changes, tt.wantChanges)
+			}
+			if !reflect.DeepEqual(plan.Orphaned, tt.wantOrphaned) {
+				t.Errorf("orphaned = %v, want %v", plan.Orphaned, tt.wantOrphaned)
+			}
+		})
+	}
+}
+
+func TestOffboardingRevokesAllAccess(t *testing.T) {
+	client, _ := newTestClient(t)
+	ctx := context.Background()
+
+	plan, err := client.PlanOffboarding(ctx, "u-alice", "u-carol")
+	if err != nil {
+		t.Fatalf("PlanOffboarding: %v", err)
+	}
+	for _, result := range client.ApplyAccessChanges(ctx, plan.Changes) {
+		if result.Err != nil {
+			t.Errorf("change on %s for %s failed: %v", result.TargetID, result.UserID, result.Err)
+		}
+	}
+
+	workspaces, err := client.GetUserWorkspaces(ctx, "u-alice")
+	if err != nil {
+		t.Fatalf("GetUserWorkspaces: %v", err)
+	}
+	if len(workspaces) != 0 {
+		t.Errorf("offboarded user still has workspace access: %+v", workspaces)
+	}
+	if got := userWorkspacePermission(t, client, "u-carol", "w-budget"); got != "full" {
+		t.Errorf("successor permission on w-budget = %q, want full", got)
+	}
+}
+
+func TestApplyAccessChangesSkipsWhenRequiredChangeFails(t *testing.T) {
+	client, _ := newTestClient(t)
+	ctx := context.Background()
+
+	grant := airfocus.AccessChange{UserID: "u-bob", TargetType: "workspace", TargetID: "w-missing", TargetName: "Missing", To: "full"}
+	revocation := airfocus.AccessChange{UserID: "u-alice", TargetType: "workspace", TargetID: "w-budget", TargetName: "Budget", From: "full", Requires: &grant}
+	results := client.ApplyAccessChanges(ctx, []airfocus.AccessChange{grant, revocation})
+
+	if !errors.Is(results[0].Err, airfocus.ErrNotFound) {
+		t.Errorf("grant error = %v, want ErrNotFound", results[0].Err)
+	}
+	if !errors.Is(results[1].Err, airfocus.ErrSkipped) {
+		t.Errorf("revocation error = %v, want ErrSkipped", results[1].Err)
+	}
+	if got := userWorkspacePermission(t, client, "u-alice", "w-budget"); got != "full" {
+		t.Errorf("permission on w-budget after skipped revocation = %q, want full", got)
+	}
+}
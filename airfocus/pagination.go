@@ -0,0 +1,72 @@
+package airfocus
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+)
+
+const (
+	// DefaultPageSize is the number of items requested per page from search endpoints
+	DefaultPageSize = 100
+
+	// maxPages guards against endpoints that ignore the offset and keep returning items
+	maxPages = 1000
+)
+
+// pageFetcher fetches one page of a search endpoint starting at offset. It
+// returns the page items and the total number of items reported by the API.
+type pageFetcher[T any] func(ctx context.Context, offset, limit int) (items []T, totalItems int, err error)
+
+// paginate walks a search endpoint page by page using offset/limit until
+// TotalItems is reached. id returns the unique ID of an item. complete is
+// false when the API stopped returning items before the reported total, or
+// returned items it had already returned (an endpoint that ignores the
+// offset), so callers can flag the result as partial.
+func paginate[T any](ctx context.Context, pageSize int, id func(T) string, fetch pageFetcher[T]) (items []T, complete bool, err error) {
+	if pageSize <= 0 {
+		pageSize = DefaultPageSize
+	}
+
+	seen := make(map[string]bool)
+	offset := 0
+	for page := 0; page < maxPages; page++ {
+		pageItems, total, err := fetch(ctx, offset, pageSize)
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to fetch page at offset %d: %w", offset, err)
+		}
+
+		if len(pageItems) > 0 && seen[id(pageItems[0])] {
+			// The API is returning a page it has already returned
+			return items, false, nil
+		}
+		added := 0
+		for _, item := range pageItems {
+			if !seen[id(item)] {
+				seen[id(item)] = true
+				items = append(items, item)
+				added++
+			}
+		}
+		offset += len(pageItems)
+
+		if offset >= total {
+			return items, true, nil
+		}
+		if added == 0 {
+			// The API reports more items than it is willing to return
+			return items, false, nil
+		}
+	}
+
+	return items, false, nil
+}
+
+// pagedURL appends offset and limit query parameters to a search endpoint URL
+func pagedURL(endpoint string, offset, limit int) string {
+	params := url.Values{}
+	params.Set("offset", strconv.Itoa(offset))
+	params.Set("limit", strconv.Itoa(limit))
+	return endpoint + "?" + params.Encode()
+}
@@ -0,0 +1,128 @@
+package airfocus_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httputil"
+	"net/url"
+	"reflect"
+	"testing"
+
+	"github.com/tibuski/goAirfocus/airfocus"
+	"github.com/tibuski/goAirfocus/airfocus/fake"
+)
+
+func TestPaginationWalksAllPages(t *testing.T) {
+	srv := fake.NewServer(fake.DefaultFixtures())
+	defer srv.Close()
+	srv.SetMaxPageSize(2)
+
+	client := airfocus.NewClient(fake.APIKey, airfocus.WithBaseURL(srv.URL), airfocus.WithPageSize(2))
+	ctx := context.Background()
+
+	fields, err := client.ListFields(ctx)
+	if err != nil {
+		t.Fatalf("ListFields: %v", err)
+	}
+	if len(fields) != 3 {
+		t.Errorf("got %d fields, want 3", len(fields))
+	}
+
+	workspaces, err := client.ListWorkspaces(ctx)
+	if err != nil {
+		t.Fatalf("ListWorkspaces: %v", err)
+	}
+	if len(workspaces) != 5 {
+		t.Errorf("got %d workspaces, want 5", len(workspaces))
+	}
+
+	tests := []struct {
+		path string
+		want int
+	}{
+		{"/fields/search", 2},
+		{"/workspaces/search", 3},
+		{"/workspaces/groups/search", 2},
+	}
+	for _, tt := range tests {
+		if n := srv.Requests("POST", tt.path); n != tt.want {
+			t.Errorf("POST %s requested %d times, want %d", tt.path, n, tt.want)
+		}
+	}
+
+	if incomplete := client.IncompleteResults(); len(incomplete) != 0 {
+		t.Errorf("IncompleteResults = %v, want none", incomplete)
+	}
+}
+
+func TestPaginationDetectsTruncation(t *testing.T) {
+	srv := fake.NewServer(fake.DefaultFixtures())
+	defer srv.Close()
+	target, _ := url.Parse(srv.URL)
+	proxy := httputil.NewSingleHostReverseProxy(target)
+
+	// Field search claims more items than it ever returns
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/fields/search" {
+			proxy.ServeHTTP(w, r)
+			return
+		}
+		resp := airfocus.FieldSearchResponse{TotalItems: 10}
+		if r.URL.Query().Get("offset") == "0" {
+			resp.Items = []airfocus.Field{{ID: "f-1", Name: "One"}, {ID: "f-2", Name: "Two"}}
+		}
+		json.NewEncoder(w).Encode(resp)
+	}))
+	defer api.Close()
+
+	client := airfocus.NewClient(fake.APIKey, airfocus.WithBaseURL(api.URL))
+	fields, err := client.ListFields(context.Background())
+	if err != nil {
+		t.Fatalf("ListFields: %v", err)
+	}
+	if len(fields) != 2 {
+		t.Errorf("got %d fields, want 2", len(fields))
+	}
+	if got, want := client.IncompleteResults(), []string{"fields"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("IncompleteResults = %v, want %v", got, want)
+	}
+}
+
+func TestPaginationStopsOnRepeatedPages(t *testing.T) {
+	srv := fake.NewServer(fake.DefaultFixtures())
+	defer srv.Close()
+	target, _ := url.Parse(srv.URL)
+	proxy := httputil.NewSingleHostReverseProxy(target)
+
+	// Field search ignores the offset and returns the first page every time
+	var fieldRequests int
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/fields/search" {
+			proxy.ServeHTTP(w, r)
+			return
+		}
+		fieldRequests++
+		json.NewEncoder(w).Encode(airfocus.FieldSearchResponse{
+			Items:      []airfocus.Field{{ID: "f-1", Name: "One"}, {ID: "f-2", Name: "Two"}},
+			TotalItems: 10,
+		})
+	}))
+	defer api.Close()
+
+	client := airfocus.NewClient(fake.APIKey, airfocus.WithBaseURL(api.URL), airfocus.WithPageSize(2))
+	fields, err := client.ListFields(context.Background())
+	if err != nil {
+		t.Fatalf("ListFields: %v", err)
+	}
+	if len(fields) != 2 {
+		t.Errorf("got %d fields, want 2", len(fields))
+	}
+	if fieldRequests != 2 {
+		t.Errorf("field search requested %d times, want 2", fieldRequests)
+	}
+	if got, want := client.IncompleteResults(), []string{"fields"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("IncompleteResults = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,139 @@
+package airfocus
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+)
+
+// Permissions lists the permission levels that can be granted, from lowest to highest.
+// PermissionNone is not among them; it ranks below all of them.
+var Permissions = []Permission{PermissionRead, PermissionComment, PermissionWrite, PermissionFull}
+
+// Valid reports whether p is one of the permission levels known to Airfocus
+func (p Permission) Valid() bool {
+	for _, known := range Permissions {
+		if p == known {
+			return true
+		}
+	}
+	return false
+}
+
+// rank orders permission levels; unknown levels and no permission rank lowest
+func (p Permission) rank() int {
+	for i, known := range Permissions {
+		if p == known {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// AtLeast reports whether p grants everything other grants. Every level,
+// including PermissionNone, is at least PermissionNone.
+func (p Permission) AtLeast(other Permission) bool {
+	return p.rank() >= other.rank()
+}
+
+// PermissionUpdate is the request body for granting or changing a permission
+type PermissionUpdate struct {
+	Permission Permission `json:"permission"` // New permission level
+}
+
+// SetWorkspacePermission grants userID the given permission on a workspace,
+// replacing any explicit permission the user already had there.
+func (c *Client) SetWorkspacePermission(ctx context.Context, workspaceID, userID string, permission Permission) error {
+	if !permission.Valid() {
+		return fmt.Errorf("permission %q: %w", permission, ErrInvalidPermission)
+	}
+	if err := c.doJSON(ctx, "PUT", workspacePermissionPath(workspaceID, userID), PermissionUpdate{Permission: permission}, nil); err != nil {
+		return fmt.Errorf("failed to set workspace permission: %w", err)
+	}
+	c.updateCachedWorkspacePermission(workspaceID, userID, permission)
+	return nil
+}
+
+// RemoveWorkspacePermission revokes userID's explicit permission on a
+// workspace. Access inherited from groups is not affected.
+func (c *Client) RemoveWorkspacePermission(ctx context.Context, workspaceID, userID string) error {
+	if err := c.doJSON(ctx, "DELETE", workspacePermissionPath(workspaceID, userID), nil, nil); err != nil {
+		return fmt.Errorf("failed to remove workspace permission: %w", err)
+	}
+	c.updateCachedWorkspacePermission(workspaceID, userID, "")
+	return nil
+}
+
+// SetGroupPermission grants userID the given permission on a workspace group,
+// replacing any explicit permission the user already had there.
+func (c *Client) SetGroupPermission(ctx context.Context, groupID, userID string, permission Permission) error {
+	if !permission.Valid() {
+		return fmt.Errorf("permission %q: %w", permission, ErrInvalidPermission)
+	}
+	if err := c.doJSON(ctx, "PUT", groupPermissionPath(groupID, userID), PermissionUpdate{Permission: permission}, nil); err != nil {
+		return fmt.Errorf("failed to set group permission: %w", err)
+	}
+	c.updateCachedGroupPermission(groupID, userID, permission)
+	return nil
+}
+
+// RemoveGroupPermission revokes userID's explicit permission on a workspace group
+func (c *Client) RemoveGroupPermission(ctx context.Context, groupID, userID string) error {
+	if err := c.doJSON(ctx, "DELETE", groupPermissionPath(groupID, userID), nil, nil); err != nil {
+		return fmt.Errorf("failed to remove group permission: %w", err)
+	}
+	c.updateCachedGroupPermission(groupID, userID, "")
+	return nil
+}
+
+func workspacePermissionPath(workspaceID, userID string) string {
+	return "/workspaces/" + url.PathEscape(workspaceID) + "/permissions/" + url.PathEscape(userID)
+}
+
+func groupPermissionPath(groupID, userID string) string {
+	return "/workspaces/groups/" + url.PathEscape(groupID) + "/permissions/" + url.PathEscape(userID)
+}
+
+// updateCachedWorkspacePermission applies a successful change to the cached
+// workspaces, so the next read reflects it without a full refresh. An empty
+// permission removes the user's entry.
+func (c *Client) updateCachedWorkspacePermission(workspaceID, userID string, permission Permission) {
+	c.cacheMutex.Lock()
+	defer c.cacheMutex.Unlock()
+
+	for i := range c.cache.workspaces {
+		ws := &c.cache.workspaces[i]
+		if ws.ID == workspaceID {
+			ws.Embedded.Permissions = withPermission(ws.Embedded.Permissions, userID, permission)
+		}
+	}
+}
+
+// updateCachedGroupPermission applies a successful change to the cached workspace groups
+func (c *Client) updateCachedGroupPermission(groupID, userID string, permission Permission) {
+	c.cacheMutex.Lock()
+	defer c.cacheMutex.Unlock()
+
+	for i := range c.cache.workspaceGroups {
+		group := &c.cache.workspaceGroups[i]
+		if group.ID == groupID {
+			group.Embedded.Permissions = withPermission(group.Embedded.Permissions, userID, permission)
+		}
+	}
+}
+
+// withPermission returns a copy of permissions with userID set to permission,
+// or removed if permission is empty. The original map is left untouched since
+// callers may still hold it through earlier copies of the cache.
+func withPermission(permissions map[string]string, userID string, permission Permission) map[string]string {
+	updated := make(map[string]string, len(permissions)+1)
+	for id, p := range permissions {
+		updated[id] = p
+	}
+	if permission == "" {
+		delete(updated, userID)
+	} else {
+		updated[userID] = string(permission)
+	}
+	return updated
+}
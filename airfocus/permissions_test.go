@@ -0,0 +1,108 @@
+package airfocus_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/tibuski/goAirfocus/airfocus"
+)
+
+func TestSetAndRemoveWorkspacePermission(t *testing.T) {
+	client, srv := newTestClient(t)
+	ctx := context.Background()
+
+	// Prime the cache so the change has to be applied to it in place
+	if _, err := client.ListWorkspaces(ctx); err != nil {
+		t.Fatalf("ListWorkspaces: %v", err)
+	}
+
+	if err := client.SetWorkspacePermission(ctx, "w-sandbox", "u-carol", airfocus.PermissionWrite); err != nil {
+		t.Fatalf("SetWorkspacePermission: %v", err)
+	}
+	if got := userWorkspacePermission(t, client, "u-carol", "w-sandbox"); got != "write" {
+		t.Errorf("cached permission = %q, want write", got)
+	}
+	ws, err := client.GetWorkspaceByID(ctx, "w-sandbox")
+	if err != nil {
+		t.Fatalf("GetWorkspaceByID: %v", err)
+	}
+	if got := ws.Embedded.Permissions["u-carol"]; got != "write" {
+		t.Errorf("API permission = %q, want write", got)
+	}
+
+	if err := client.RemoveWorkspacePermission(ctx, "w-sandbox", "u-carol"); err != nil {
+		t.Fatalf("RemoveWorkspacePermission: %v", err)
+	}
+	if got := userWorkspacePermission(t, client, "u-carol", "w-sandbox"); got != "" {
+		t.Errorf("cached permission = %q after removal, want none", got)
+	}
+	if n := srv.Requests("POST", "/workspaces/search"); n != 1 {
+		t.Errorf("workspaces fetched %d times, want 1", n)
+	}
+}
+
+func TestSetAndRemoveGroupPermission(t *testing.T) {
+	client, _ := newTestClient(t)
+	ctx := context.Background()
+
+	if err := client.SetGroupPermission(ctx, "g-finance", "u-bob", airfocus.PermissionComment); err != nil {
+		t.Fatalf("SetGroupPermission: %v", err)
+	}
+	if got := groupPermission(t, client, "g-finance", "u-bob"); got != "comment" {
+		t.Errorf("permission = %q, want comment", got)
+	}
+
+	if err := client.RemoveGroupPermission(ctx, "g-finance", "u-bob"); err != nil {
+		t.Fatalf("RemoveGroupPermission: %v", err)
+	}
+	if got := groupPermission(t, client, "g-finance", "u-bob"); got != "" {
+		t.Errorf("permission = %q after removal, want none", got)
+	}
+}
+
+func TestPermissionWriteErrors(t *testing.T) {
+	client, _ := newTestClient(t)
+	ctx := context.Background()
+
+	if err := client.SetWorkspacePermission(ctx, "w-roadmap", "u-bob", "owner"); !errors.Is(err, airfocus.ErrInvalidPermission) {
+		t.Errorf("invalid permission: error = %v, want ErrInvalidPermission", err)
+	}
+	if err := client.SetWorkspacePermission(ctx, "w-missing", "u-bob", airfocus.PermissionRead); !errors.Is(err, airfocus.ErrNotFound) {
+		t.Errorf("unknown workspace: error = %v, want ErrNotFound", err)
+	}
+	if err := client.RemoveGroupPermission(ctx, "g-missing", "u-bob"); !errors.Is(err, airfocus.ErrNotFound) {
+		t.Errorf("unknown group: error = %v, want ErrNotFound", err)
+	}
+}
+
+// userWorkspacePermission returns the explicit permission of userID on workspaceID as seen by GetUserWorkspaces
+func userWorkspacePermission(t *testing.T, client *airfocus.Client, userID, workspaceID string) string {
+	t.Helper()
+	workspaces, err := client.GetUserWorkspaces(context.Background(), userID)
+	if err != nil {
+		t.Fatalf("GetUserWorkspaces: %v", err)
+	}
+	for _, ws := range workspaces {
+		if ws.WorkspaceID == workspaceID {
+			return ws.Permission
+		}
+	}
+	return ""
+}
+
+// groupPermission returns the explicit permission of userID on groupID from the cached groups
+func groupPermission(t *testing.T, client *airfocus.Client, groupID, userID string) string {
+	t.Helper()
+	groups, err := client.ListWorkspaceGroups(context.Background())
+	if err != nil {
+		t.Fatalf("ListWorkspaceGroups: %v", err)
+	}
+	for _, group := range groups {
+		if group.ID == groupID {
+			return group.Embedded.Permissions[userID]
+		}
+	}
+	t.Fatalf("group %s not listed", groupID)
+	return ""
+}
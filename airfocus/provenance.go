@@ -0,0 +1,111 @@
+package airfocus
+
+// Kinds of PermissionSource
+const (
+	SourceWorkspaceGrant   = "workspace grant"   // Explicit permission on the workspace
+	SourceWorkspaceDefault = "workspace default" // Default permission of the workspace
+	SourceGroupGrant       = "group grant"       // Explicit permission on a group or one of its ancestors
+	SourceGroupDefault     = "group default"     // Default permission of a group or one of its ancestors
+)
+
+// PermissionSource is one input considered when deriving a user's effective
+// permission on a workspace or group
+type PermissionSource struct {
+	Kind       string     `json:"kind"`           // One of the Source constants
+	ID         string     `json:"id,omitempty"`   // ID of the workspace or group the permission comes from
+	Name       string     `json:"name,omitempty"` // Name of the workspace or group the permission comes from
+	Permission Permission `json:"permission"`     // Permission granted by this source
+	Winning    bool       `json:"winning"`        // Whether this source produced the effective permission
+}
+
+// EffectivePermission returns the permission userID effectively has on
+// workspace: the highest of the explicit workspace grant, the workspace's
+// default permission, and the grants and defaults of the workspace's group
+// and all its ancestors in groups. It returns PermissionNone if nothing
+// grants access. The trace lists every source considered, nearest first,
+// and marks the one that produced the result.
+func EffectivePermission(userID string, workspace Workspace, groups []WorkspaceGroup) (Permission, []PermissionSource) {
+	var trace []PermissionSource
+	if permission, ok := workspace.Embedded.Permissions[userID]; ok {
+		trace = append(trace, PermissionSource{Kind: SourceWorkspaceGrant, ID: workspace.ID, Name: workspace.Name, Permission: Permission(permission)})
+	}
+	if grantsAccess(workspace.DefaultPermission) {
+		trace = append(trace, PermissionSource{Kind: SourceWorkspaceDefault, ID: workspace.ID, Name: workspace.Name, Permission: Permission(workspace.DefaultPermission)})
+	}
+	trace = append(trace, groupPermissionSources(userID, workspace.GroupID, groups)...)
+	return resolvePermissionTrace(trace)
+}
+
+// EffectiveGroupPermission returns the permission userID effectively has on
+// the group with groupID: the highest of the grants and defaults of the group
+// and all its ancestors in groups. It returns PermissionNone if nothing grants
+// access. The trace is built as for EffectivePermission.
+func EffectiveGroupPermission(userID, groupID string, groups []WorkspaceGroup) (Permission, []PermissionSource) {
+	return resolvePermissionTrace(groupPermissionSources(userID, groupID, groups))
+}
+
+// EffectiveDefaultPermission returns the permission every team member has on
+// workspace without any grant of their own: the highest of the workspace's
+// default permission and the defaults of its group and all its ancestors.
+func EffectiveDefaultPermission(workspace Workspace, groups []WorkspaceGroup) (Permission, []PermissionSource) {
+	var trace []PermissionSource
+	if grantsAccess(workspace.DefaultPermission) {
+		trace = append(trace, PermissionSource{Kind: SourceWorkspaceDefault, ID: workspace.ID, Name: workspace.Name, Permission: Permission(workspace.DefaultPermission)})
+	}
+	for _, source := range groupPermissionSources("", workspace.GroupID, groups) {
+		if source.Kind == SourceGroupDefault {
+			trace = append(trace, source)
+		}
+	}
+	return resolvePermissionTrace(trace)
+}
+
+// groupPermissionSources collects the grants and defaults for userID on the
+// group with groupID and its ancestors, nearest group first
+func groupPermissionSources(userID, groupID string, groups []WorkspaceGroup) []PermissionSource {
+	groupMap := make(map[string]WorkspaceGroup, len(groups))
+	for _, group := range groups {
+		groupMap[group.ID] = group
+	}
+
+	var sources []PermissionSource
+	visited := make(map[string]bool)
+	for currentID := groupID; currentID != "" && !visited[currentID]; {
+		group, ok := groupMap[currentID]
+		if !ok {
+			break // Group not found, stop traversing
+		}
+		visited[currentID] = true
+
+		if permission, ok := group.Embedded.Permissions[userID]; ok {
+			sources = append(sources, PermissionSource{Kind: SourceGroupGrant, ID: group.ID, Name: group.Name, Permission: Permission(permission)})
+		}
+		if grantsAccess(group.DefaultPermission) {
+			sources = append(sources, PermissionSource{Kind: SourceGroupDefault, ID: group.ID, Name: group.Name, Permission: Permission(group.DefaultPermission)})
+		}
+		currentID = group.ParentID // Move up the hierarchy
+	}
+	return sources
+}
+
+// grantsAccess reports whether a default permission gives any access
+func grantsAccess(permission string) bool {
+	return permission != "" && Permission(permission) != PermissionNone
+}
+
+// resolvePermissionTrace returns the highest permission in trace and marks
+// the source that produced it. When several sources grant the same level,
+// the first one considered wins. An empty trace resolves to PermissionNone.
+func resolvePermissionTrace(trace []PermissionSource) (Permission, []PermissionSource) {
+	winner := -1
+	for i, source := range trace {
+		if source.Permission.rank() > 0 && (winner < 0 || source.Permission.rank() > trace[winner].Permission.rank()) {
+			winner = i
+		}
+	}
+	if winner < 0 {
+		return PermissionNone, trace
+	}
+	trace[winner].Winning = true
+	return trace[winner].Permission, trace
+}
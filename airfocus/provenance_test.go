@@ -0,0 +1,127 @@
+package airfocus_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/tibuski/goAirfocus/airfocus"
+)
+
+func TestEffectivePermission(t *testing.T) {
+	groups := []airfocus.WorkspaceGroup{
+		{ID: "g-root", Name: "Root", DefaultPermission: "read"},
+		{ID: "g-team", Name: "Team", ParentID: "g-root"},
+		{ID: "g-private", Name: "Private", DefaultPermission: "none"},
+		{ID: "g-loop-a", Name: "Loop A", ParentID: "g-loop-b"},
+		{ID: "g-loop-b", Name: "Loop B", ParentID: "g-loop-a"},
+	}
+	groups[1].Embedded.Permissions = map[string]string{"u-lead": "full"}
+
+	workspace := func(groupID, defaultPermission string, grants map[string]string) airfocus.Workspace {
+		ws := airfocus.Workspace{ID: "w-test", Name: "Test", GroupID: groupID, DefaultPermission: defaultPermission}
+		ws.Embedded.Permissions = grants
+		return ws
+	}
+
+	tests := []struct {
+		name       string
+		userID     string
+		workspace  airfocus.Workspace
+		want       airfocus.Permission
+		wantSource string
+	}{
+		{"no access at all", "u-guest", workspace("", "", nil), airfocus.PermissionNone, ""},
+		{"none default grants nothing", "u-guest", workspace("g-private", "none", nil), airfocus.PermissionNone, ""},
+		{"explicit workspace grant", "u-guest", workspace("", "", map[string]string{"u-guest": "comment"}), airfocus.PermissionComment, airfocus.SourceWorkspaceGrant},
+		{"workspace default", "u-guest", workspace("", "write", nil), airfocus.PermissionWrite, airfocus.SourceWorkspaceDefault},
+		{"ancestor group default", "u-guest", workspace("g-team", "", nil), airfocus.PermissionRead, airfocus.SourceGroupDefault},
+		{"group grant beats lower workspace grant", "u-lead", workspace("g-team", "", map[string]string{"u-lead": "read"}), airfocus.PermissionFull, airfocus.SourceGroupGrant},
+		{"equal levels keep the nearest source", "u-guest", workspace("g-team", "", map[string]string{"u-guest": "read"}), airfocus.PermissionRead, airfocus.SourceWorkspaceGrant},
+		{"parent cycle terminates", "u-guest", workspace("g-loop-a", "", nil), airfocus.PermissionNone, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, trace := airfocus.EffectivePermission(tt.userID, tt.workspace, groups)
+			if got != tt.want {
+				t.Errorf("permission = %q, want %q", got, tt.want)
+			}
+			winner := ""
+			for _, source := range trace {
+				if source.Winning {
+					if winner != "" {
+						t.Errorf("more than one winning source in %+v", trace)
+					}
+					winner = source.Kind
+				}
+			}
+			if winner != tt.wantSource {
+				t.Errorf("winning source = %q, want %q (trace %+v)", winner, tt.wantSource, trace)
+			}
+		})
+	}
+}
+
+func TestEffectiveDefaultPermission(t *testing.T) {
+	groups := []airfocus.WorkspaceGroup{
+		{ID: "g-root", Name: "Root", DefaultPermission: "read"},
+		{ID: "g-team", Name: "Team", ParentID: "g-root"},
+	}
+	groups[1].Embedded.Permissions = map[string]string{"u-lead": "full"}
+
+	ws := airfocus.Workspace{ID: "w-test", Name: "Test", GroupID: "g-team", DefaultPermission: "none"}
+	ws.Embedded.Permissions = map[string]string{"u-lead": "full"}
+	if got, _ := airfocus.EffectiveDefaultPermission(ws, groups); got != airfocus.PermissionRead {
+		t.Errorf("default permission = %q, want read from the root group, ignoring grants", got)
+	}
+	ws.DefaultPermission = "comment"
+	if got, _ := airfocus.EffectiveDefaultPermission(ws, groups); got != airfocus.PermissionComment {
+		t.Errorf("default permission = %q, want comment from the workspace", got)
+	}
+	if got, _ := airfocus.EffectiveDefaultPermission(airfocus.Workspace{ID: "w-private"}, groups); got != airfocus.PermissionNone {
+		t.Errorf("default permission of an ungrouped private workspace = %q, want none", got)
+	}
+
+	if !airfocus.PermissionFull.AtLeast(airfocus.PermissionWrite) || airfocus.PermissionRead.AtLeast(airfocus.PermissionComment) || !airfocus.PermissionNone.AtLeast(airfocus.PermissionNone) {
+		t.Errorf("AtLeast does not order permission levels")
+	}
+}
+
+func TestPermissionTrace(t *testing.T) {
+	client, _ := newTestClient(t)
+
+	groups, err := client.GetUserGroupAccess(context.Background(), "u-bob")
+	if err != nil {
+		t.Fatalf("GetUserGroupAccess: %v", err)
+	}
+	traces := make(map[string][]airfocus.PermissionSource)
+	for _, g := range groups {
+		traces[g.ID] = g.PermissionTrace
+		for _, ws := range g.Embedded.Workspaces {
+			traces[ws.ID] = ws.PermissionTrace
+		}
+	}
+
+	tests := []struct {
+		name     string
+		targetID string
+		want     []airfocus.PermissionSource
+	}{
+		{"inherited from parent group grant", "g-mobile", []airfocus.PermissionSource{
+			{Kind: airfocus.SourceGroupGrant, ID: "g-product", Name: "Product", Permission: "write", Winning: true},
+			{Kind: airfocus.SourceGroupDefault, ID: "g-product", Name: "Product", Permission: "read"},
+		}},
+		{"workspace grant beats group default", "w-android", []airfocus.PermissionSource{
+			{Kind: airfocus.SourceWorkspaceGrant, ID: "w-android", Name: "Android App", Permission: "write", Winning: true},
+			{Kind: airfocus.SourceGroupGrant, ID: "g-product", Name: "Product", Permission: "write"},
+			{Kind: airfocus.SourceGroupDefault, ID: "g-product", Name: "Product", Permission: "read"},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := traces[tt.targetID]; !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("trace = %+v\nwant %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,187 @@
+package airfocus
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	// DefaultMaxRetries is how often a failed request is retried when no WithRetry option is given
+	DefaultMaxRetries = 3
+
+	// DefaultRetryBaseDelay is the initial backoff delay when no WithRetry option is given
+	DefaultRetryBaseDelay = 500 * time.Millisecond
+
+	// DefaultRequestsPerSecond is the client-side rate limit when no WithRateLimit option is given
+	DefaultRequestsPerSecond = 10
+
+	// DefaultRateLimitBurst is the number of requests allowed in a burst when no WithRateLimit option is given
+	DefaultRateLimitBurst = 20
+
+	maxRetryDelay    = 30 * time.Second // Upper bound for a single backoff or Retry-After wait
+	maxErrorBodySize = 4096             // Number of response body bytes kept in an APIError
+)
+
+// WithRetry sets how often failed requests are retried and the initial
+// backoff delay. Requests are retried on network errors, truncated responses,
+// 429 and transient 5xx responses with exponential backoff and jitter. Use 0
+// retries to disable.
+func WithRetry(maxRetries int, baseDelay time.Duration) Option {
+	return func(c *Client) {
+		if maxRetries >= 0 {
+			c.maxRetries = maxRetries
+		}
+		if baseDelay > 0 {
+			c.retryBaseDelay = baseDelay
+		}
+	}
+}
+
+// WithRateLimit limits the client to requestsPerSecond with bursts of up to
+// burst requests. A non-positive rate disables client-side rate limiting.
+func WithRateLimit(requestsPerSecond float64, burst int) Option {
+	return func(c *Client) {
+		c.limiter = newRateLimiter(requestsPerSecond, burst)
+	}
+}
+
+// doJSON sends a request to the API and decodes the JSON response into out.
+// body, if not nil, is sent as JSON. Failed requests are retried according
+// to the client's retry settings; the final failure is returned as an
+// *APIError when the API answered with an error status.
+func (c *Client) doJSON(ctx context.Context, method, path string, body, out interface{}) error {
+	var payload []byte
+	if body != nil {
+		var err error
+		if payload, err = json.Marshal(body); err != nil {
+			return fmt.Errorf("failed to marshal request body: %w", err)
+		}
+	}
+
+	for attempt := 0; ; attempt++ {
+		if err := c.limiter.Wait(ctx); err != nil {
+			return err
+		}
+
+		err := c.doOnce(ctx, method, path, payload, out)
+		if err == nil {
+			return nil
+		}
+
+		delay, retry := c.retryDelay(ctx, err, attempt)
+		if !retry {
+			return err
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+}
+
+// doOnce performs a single HTTP round trip
+func (c *Client) doOnce(ctx context.Context, method, path string, payload []byte, out interface{}) error {
+	var reqBody io.Reader
+	if payload != nil {
+		reqBody = bytes.NewReader(payload)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, reqBody)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	req.Header.Set("Accept", "application/json")
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodySize))
+		return &APIError{
+			StatusCode: resp.StatusCode,
+			Method:     method,
+			Endpoint:   path,
+			Body:       string(respBody),
+			RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After")),
+		}
+	}
+
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
+
+// retryDelay decides whether err is worth retrying and how long to wait first.
+// Only transport errors, truncated responses and the transient statuses are
+// retried; a response that does not decode would fail the same way again.
+func (c *Client) retryDelay(ctx context.Context, err error, attempt int) (time.Duration, bool) {
+	if attempt >= c.maxRetries || ctx.Err() != nil {
+		return 0, false
+	}
+
+	var apiErr *APIError
+	var netErr net.Error
+	switch {
+	case errors.As(err, &apiErr):
+		switch apiErr.StatusCode {
+		case http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusBadGateway,
+			http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		default:
+			return 0, false
+		}
+		if apiErr.RetryAfter > 0 {
+			return min(apiErr.RetryAfter, maxRetryDelay), true
+		}
+	case errors.As(err, &netErr), errors.Is(err, io.ErrUnexpectedEOF):
+	default:
+		return 0, false
+	}
+
+	// Exponential backoff with jitter: a random delay in [d/2, d)
+	d := c.retryBaseDelay << attempt
+	if d <= 0 || d > maxRetryDelay {
+		d = maxRetryDelay
+	}
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1)), true
+}
+
+// parseRetryAfter reads a Retry-After header given in seconds or as an HTTP date
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		if d := time.Until(t); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
@@ -0,0 +1,181 @@
+package airfocus_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httputil"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/tibuski/goAirfocus/airfocus"
+	"github.com/tibuski/goAirfocus/airfocus/fake"
+)
+
+// flakyAPI fronts a fake API and answers the first n requests with status
+func flakyAPI(t *testing.T, n int, status int, retryAfter string) (*httptest.Server, func() int) {
+	t.Helper()
+	srv := fake.NewServer(fake.DefaultFixtures())
+	t.Cleanup(srv.Close)
+	target, _ := url.Parse(srv.URL)
+	proxy := httputil.NewSingleHostReverseProxy(target)
+
+	var mu sync.Mutex
+	calls := 0
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		calls++
+		fail := calls <= n
+		mu.Unlock()
+		if fail {
+			if retryAfter != "" {
+				w.Header().Set("Retry-After", retryAfter)
+			}
+			http.Error(w, "try again later", status)
+			return
+		}
+		proxy.ServeHTTP(w, r)
+	}))
+	t.Cleanup(api.Close)
+
+	return api, func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		return calls
+	}
+}
+
+func TestRetryTransientErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+	}{
+		{"service unavailable", http.StatusServiceUnavailable},
+		{"bad gateway", http.StatusBadGateway},
+		{"too many requests", http.StatusTooManyRequests},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api, calls := flakyAPI(t, 2, tt.status, "")
+			client := airfocus.NewClient(fake.APIKey, airfocus.WithBaseURL(api.URL), airfocus.WithRetry(3, time.Millisecond))
+
+			if _, err := client.GetTeam(context.Background()); err != nil {
+				t.Fatalf("GetTeam: %v", err)
+			}
+			if got := calls(); got != 3 {
+				t.Errorf("made %d requests, want 3", got)
+			}
+		})
+	}
+}
+
+func TestRetryHonoursRetryAfter(t *testing.T) {
+	api, calls := flakyAPI(t, 1, http.StatusTooManyRequests, "1")
+	client := airfocus.NewClient(fake.APIKey, airfocus.WithBaseURL(api.URL), airfocus.WithRetry(1, time.Millisecond))
+
+	start := time.Now()
+	if _, err := client.GetTeam(context.Background()); err != nil {
+		t.Fatalf("GetTeam: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < time.Second {
+		t.Errorf("retried after %v, want at least the 1s Retry-After", elapsed)
+	}
+	if got := calls(); got != 2 {
+		t.Errorf("made %d requests, want 2", got)
+	}
+}
+
+func TestRetryGivesUpWithAPIError(t *testing.T) {
+	api, calls := flakyAPI(t, 10, http.StatusServiceUnavailable, "")
+	client := airfocus.NewClient(fake.APIKey, airfocus.WithBaseURL(api.URL), airfocus.WithRetry(2, time.Millisecond))
+
+	_, err := client.GetTeam(context.Background())
+	var apiErr *airfocus.APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("error = %v, want *APIError", err)
+	}
+	if apiErr.StatusCode != http.StatusServiceUnavailable || apiErr.Endpoint != "/team" || apiErr.Method != "GET" {
+		t.Errorf("APIError = %+v, want GET /team with status 503", apiErr)
+	}
+	if got := calls(); got != 3 {
+		t.Errorf("made %d requests, want 3", got)
+	}
+}
+
+func TestNoRetryOnClientErrors(t *testing.T) {
+	api, calls := flakyAPI(t, 10, http.StatusNotFound, "")
+	client := airfocus.NewClient(fake.APIKey, airfocus.WithBaseURL(api.URL), airfocus.WithRetry(3, time.Millisecond))
+
+	if _, err := client.GetTeam(context.Background()); err == nil {
+		t.Fatal("expected an error")
+	}
+	if got := calls(); got != 1 {
+		t.Errorf("made %d requests, want 1", got)
+	}
+}
+
+func TestNoRetryOnDecodeErrors(t *testing.T) {
+	calls := 0
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte("<html>not json</html>"))
+	}))
+	defer api.Close()
+	client := airfocus.NewClient(fake.APIKey, airfocus.WithBaseURL(api.URL), airfocus.WithRetry(3, time.Millisecond))
+
+	if _, err := client.GetTeam(context.Background()); err == nil {
+		t.Fatal("expected an error")
+	}
+	if calls != 1 {
+		t.Errorf("made %d requests, want 1", calls)
+	}
+}
+
+func TestRetryTruncatedResponses(t *testing.T) {
+	srv := fake.NewServer(fake.DefaultFixtures())
+	defer srv.Close()
+	target, _ := url.Parse(srv.URL)
+	proxy := httputil.NewSingleHostReverseProxy(target)
+
+	calls := 0
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"id":"t-1","na`))
+			return
+		}
+		proxy.ServeHTTP(w, r)
+	}))
+	defer api.Close()
+	client := airfocus.NewClient(fake.APIKey, airfocus.WithBaseURL(api.URL), airfocus.WithRetry(3, time.Millisecond))
+
+	if _, err := client.GetTeam(context.Background()); err != nil {
+		t.Fatalf("GetTeam: %v", err)
+	}
+	if calls != 2 {
+		t.Errorf("made %d requests, want 2", calls)
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	srv := fake.NewServer(fake.DefaultFixtures())
+	defer srv.Close()
+
+	// One request up front, then one every 50ms
+	client := airfocus.NewClient(fake.APIKey, airfocus.WithBaseURL(srv.URL), airfocus.WithRateLimit(20, 1))
+
+	start := time.Now()
+	for i := 0; i < 5; i++ {
+		if _, err := client.GetWorkspaceByID(context.Background(), "w-roadmap"); err != nil {
+			t.Fatalf("GetWorkspaceByID: %v", err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 180*time.Millisecond {
+		t.Errorf("5 requests took %v, want at least 200ms at 20 requests/s", elapsed)
+	}
+}
@@ -0,0 +1,134 @@
+package airfocus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Snapshot is the state of a team at one point in time: everything the
+// client caches, including the explicit permissions embedded in workspaces
+// and workspace groups, and the license seats.
+type Snapshot struct {
+	TakenAt    time.Time                 `json:"takenAt"`              // When the data was fetched
+	Team       TeamLicenseInfo           `json:"team"`                 // Team and license seats
+	Users      []User                    `json:"users"`                // All users
+	Workspaces []Workspace               `json:"workspaces"`           // All workspaces with their permissions
+	Groups     []WorkspaceGroup          `json:"groups"`               // All workspace groups with their permissions
+	Fields     []FieldWithWorkspaceNames `json:"fields"`               // All fields with the workspaces using them
+	Incomplete []string                  `json:"incomplete,omitempty"` // Lists that came back shorter than reported
+}
+
+// FallbackFunc returns the most recent snapshot to serve from when the API
+// cannot be reached
+type FallbackFunc func(ctx context.Context) (*Snapshot, error)
+
+// WithFallback makes the client serve data from a snapshot when refreshing
+// its cache fails, e.g. because the API is down or too slow. Authentication
+// errors and cancelled requests are still returned, so a revoked key is not
+// masked by old data. Only a client that has fetched its team before falls
+// back, and only onto a snapshot of that team. StaleSince reports when the
+// client is serving fallback data.
+func WithFallback(fallback FallbackFunc) Option {
+	return func(c *Client) {
+		c.fallback = fallback
+	}
+}
+
+// Snapshot captures the current state of the team. Data cached within the
+// client's cache TTL is reused.
+func (c *Client) Snapshot(ctx context.Context) (*Snapshot, error) {
+	if err := c.RefreshCacheIfNeeded(ctx); err != nil {
+		return nil, err
+	}
+	team, err := c.GetTeam(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	c.cacheMutex.RLock()
+	defer c.cacheMutex.RUnlock()
+	if !c.cache.staleSince.IsZero() {
+		return nil, fmt.Errorf("cannot snapshot fallback data from %s", c.cache.staleSince.Format(time.RFC3339))
+	}
+	return &Snapshot{
+		TakenAt:    c.cache.lastUpdate.UTC(),
+		Team:       team,
+		Users:      append([]User(nil), c.cache.users...),
+		Workspaces: append([]Workspace(nil), c.cache.workspaces...),
+		Groups:     append([]WorkspaceGroup(nil), c.cache.workspaceGroups...),
+		Fields:     append([]FieldWithWorkspaceNames(nil), c.cache.fields...),
+		Incomplete: append([]string(nil), c.cache.incomplete...),
+	}, nil
+}
+
+// StaleSince returns when the data the client is serving was captured if
+// it comes from a fallback snapshot, or the zero time when it is live.
+func (c *Client) StaleSince() time.Time {
+	c.cacheMutex.RLock()
+	defer c.cacheMutex.RUnlock()
+	return c.cache.staleSince
+}
+
+// useFallbackLocked replaces the cache with the fallback snapshot after a
+// failed refresh, unless the cache already holds newer data. It returns
+// false when there is no usable fallback; the caller must hold the write lock.
+func (c *Client) useFallbackLocked(ctx context.Context, refreshErr error) bool {
+	if !c.mayFallBack(refreshErr) || c.cache.team == nil {
+		return false
+	}
+
+	snapshot, err := c.fallback(ctx)
+	if err != nil || snapshot == nil || snapshot.Team.TeamID != c.cache.team.TeamID {
+		return false
+	}
+
+	// Keep whichever data is newer: the snapshot or what was fetched before the API failed
+	cachedAt := c.cache.lastUpdate
+	if !c.cache.staleSince.IsZero() {
+		cachedAt = c.cache.staleSince
+	}
+	if cachedAt.IsZero() || snapshot.TakenAt.After(cachedAt) {
+		c.cache.users = snapshot.Users
+		c.cache.workspaces = snapshot.Workspaces
+		c.cache.fields = snapshot.Fields
+		c.cache.workspaceGroups = snapshot.Groups
+		c.cache.incomplete = snapshot.Incomplete
+		cachedAt = snapshot.TakenAt
+	}
+	c.cache.staleSince = cachedAt
+
+	// Try the API again after the cache TTL rather than on every request
+	c.cache.lastUpdate = time.Now()
+	return true
+}
+
+// fallbackTeam returns the team to serve after fetching it failed: the one
+// fetched before. A client that never fetched its team has nothing to serve.
+func (c *Client) fallbackTeam(fetchErr error) (TeamLicenseInfo, bool) {
+	if !c.mayFallBack(fetchErr) {
+		return TeamLicenseInfo{}, false
+	}
+
+	c.cacheMutex.RLock()
+	defer c.cacheMutex.RUnlock()
+	if c.cache.team == nil {
+		return TeamLicenseInfo{}, false
+	}
+	return *c.cache.team, true
+}
+
+// mayFallBack reports whether err is a failure fallback data may stand in
+// for: not a rejected key, and not a request the caller gave up on
+func (c *Client) mayFallBack(err error) bool {
+	switch {
+	case c.fallback == nil:
+		return false
+	case errors.Is(err, ErrUnauthorized), errors.Is(err, ErrForbidden):
+		return false
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return false
+	}
+	return true
+}
@@ -0,0 +1,193 @@
+package airfocus_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httputil"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/tibuski/goAirfocus/airfocus"
+	"github.com/tibuski/goAirfocus/airfocus/fake"
+)
+
+func TestSnapshot(t *testing.T) {
+	client, _ := newTestClient(t)
+
+	snap, err := client.Snapshot(context.Background())
+	if err != nil {
+		t.Fatalf("Snapshot: %v", err)
+	}
+	if snap.TakenAt.IsZero() || snap.Team.Name != "Acme" {
+		t.Errorf("snapshot taken at %v of team %q", snap.TakenAt, snap.Team.Name)
+	}
+	if len(snap.Users) != 5 || len(snap.Workspaces) == 0 || len(snap.Groups) == 0 || len(snap.Fields) == 0 {
+		t.Errorf("snapshot has %d users, %d workspaces, %d groups, %d fields", len(snap.Users), len(snap.Workspaces), len(snap.Groups), len(snap.Fields))
+	}
+	for _, ws := range snap.Workspaces {
+		if ws.ID == "w-roadmap" && len(ws.Embedded.Permissions) == 0 {
+			t.Error("snapshot lacks the explicit workspace permissions")
+		}
+	}
+}
+
+// switchableAPI serves the fake API until down is set, then fails with status
+func switchableAPI(t *testing.T, status int) (*httptest.Server, *atomic.Bool) {
+	t.Helper()
+	srv := fake.NewServer(fake.DefaultFixtures())
+	t.Cleanup(srv.Close)
+	target, _ := url.Parse(srv.URL)
+	proxy := httputil.NewSingleHostReverseProxy(target)
+
+	down := new(atomic.Bool)
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if down.Load() {
+			http.Error(w, `{"message":"unavailable"}`, status)
+			return
+		}
+		proxy.ServeHTTP(w, r)
+	}))
+	t.Cleanup(api.Close)
+	return api, down
+}
+
+func TestFallbackServesSnapshot(t *testing.T) {
+	api, down := switchableAPI(t, http.StatusServiceUnavailable)
+	ctx := context.Background()
+
+	live := airfocus.NewClient(fake.APIKey, airfocus.WithBaseURL(api.URL))
+	snap, err := live.Snapshot(ctx)
+	if err != nil {
+		t.Fatalf("Snapshot: %v", err)
+	}
+	snap.TakenAt = time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	client := airfocus.NewClient(fake.APIKey,
+		airfocus.WithBaseURL(api.URL),
+		airfocus.WithRetry(0, 0),
+		airfocus.WithCacheTTL(0),
+		airfocus.WithFallback(func(context.Context) (*airfocus.Snapshot, error) { return snap, nil }),
+	)
+	if _, err := client.GetTeam(ctx); err != nil {
+		t.Fatalf("GetTeam: %v", err)
+	}
+
+	down.Store(true)
+	users, err := client.ListUsers(ctx)
+	if err != nil {
+		t.Fatalf("ListUsers with the API down: %v", err)
+	}
+	if len(users) != 5 {
+		t.Errorf("got %d users from the snapshot, want 5", len(users))
+	}
+	if team, err := client.GetTeam(ctx); err != nil || team.Name != "Acme" {
+		t.Errorf("GetTeam with the API down = %q, %v", team.Name, err)
+	}
+	if got := client.StaleSince(); !got.Equal(snap.TakenAt) {
+		t.Errorf("StaleSince = %v, want %v", got, snap.TakenAt)
+	}
+	if _, err := client.Snapshot(ctx); err == nil {
+		t.Error("Snapshot of fallback data succeeded")
+	}
+
+	// Once the API is back the client serves live data again
+	down.Store(false)
+	if _, err := client.ListUsers(ctx); err != nil {
+		t.Fatalf("ListUsers after recovery: %v", err)
+	}
+	if got := client.StaleSince(); !got.IsZero() {
+		t.Errorf("StaleSince after recovery = %v, want zero", got)
+	}
+}
+
+func TestFallbackKeepsAuthErrors(t *testing.T) {
+	api, down := switchableAPI(t, http.StatusUnauthorized)
+	down.Store(true)
+
+	fallbackUsed := false
+	client := airfocus.NewClient(fake.APIKey,
+		airfocus.WithBaseURL(api.URL),
+		airfocus.WithFallback(func(context.Context) (*airfocus.Snapshot, error) {
+			fallbackUsed = true
+			return &airfocus.Snapshot{TakenAt: time.Now()}, nil
+		}),
+	)
+	if _, err := client.ListUsers(context.Background()); !errors.Is(err, airfocus.ErrUnauthorized) {
+		t.Errorf("ListUsers error = %v, want ErrUnauthorized", err)
+	}
+	if fallbackUsed {
+		t.Error("fallback was used for an authentication error")
+	}
+}
+
+func TestFallbackNeedsTheClientsTeam(t *testing.T) {
+	api, down := switchableAPI(t, http.StatusServiceUnavailable)
+	ctx := context.Background()
+
+	live := airfocus.NewClient(fake.APIKey, airfocus.WithBaseURL(api.URL))
+	snap, err := live.Snapshot(ctx)
+	if err != nil {
+		t.Fatalf("Snapshot: %v", err)
+	}
+	newClient := func(snap *airfocus.Snapshot) *airfocus.Client {
+		return airfocus.NewClient(fake.APIKey,
+			airfocus.WithBaseURL(api.URL),
+			airfocus.WithRetry(0, 0),
+			airfocus.WithCacheTTL(0),
+			airfocus.WithFallback(func(context.Context) (*airfocus.Snapshot, error) { return snap, nil }),
+		)
+	}
+
+	// A key that never succeeded is not vouched for by the snapshot
+	down.Store(true)
+	unknown := newClient(snap)
+	if _, err := unknown.ListUsers(ctx); err == nil {
+		t.Error("ListUsers of an unknown key with the API down served snapshot data")
+	}
+	if _, err := unknown.GetTeam(ctx); err == nil {
+		t.Error("GetTeam of an unknown key with the API down served snapshot data")
+	}
+
+	// A snapshot of another team is not served
+	down.Store(false)
+	other := *snap
+	other.Team.TeamID = "t-other"
+	client := newClient(&other)
+	if _, err := client.GetTeam(ctx); err != nil {
+		t.Fatalf("GetTeam: %v", err)
+	}
+	down.Store(true)
+	if _, err := client.ListUsers(ctx); err == nil {
+		t.Error("ListUsers with the API down served a snapshot of another team")
+	}
+}
+
+func TestFallbackKeepsCancellation(t *testing.T) {
+	api, _ := switchableAPI(t, http.StatusServiceUnavailable)
+
+	fallbackUsed := false
+	client := airfocus.NewClient(fake.APIKey,
+		airfocus.WithBaseURL(api.URL),
+		airfocus.WithCacheTTL(0),
+		airfocus.WithFallback(func(context.Context) (*airfocus.Snapshot, error) {
+			fallbackUsed = true
+			return &airfocus.Snapshot{TakenAt: time.Now()}, nil
+		}),
+	)
+	if _, err := client.GetTeam(context.Background()); err != nil {
+		t.Fatalf("GetTeam: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := client.ListUsers(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("ListUsers error = %v, want context.Canceled", err)
+	}
+	if fallbackUsed {
+		t.Error("fallback was used for a cancelled request")
+	}
+}
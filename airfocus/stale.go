@@ -0,0 +1,172 @@
+package airfocus
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Categories of stale access, in the order they are reported
+const (
+	StaleDisabled = "disabled" // Held by a disabled user
+	StalePending  = "pending"  // Held by a user who has not accepted their invitation
+	StaleUnseated = "unseated" // Held by a user without a seat
+	StaleUnknown  = "unknown"  // Held by a user who no longer exists
+)
+
+// StaleCategories lists the categories of stale access in report order
+var StaleCategories = []string{StaleDisabled, StalePending, StaleUnseated, StaleUnknown}
+
+// StaleGrant is an explicit permission held by a user who cannot use it.
+// The embedded change revokes it.
+type StaleGrant struct {
+	AccessChange
+	Category  string // One of the Stale constants
+	UserName  string // "Unknown User" for users who no longer exist
+	UserEmail string
+}
+
+// StaleAccessReport lists access that should be cleaned up
+type StaleAccessReport struct {
+	Grants    []StaleGrant // By category, then user name, groups before workspaces
+	Ownerless []Workspace  // Workspaces no active user effectively holds "full" on, by name
+}
+
+// StaleCategory returns the category of stale access user's permissions
+// fall in, or "" for an active user. A disabled user is reported as
+// disabled even if they are also pending or unseated.
+func StaleCategory(user User) string {
+	switch {
+	case user.Disabled:
+		return StaleDisabled
+	case user.State != nil && user.State.Pending:
+		return StalePending
+	case user.State != nil && user.State.Unseated:
+		return StaleUnseated
+	}
+	return ""
+}
+
+// StaleAccess finds every explicit workspace and group permission held by
+// disabled, pending, unseated or unknown users, and every workspace that no
+// active user effectively holds "full" on. Nothing is changed; use
+// Revocations and AssignOwner to plan the cleanup.
+func (c *Client) StaleAccess(ctx context.Context) (StaleAccessReport, error) {
+	users, err := c.ListUsers(ctx)
+	if err != nil {
+		return StaleAccessReport{}, fmt.Errorf("failed to list users: %w", err)
+	}
+	groups, err := c.ListWorkspaceGroups(ctx)
+	if err != nil {
+		return StaleAccessReport{}, fmt.Errorf("failed to list workspace groups: %w", err)
+	}
+	workspaces, err := c.ListWorkspaces(ctx)
+	if err != nil {
+		return StaleAccessReport{}, fmt.Errorf("failed to list workspaces: %w", err)
+	}
+
+	usersByID := make(map[string]User, len(users))
+	for _, user := range users {
+		usersByID[user.UserID] = user
+	}
+
+	var report StaleAccessReport
+	add := func(targetType, targetID, targetName string, permissions map[string]string) {
+		for userID, permission := range permissions {
+			grant := StaleGrant{
+				AccessChange: AccessChange{UserID: userID, TargetType: targetType, TargetID: targetID, TargetName: targetName, From: Permission(permission)},
+				Category:     StaleUnknown,
+				UserName:     "Unknown User",
+			}
+			if user, ok := usersByID[userID]; ok {
+				if grant.Category = StaleCategory(user); grant.Category == "" {
+					continue
+				}
+				grant.UserName, grant.UserEmail = user.FullName, user.Email
+			}
+			report.Grants = append(report.Grants, grant)
+		}
+	}
+	for _, group := range groups {
+		add(TargetGroup, group.ID, group.Name, group.Embedded.Permissions)
+	}
+	for _, ws := range workspaces {
+		add(TargetWorkspace, ws.ID, ws.Name, ws.Embedded.Permissions)
+
+		owned := false
+		for _, user := range users {
+			if StaleCategory(user) != "" {
+				continue
+			}
+			if permission, _ := EffectivePermission(user.UserID, ws, groups); permission == PermissionFull {
+				owned = true
+				break
+			}
+		}
+		if !owned {
+			report.Ownerless = append(report.Ownerless, ws)
+		}
+	}
+
+	order := make(map[string]int, len(StaleCategories))
+	for i, category := range StaleCategories {
+		order[category] = i
+	}
+	sort.SliceStable(report.Grants, func(i, j int) bool {
+		a, b := report.Grants[i], report.Grants[j]
+		switch {
+		case a.Category != b.Category:
+			return order[a.Category] < order[b.Category]
+		case !strings.EqualFold(a.UserName, b.UserName):
+			return strings.ToLower(a.UserName) < strings.ToLower(b.UserName)
+		case a.UserID != b.UserID:
+			return a.UserID < b.UserID
+		case a.TargetType != b.TargetType:
+			return a.TargetType == TargetGroup
+		}
+		return strings.ToLower(a.TargetName) < strings.ToLower(b.TargetName)
+	})
+	sort.Slice(report.Ownerless, func(i, j int) bool {
+		return strings.ToLower(report.Ownerless[i].Name) < strings.ToLower(report.Ownerless[j].Name)
+	})
+	return report, nil
+}
+
+// InCategory returns the stale grants of one category
+func (r StaleAccessReport) InCategory(category string) []StaleGrant {
+	var grants []StaleGrant
+	for _, grant := range r.Grants {
+		if grant.Category == category {
+			grants = append(grants, grant)
+		}
+	}
+	return grants
+}
+
+// Revocations returns the changes that revoke every stale grant of one
+// category; pass them to ApplyAccessChanges
+func (r StaleAccessReport) Revocations(category string) []AccessChange {
+	var changes []AccessChange
+	for _, grant := range r.InCategory(category) {
+		changes = append(changes, grant.AccessChange)
+	}
+	return changes
+}
+
+// AssignOwner returns the changes that grant userID "full" on every
+// ownerless workspace; pass them to ApplyAccessChanges
+func (r StaleAccessReport) AssignOwner(userID string) []AccessChange {
+	changes := make([]AccessChange, 0, len(r.Ownerless))
+	for _, ws := range r.Ownerless {
+		changes = append(changes, AccessChange{
+			UserID:     userID,
+			TargetType: TargetWorkspace,
+			TargetID:   ws.ID,
+			TargetName: ws.Name,
+			From:       Permission(ws.Embedded.Permissions[userID]),
+			To:         PermissionFull,
+		})
+	}
+	return changes
+}
@@ -0,0 +1,120 @@
+package airfocus_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/tibuski/goAirfocus/airfocus"
+	"github.com/tibuski/goAirfocus/airfocus/fake"
+)
+
+// newStaleTestClient returns a client for the sample tenant with access
+// held by the disabled and the pending user
+func newStaleTestClient(t *testing.T) *airfocus.Client {
+	t.Helper()
+	fixtures := fake.DefaultFixtures()
+	for i := range fixtures.Workspaces {
+		if fixtures.Workspaces[i].ID == "w-roadmap" {
+			fixtures.Workspaces[i].Embedded.Permissions["u-dave"] = "write"
+		}
+	}
+	for i := range fixtures.Groups {
+		if fixtures.Groups[i].ID == "g-finance" {
+			fixtures.Groups[i].Embedded.Permissions["u-erin"] = "read"
+		}
+	}
+	srv := fake.NewServer(fixtures)
+	t.Cleanup(srv.Close)
+	return airfocus.NewClient(fake.APIKey, airfocus.WithBaseURL(srv.URL))
+}
+
+func TestStaleAccess(t *testing.T) {
+	client := newStaleTestClient(t)
+
+	report, err := client.StaleAccess(context.Background())
+	if err != nil {
+		t.Fatalf("StaleAccess: %v", err)
+	}
+
+	want := []airfocus.StaleGrant{
+		{
+			AccessChange: airfocus.AccessChange{UserID: "u-dave", TargetType: "workspace", TargetID: "w-roadmap", TargetName: "Roadmap", From: "write"},
+			Category:     airfocus.StaleDisabled, UserName: "Dave Disabled", UserEmail: "dave@example.com",
+		},
+		{
+			AccessChange: airfocus.AccessChange{UserID: "u-erin", TargetType: "group", TargetID: "g-finance", TargetName: "Finance", From: "read"},
+			Category:     airfocus.StalePending, UserName: "Erin Pending", UserEmail: "erin@example.com",
+		},
+		{
+			AccessChange: airfocus.AccessChange{UserID: "u-ghost", TargetType: "workspace", TargetID: "w-budget", TargetName: "Budget", From: "read"},
+			Category:     airfocus.StaleUnknown, UserName: "Unknown User",
+		},
+	}
+	if !reflect.DeepEqual(report.Grants, want) {
+		t.Errorf("grants = %+v\nwant %+v", report.Grants, want)
+	}
+
+	var ownerless []string
+	for _, ws := range report.Ownerless {
+		ownerless = append(ownerless, ws.ID)
+	}
+	if want := []string{"w-android"}; !reflect.DeepEqual(ownerless, want) {
+		t.Errorf("ownerless = %v, want %v", ownerless, want)
+	}
+
+	if got := report.Revocations(airfocus.StaleUnseated); got != nil {
+		t.Errorf("unseated revocations = %+v, want none", got)
+	}
+	wantOwner := []airfocus.AccessChange{
+		{UserID: "u-bob", TargetType: "workspace", TargetID: "w-android", TargetName: "Android App", From: "write", To: "full"},
+	}
+	if got := report.AssignOwner("u-bob"); !reflect.DeepEqual(got, wantOwner) {
+		t.Errorf("AssignOwner = %+v\nwant %+v", got, wantOwner)
+	}
+}
+
+func TestStaleAccessCleanup(t *testing.T) {
+	client := newStaleTestClient(t)
+	ctx := context.Background()
+
+	report, err := client.StaleAccess(ctx)
+	if err != nil {
+		t.Fatalf("StaleAccess: %v", err)
+	}
+	changes := report.AssignOwner("u-carol")
+	for _, category := range airfocus.StaleCategories {
+		changes = append(changes, report.Revocations(category)...)
+	}
+	for _, result := range client.ApplyAccessChanges(ctx, changes) {
+		if result.Err != nil {
+			t.Errorf("change on %s for %s failed: %v", result.TargetID, result.UserID, result.Err)
+		}
+	}
+
+	report, err = client.StaleAccess(ctx)
+	if err != nil {
+		t.Fatalf("StaleAccess: %v", err)
+	}
+	if len(report.Grants) != 0 || len(report.Ownerless) != 0 {
+		t.Errorf("after cleanup: grants = %+v, ownerless = %+v", report.Grants, report.Ownerless)
+	}
+}
+
+func TestStaleCategory(t *testing.T) {
+	tests := []struct {
+		user airfocus.User
+		want string
+	}{
+		{airfocus.User{}, ""},
+		{airfocus.User{State: &airfocus.UserState{}}, ""},
+		{airfocus.User{Disabled: true, State: &airfocus.UserState{Unseated: true}}, airfocus.StaleDisabled},
+		{airfocus.User{State: &airfocus.UserState{Pending: true}}, airfocus.StalePending},
+		{airfocus.User{State: &airfocus.UserState{Unseated: true}}, airfocus.StaleUnseated},
+	}
+	for _, tt := range tests {
+		if got := airfocus.StaleCategory(tt.user); got != tt.want {
+			t.Errorf("StaleCategory(%+v) = %q, want %q", tt.user, got, tt.want)
+		}
+	}
+}
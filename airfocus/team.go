@@ -0,0 +1,91 @@
+package airfocus
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// SeatUsage represents the usage of one seat type in the team license
+type SeatUsage struct {
+	Total int `json:"total"` // Total number of seats
+	Used  int `json:"used"`  // Number of used seats
+	Free  int `json:"free"`  // Number of free seats
+}
+
+// TeamFlag represents a team feature flag
+type TeamFlag struct {
+	Value    bool `json:"value"`    // Whether the flag is enabled
+	Enforced bool `json:"enforced"` // Whether the flag is enforced
+	Explicit bool `json:"explicit"` // Whether the flag was set explicitly
+}
+
+// TeamLicenseInfo represents the license information for a team
+type TeamLicenseInfo struct {
+	TeamID string `json:"teamId"` // Unique identifier for the team
+	Slug   string `json:"slug"`   // Team slug
+	Name   string `json:"name"`   // Team name
+	State  struct {
+		Features []string `json:"features"` // List of enabled features
+		Seats    struct {
+			Admin       SeatUsage `json:"admin"`       // Admin seats
+			Editor      SeatUsage `json:"editor"`      // Editor seats
+			Contributor SeatUsage `json:"contributor"` // Contributor seats
+			Any         SeatUsage `json:"any"`         // Any-type seats
+		} `json:"seats"`
+		Workspaces struct {
+			Total int `json:"total"` // Total number of workspaces allowed
+		} `json:"workspaces"`
+		Subscription struct {
+			Type string `json:"type"` // Type of subscription
+		} `json:"subscription"`
+	} `json:"state"`
+	Flags struct {
+		EnableAi                  TeamFlag `json:"enableAi"`                  // AI feature flag
+		EnableOkrApp              TeamFlag `json:"enableOkrApp"`              // OKR app feature flag
+		RemoveBranding            TeamFlag `json:"removeBranding"`            // Branding removal flag
+		ForbidShareLinkCreation   TeamFlag `json:"forbidShareLinkCreation"`   // Share link creation restriction flag
+		RestrictShareLinkCreation TeamFlag `json:"restrictShareLinkCreation"` // Share link creation restriction flag
+		RequireShareLinkPassword  TeamFlag `json:"requireShareLinkPassword"`  // Share link password requirement flag
+		RequirePortalLogin        TeamFlag `json:"requirePortalLogin"`        // Portal login requirement flag
+		RequirePortalPassword     TeamFlag `json:"requirePortalPassword"`     // Portal password requirement flag
+	} `json:"flags"`
+	CreatedAt string `json:"createdAt"` // Creation timestamp
+	UpdatedAt string `json:"updatedAt"` // Last update timestamp
+}
+
+// GetTeam retrieves the team and its license information. The result is
+// cached for the client's cache TTL, like users and workspaces.
+func (c *Client) GetTeam(ctx context.Context) (TeamLicenseInfo, error) {
+	c.cacheMutex.RLock()
+	if c.cache.team != nil && time.Since(c.cache.teamUpdate) <= c.cacheTTL {
+		team := *c.cache.team
+		c.cacheMutex.RUnlock()
+		return team, nil
+	}
+	c.cacheMutex.RUnlock()
+
+	team, err := c.fetchTeam(ctx)
+	if err != nil {
+		if team, ok := c.fallbackTeam(err); ok {
+			return team, nil
+		}
+		return TeamLicenseInfo{}, err
+	}
+
+	c.cacheMutex.Lock()
+	c.cache.team = &team
+	c.cache.teamUpdate = time.Now()
+	c.cacheMutex.Unlock()
+
+	return team, nil
+}
+
+// fetchTeam retrieves the team license information from the API
+func (c *Client) fetchTeam(ctx context.Context) (TeamLicenseInfo, error) {
+	var team TeamLicenseInfo
+	if err := c.doJSON(ctx, "GET", "/team", nil, &team); err != nil {
+		return TeamLicenseInfo{}, fmt.Errorf("failed to get team: %w", err)
+	}
+	return team, nil
+}
@@ -0,0 +1,32 @@
+package airfocus_test
+
+import (
+	"context"
+	"testing"
+)
+
+func TestGetTeam(t *testing.T) {
+	client, srv := newTestClient(t)
+	ctx := context.Background()
+
+	team, err := client.GetTeam(ctx)
+	if err != nil {
+		t.Fatalf("GetTeam: %v", err)
+	}
+	if team.TeamID != "t-acme" || team.Name != "Acme" {
+		t.Errorf("team = (%q, %q), want (t-acme, Acme)", team.TeamID, team.Name)
+	}
+	if got := team.State.Seats.Any; got.Total != 17 || got.Used != 5 || got.Free != 12 {
+		t.Errorf("any seats = %+v, want {17 5 12}", got)
+	}
+	if !team.Flags.RequirePortalLogin.Value || !team.Flags.RequirePortalLogin.Enforced {
+		t.Errorf("requirePortalLogin flag = %+v, want enabled and enforced", team.Flags.RequirePortalLogin)
+	}
+
+	if _, err := client.GetTeam(ctx); err != nil {
+		t.Fatalf("GetTeam (cached): %v", err)
+	}
+	if n := srv.Requests("GET", "/team"); n != 1 {
+		t.Errorf("GET /team requested %d times, want 1", n)
+	}
+}
@@ -0,0 +1,262 @@
+package main
+
+import (
+	_ "embed"
+	"encoding/json"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/tibuski/goAirfocus/airfocus"
+)
+
+// openAPIDocument describes the JSON API served under /api/v1
+//
+//go:embed api/openapi.json
+var openAPIDocument []byte
+
+// apiUser is a user as returned by the JSON API
+type apiUser struct {
+	ID            string `json:"id"`
+	FullName      string `json:"fullName"`
+	Email         string `json:"email"`
+	Role          string `json:"role"`
+	Disabled      bool   `json:"disabled"`
+	IsTeamCreator bool   `json:"isTeamCreator"`
+}
+
+// apiField is a field as returned by the JSON API
+type apiField struct {
+	ID             string          `json:"id"`
+	Name           string          `json:"name"`
+	Description    string          `json:"description"`
+	Type           string          `json:"type"`
+	Settings       json.RawMessage `json:"settings,omitempty"`
+	IsTeamField    bool            `json:"isTeamField"`
+	WorkspaceNames []string        `json:"workspaceNames"`
+}
+
+// apiLicense is the team license as returned by the JSON API
+type apiLicense struct {
+	TeamID       string         `json:"teamId"`
+	Name         string         `json:"name"`
+	Subscription string         `json:"subscription"`
+	Seats        apiSeats       `json:"seats"`
+	Roles        map[string]int `json:"roles"` // Number of users per role
+}
+
+// apiSeats holds the seat usage per seat type
+type apiSeats struct {
+	Admin       airfocus.SeatUsage `json:"admin"`
+	Editor      airfocus.SeatUsage `json:"editor"`
+	Contributor airfocus.SeatUsage `json:"contributor"`
+	Any         airfocus.SeatUsage `json:"any"`
+}
+
+// apiError is the body of every JSON API error response
+type apiError struct {
+	Error string `json:"error"`
+}
+
+// handleAPIv1 serves the read-only JSON API. Routes are matched by hand
+// because the module still targets Go 1.21, whose ServeMux has no path
+// parameters. The API key is passed as a bearer token; a key without a
+// cached client is verified before one is kept for it.
+func (s *Server) handleAPIv1(w http.ResponseWriter, r *http.Request) {
+	path := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/v1"), "/")
+	segments := strings.Split(path, "/")
+
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed.")
+		return
+	}
+	if path == "openapi.json" {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(openAPIDocument)
+		return
+	}
+
+	apiKey, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || apiKey == "" {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="airfocus"`)
+		writeJSONError(w, http.StatusUnauthorized, "An Airfocus API key is required as a bearer token.")
+		return
+	}
+	client, err := s.clients.Verify(r.Context(), apiKey)
+	if err != nil {
+		renderJSONError(w, err, "Failed to verify API key")
+		return
+	}
+
+	switch {
+	case path == "users":
+		s.apiListUsers(w, r, client)
+	case len(segments) == 3 && segments[0] == "users" && segments[2] == "workspaces" && segments[1] != "":
+		s.apiUserWorkspaces(w, r, client, segments[1])
+	case len(segments) == 3 && segments[0] == "workspaces" && segments[2] == "users" && segments[1] != "":
+		s.apiWorkspaceUsers(w, r, client, segments[1])
+	case path == "fields":
+		s.apiListFields(w, r, client)
+	case path == "license":
+		s.apiLicense(w, r, client)
+	default:
+		writeJSONError(w, http.StatusNotFound, "Unknown API route.")
+	}
+}
+
+// apiListUsers returns all users of the team, sorted by name
+func (s *Server) apiListUsers(w http.ResponseWriter, r *http.Request, client *airfocus.Client) {
+	users, err := client.ListUsers(r.Context())
+	if err != nil {
+		renderJSONError(w, err, "Failed to retrieve users")
+		return
+	}
+	sort.Slice(users, func(i, j int) bool {
+		return strings.ToLower(users[i].FullName) < strings.ToLower(users[j].FullName)
+	})
+
+	result := make([]apiUser, len(users))
+	for i, user := range users {
+		result[i] = newAPIUser(user)
+	}
+	writeJSON(w, result)
+}
+
+// newAPIUser converts a user to its JSON API representation
+func newAPIUser(user airfocus.User) apiUser {
+	return apiUser{
+		ID:            user.UserID,
+		FullName:      user.FullName,
+		Email:         user.Email,
+		Role:          user.Role,
+		Disabled:      user.Disabled,
+		IsTeamCreator: user.IsTeamCreator,
+	}
+}
+
+// apiUserWorkspaces returns the workspaces a user has an explicit permission on
+func (s *Server) apiUserWorkspaces(w http.ResponseWriter, r *http.Request, client *airfocus.Client, userID string) {
+	if _, err := client.GetUser(r.Context(), userID); err != nil {
+		renderJSONError(w, err, "Failed to retrieve user")
+		return
+	}
+	workspaces, err := client.GetUserWorkspaces(r.Context(), userID)
+	if err != nil {
+		renderJSONError(w, err, "Failed to retrieve user workspaces")
+		return
+	}
+	if workspaces == nil {
+		workspaces = []airfocus.UserWorkspaceAccess{}
+	}
+	writeJSON(w, workspaces)
+}
+
+// apiWorkspaceUsers returns the users with an explicit permission on a workspace
+func (s *Server) apiWorkspaceUsers(w http.ResponseWriter, r *http.Request, client *airfocus.Client, workspaceID string) {
+	users, err := client.GetWorkspaceUsers(r.Context(), workspaceID)
+	if err != nil {
+		renderJSONError(w, err, "Failed to retrieve workspace users")
+		return
+	}
+	writeJSON(w, users)
+}
+
+// apiListFields returns all fields with the names of the workspaces using them
+func (s *Server) apiListFields(w http.ResponseWriter, r *http.Request, client *airfocus.Client) {
+	fields, err := client.ListFields(r.Context())
+	if err != nil {
+		renderJSONError(w, err, "Failed to retrieve fields")
+		return
+	}
+	sort.Slice(fields, func(i, j int) bool {
+		return strings.ToLower(fields[i].Name) < strings.ToLower(fields[j].Name)
+	})
+
+	result := make([]apiField, len(fields))
+	for i, field := range fields {
+		result[i] = newAPIField(field)
+	}
+	writeJSON(w, result)
+}
+
+// newAPIField converts a field to its JSON API representation
+func newAPIField(field airfocus.FieldWithWorkspaceNames) apiField {
+	names := field.WorkspaceNames
+	if names == nil {
+		names = []string{}
+	}
+	return apiField{
+		ID:             field.ID,
+		Name:           field.Name,
+		Description:    field.Description,
+		Type:           string(field.Type),
+		Settings:       field.Settings,
+		IsTeamField:    field.IsTeamField,
+		WorkspaceNames: names,
+	}
+}
+
+// apiLicense returns the seat usage of the team license and the number of users per role
+func (s *Server) apiLicense(w http.ResponseWriter, r *http.Request, client *airfocus.Client) {
+	team, err := client.GetTeam(r.Context())
+	if err != nil {
+		renderJSONError(w, err, "Failed to retrieve license information")
+		return
+	}
+	users, err := client.ListUsers(r.Context())
+	if err != nil {
+		renderJSONError(w, err, "Failed to retrieve users")
+		return
+	}
+
+	writeJSON(w, newAPILicense(team, users))
+}
+
+// newAPILicense combines the team license with the number of users per role
+func newAPILicense(team airfocus.TeamLicenseInfo, users []airfocus.User) apiLicense {
+	license := apiLicense{
+		TeamID:       team.TeamID,
+		Name:         team.Name,
+		Subscription: team.State.Subscription.Type,
+		Seats: apiSeats{
+			Admin:       team.State.Seats.Admin,
+			Editor:      team.State.Seats.Editor,
+			Contributor: team.State.Seats.Contributor,
+			Any:         team.State.Seats.Any,
+		},
+		Roles: map[string]int{"admin": 0, "editor": 0, "contributor": 0},
+	}
+	for _, user := range users {
+		if role := strings.ToLower(user.Role); role != "" {
+			license.Roles[role]++
+		}
+	}
+	return license
+}
+
+// writeJSON writes v as a JSON response with status 200
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("Error encoding JSON response: %v", err)
+	}
+}
+
+// writeJSONError writes a JSON error body with the given status
+func writeJSONError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(apiError{Error: message}); err != nil {
+		log.Printf("Error encoding JSON error: %v", err)
+	}
+}
+
+// renderJSONError logs err and writes it as a JSON error, using the same
+// status codes and messages as the HTML fragments
+func renderJSONError(w http.ResponseWriter, err error, fallback string) {
+	status, message := errorResponse(err, fallback)
+	log.Printf("%s: %v", fallback, err)
+	writeJSONError(w, status, message)
+}
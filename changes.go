@@ -0,0 +1,184 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/tibuski/goAirfocus/airfocus"
+	"github.com/tibuski/goAirfocus/snapshot"
+)
+
+// errSnapshotsDisabled is reported when the server keeps no snapshots
+var errSnapshotsDisabled = errors.New("snapshots are disabled")
+
+// liveSnapshot selects the current state of the team instead of a stored snapshot
+const liveSnapshot = "now"
+
+// changesExportFormats maps the supported change export formats to their content type
+var changesExportFormats = map[string]string{
+	"csv":  "text/csv; charset=utf-8",
+	"json": "application/json",
+}
+
+// changeSet is the filtered difference between two points in time
+type changeSet struct {
+	From    time.Time
+	To      time.Time
+	Live    bool // To is the current state rather than a stored snapshot
+	Changes []snapshot.Change
+}
+
+// changesReport returns the changes as a report for CSV and JSON output
+func changesReport(changes []snapshot.Change) report {
+	r := report{
+		header: []string{"KIND", "TYPE", "TARGET ID", "TARGET", "USER ID", "USER", "EMAIL", "FROM", "TO"},
+		json:   changes,
+	}
+	if changes == nil {
+		r.json = []snapshot.Change{}
+	}
+	for _, change := range changes {
+		r.rows = append(r.rows, []string{
+			string(change.Kind), change.TargetType, change.TargetID, change.TargetName,
+			change.UserID, change.UserName, change.UserEmail, string(change.From), string(change.To),
+		})
+	}
+	return r
+}
+
+// changesFilter reads the user, target and permission filters from the form
+func changesFilter(r *http.Request) (snapshot.Filter, error) {
+	filter := snapshot.Filter{
+		User:       strings.TrimSpace(r.FormValue("user")),
+		Target:     strings.TrimSpace(r.FormValue("target")),
+		Permission: airfocus.Permission(r.FormValue("permission")),
+	}
+	if filter.Permission != "" && !filter.Permission.Valid() {
+		return snapshot.Filter{}, fmt.Errorf("%w: %q", airfocus.ErrInvalidPermission, filter.Permission)
+	}
+	return filter, nil
+}
+
+// loadChanges compares the snapshots selected in the form, "from" and "to",
+// where "to" may also be the live state of the team. Only snapshots of the
+// session's own team can be compared. It writes an error response and
+// returns false on failure.
+func (s *Server) loadChanges(w http.ResponseWriter, r *http.Request) (changeSet, bool) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return changeSet{}, false
+	}
+
+	fromID, toID := r.FormValue("from"), r.FormValue("to")
+	if fromID == "" || toID == "" {
+		http.Error(w, "Two points in time are required", http.StatusBadRequest)
+		return changeSet{}, false
+	}
+	filter, err := changesFilter(r)
+	if err != nil {
+		s.renderError(w, err, "Invalid filter")
+		return changeSet{}, false
+	}
+
+	client, ok := s.sessionClient(w, r)
+	if !ok {
+		return changeSet{}, false
+	}
+	if s.snapshots == nil {
+		s.renderError(w, errSnapshotsDisabled, "Snapshots are not enabled")
+		return changeSet{}, false
+	}
+	team, err := client.GetTeam(r.Context())
+	if err != nil {
+		s.renderError(w, err, "Failed to retrieve team")
+		return changeSet{}, false
+	}
+
+	load := func(id string) (*airfocus.Snapshot, error) {
+		if id == liveSnapshot {
+			return client.Snapshot(r.Context())
+		}
+		snap, err := s.snapshots.Load(id)
+		if err != nil {
+			return nil, err
+		}
+		if snap.Team.TeamID != team.TeamID {
+			return nil, fmt.Errorf("snapshot %s belongs to team %s: %w", id, snap.Team.TeamID, airfocus.ErrForbidden)
+		}
+		return snap, nil
+	}
+	from, err := load(fromID)
+	if err != nil {
+		s.renderError(w, err, "Failed to load snapshot")
+		return changeSet{}, false
+	}
+	to, err := load(toID)
+	if err != nil {
+		s.renderError(w, err, "Failed to load snapshot")
+		return changeSet{}, false
+	}
+
+	// Always compare forward in time, whichever order the points were picked in
+	live := toID == liveSnapshot
+	if from.TakenAt.After(to.TakenAt) {
+		from, to = to, from
+		live = fromID == liveSnapshot
+	}
+	return changeSet{
+		From:    from.TakenAt,
+		To:      to.TakenAt,
+		Live:    live,
+		Changes: filter.Apply(snapshot.Diff(from, to)),
+	}, true
+}
+
+// handleChangesHTMX shows who gained, lost or changed access to workspaces
+// and workspace groups between two snapshots
+func (s *Server) handleChangesHTMX(w http.ResponseWriter, r *http.Request) {
+	set, ok := s.loadChanges(w, r)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html")
+	if err := s.templates.ExecuteTemplate(w, "changes_partial.html", set); err != nil {
+		log.Printf("Error executing template: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	}
+}
+
+// handleExportChanges downloads the changes between two snapshots as CSV or
+// JSON. Like the access matrix export it is submitted as a regular form.
+func (s *Server) handleExportChanges(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	format := r.FormValue("format")
+	contentType, ok := changesExportFormats[format]
+	if !ok {
+		http.Error(w, "Unsupported export format", http.StatusBadRequest)
+		return
+	}
+	set, ok := s.loadChanges(w, r)
+	if !ok {
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := changesReport(set.Changes).write(&buf, format); err != nil {
+		s.renderError(w, err, "Failed to export changes")
+		return
+	}
+
+	filename := fmt.Sprintf("access-changes-%s-%s.%s", set.From.Format("20060102-150405"), set.To.Format("20060102-150405"), format)
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	w.Write(buf.Bytes())
+}
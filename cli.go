@@ -0,0 +1,731 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/tibuski/goAirfocus/airfocus"
+	"github.com/tibuski/goAirfocus/policy"
+	"github.com/tibuski/goAirfocus/review"
+	"github.com/tibuski/goAirfocus/snapshot"
+)
+
+// cliUsage describes the command line interface
+const cliUsage = `Usage:
+  goAirfocus                                   start the web server
+  goAirfocus users list                        list all users
+  goAirfocus user access <email>               list the workspaces a user can access
+  goAirfocus workspace users <name>            list the users with access to a workspace
+  goAirfocus fields list [-unused]             list fields, optionally only those used nowhere
+  goAirfocus license                           show seat usage and users per role
+  goAirfocus export access-matrix [flags]      export every user's workspace permissions
+  goAirfocus snapshot take                     capture the team into the snapshot directory
+  goAirfocus snapshot list                     list the stored snapshots
+  goAirfocus snapshot diff <from> <to>         list permission changes between two snapshots
+  goAirfocus review preview                    print an access review without sending it
+  goAirfocus review send                       build and deliver an access review now
+  goAirfocus compliance check                  check access against the policy rules
+
+Reports accept -format table (default), json or csv. export access-matrix
+accepts -format csv (default) or xlsx and -o <file>. Snapshot commands
+accept -dir <directory>, by default AIRFOCUS_SNAPSHOT_DIR. snapshot diff takes
+snapshot IDs or "now" for the live team, and filters with -user, -target and
+-permission. Review commands accept -config <file>, by default
+AIRFOCUS_REVIEW_CONFIG; review preview also accepts -format markdown (default)
+or html. compliance check accepts -policy <file>, by default
+AIRFOCUS_POLICY_FILE, and exits with status 3 when a rule is broken.
+
+The API key is read from AIRFOCUS_API_KEY.
+`
+
+// errUsage reports invalid command line arguments; the usage text is printed with it
+var errUsage = errors.New("invalid arguments")
+
+// errViolations reports that a compliance check found broken rules
+var errViolations = errors.New("compliance check failed")
+
+// cli holds what every command needs: the client settings and the output streams
+type cli struct {
+	apiKey string
+	opts   []airfocus.Option
+	stdout io.Writer
+	stderr io.Writer
+}
+
+// cliCommand is a subcommand selected by its leading words
+type cliCommand struct {
+	words []string
+	run   func(c *cli, ctx context.Context, args []string) error
+}
+
+// cliCommands lists the subcommands; the first whose words prefix the arguments runs
+var cliCommands = []cliCommand{
+	{[]string{"users", "list"}, (*cli).runUsersList},
+	{[]string{"user", "access"}, (*cli).runUserAccess},
+	{[]string{"workspace", "users"}, (*cli).runWorkspaceUsers},
+	{[]string{"fields", "list"}, (*cli).runFieldsList},
+	{[]string{"license"}, (*cli).runLicense},
+	{[]string{"export", "access-matrix"}, (*cli).runExportAccessMatrix},
+	{[]string{"snapshot", "take"}, (*cli).runSnapshotTake},
+	{[]string{"snapshot", "list"}, (*cli).runSnapshotList},
+	{[]string{"snapshot", "diff"}, (*cli).runSnapshotDiff},
+	{[]string{"review", "preview"}, (*cli).runReviewPreview},
+	{[]string{"review", "send"}, (*cli).runReviewSend},
+	{[]string{"compliance", "check"}, (*cli).runComplianceCheck},
+}
+
+// runCommand runs the command line interface with args (without the program
+// name) and returns the process exit code. opts configure the Airfocus client.
+func runCommand(ctx context.Context, args []string, stdout, stderr io.Writer, opts ...airfocus.Option) int {
+	c := &cli{apiKey: os.Getenv("AIRFOCUS_API_KEY"), opts: opts, stdout: stdout, stderr: stderr}
+
+	if len(args) == 1 && (args[0] == "help" || args[0] == "-h" || args[0] == "--help") {
+		fmt.Fprint(stdout, cliUsage)
+		return 0
+	}
+
+	err := errUsage
+	for _, cmd := range cliCommands {
+		if hasWords(args, cmd.words) {
+			err = cmd.run(c, ctx, args[len(cmd.words):])
+			break
+		}
+	}
+
+	switch {
+	case err == nil:
+		return 0
+	case errors.Is(err, flag.ErrHelp):
+		return 0
+	case errors.Is(err, errUsage):
+		fmt.Fprintf(stderr, "Error: %v\n\n%s", err, cliUsage)
+		return 2
+	case errors.Is(err, errViolations):
+		fmt.Fprintf(stderr, "%v\n", err)
+		return 3
+	default:
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 1
+	}
+}
+
+// hasWords reports whether args starts with words
+func hasWords(args, words []string) bool {
+	if len(args) < len(words) {
+		return false
+	}
+	for i, word := range words {
+		if args[i] != word {
+			return false
+		}
+	}
+	return true
+}
+
+// parseArgs parses flags that may appear before, between or after the
+// positional arguments, and checks that exactly want positional arguments
+// were given.
+func parseArgs(fs *flag.FlagSet, args []string, want int) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			if errors.Is(err, flag.ErrHelp) {
+				return nil, err
+			}
+			return nil, fmt.Errorf("%w: %v", errUsage, err)
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			break
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+	if len(positional) < want {
+		return nil, fmt.Errorf("%w: missing argument", errUsage)
+	}
+	if len(positional) > want {
+		return nil, fmt.Errorf("%w: unexpected argument %q", errUsage, positional[want])
+	}
+	return positional, nil
+}
+
+// newFlagSet returns a flag set for a command that reports errors on stderr
+func (c *cli) newFlagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(c.stderr)
+	return fs
+}
+
+// client returns a client for the command line, failing without an API key
+func (c *cli) client() (*airfocus.Client, error) {
+	if c.apiKey == "" {
+		return nil, errors.New("AIRFOCUS_API_KEY is not set")
+	}
+	return airfocus.NewClient(c.apiKey, c.opts...), nil
+}
+
+// reportFormat registers the -format flag of a report command
+func reportFormat(fs *flag.FlagSet) *string {
+	return fs.String("format", "table", "output format: table, json or csv")
+}
+
+// report is tabular command output that can also be printed as JSON
+type report struct {
+	header []string
+	rows   [][]string
+	json   interface{} // Value printed for -format json
+}
+
+// write prints the report in the given format
+func (r report) write(w io.Writer, format string) error {
+	switch format {
+	case "table":
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, strings.Join(r.header, "\t"))
+		for _, row := range r.rows {
+			fmt.Fprintln(tw, strings.Join(row, "\t"))
+		}
+		return tw.Flush()
+	case "csv":
+		cw := csv.NewWriter(w)
+		if err := cw.Write(r.header); err != nil {
+			return err
+		}
+		if err := cw.WriteAll(r.rows); err != nil {
+			return fmt.Errorf("failed to write CSV: %w", err)
+		}
+		return nil
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		enc.SetEscapeHTML(false)
+		return enc.Encode(r.json)
+	default:
+		return checkReportFormat(format)
+	}
+}
+
+// checkReportFormat rejects an unknown -format before any request is made
+func checkReportFormat(format string) error {
+	switch format {
+	case "table", "json", "csv":
+		return nil
+	}
+	return fmt.Errorf("%w: unsupported format %q (use table, json or csv)", errUsage, format)
+}
+
+// runUsersList prints all users sorted by name
+func (c *cli) runUsersList(ctx context.Context, args []string) error {
+	fs := c.newFlagSet("users list")
+	format := reportFormat(fs)
+	if _, err := parseArgs(fs, args, 0); err != nil {
+		return err
+	}
+	if err := checkReportFormat(*format); err != nil {
+		return err
+	}
+
+	client, err := c.client()
+	if err != nil {
+		return err
+	}
+	users, err := client.ListUsers(ctx)
+	if err != nil {
+		return err
+	}
+	sort.Slice(users, func(i, j int) bool {
+		return strings.ToLower(users[i].FullName) < strings.ToLower(users[j].FullName)
+	})
+
+	r := report{header: []string{"ID", "NAME", "EMAIL", "ROLE", "DISABLED"}}
+	result := make([]apiUser, len(users))
+	for i, user := range users {
+		result[i] = newAPIUser(user)
+		r.rows = append(r.rows, []string{user.UserID, user.FullName, user.Email, user.Role, strconv.FormatBool(user.Disabled)})
+	}
+	r.json = result
+	return r.write(c.stdout, *format)
+}
+
+// cliUserAccess is one workspace a user can access, as printed by "user access"
+type cliUserAccess struct {
+	WorkspaceID   string `json:"workspaceId"`
+	WorkspaceName string `json:"workspaceName"`
+	GroupPath     string `json:"groupPath,omitempty"`
+	Permission    string `json:"permission"`
+}
+
+// runUserAccess prints the effective permission of a user on every workspace they can access
+func (c *cli) runUserAccess(ctx context.Context, args []string) error {
+	fs := c.newFlagSet("user access")
+	format := reportFormat(fs)
+	positional, err := parseArgs(fs, args, 1)
+	if err != nil {
+		return err
+	}
+	if err := checkReportFormat(*format); err != nil {
+		return err
+	}
+	email := positional[0]
+
+	client, err := c.client()
+	if err != nil {
+		return err
+	}
+	users, err := client.ListUsers(ctx)
+	if err != nil {
+		return err
+	}
+	userID := ""
+	for _, user := range users {
+		if strings.EqualFold(user.Email, email) {
+			userID = user.UserID
+			break
+		}
+	}
+	if userID == "" {
+		return fmt.Errorf("no user with email %s", email)
+	}
+
+	matrix, err := client.AccessMatrix(ctx)
+	if err != nil {
+		return err
+	}
+	column := -1
+	for j, user := range matrix.Users {
+		if user.UserID == userID {
+			column = j
+		}
+	}
+
+	r := report{header: []string{"WORKSPACE ID", "WORKSPACE", "GROUP PATH", "PERMISSION"}}
+	access := []cliUserAccess{}
+	for i, ws := range matrix.Workspaces {
+		permission := matrix.Permissions[i][column]
+		if permission == airfocus.PermissionNone {
+			continue
+		}
+		access = append(access, cliUserAccess{WorkspaceID: ws.ID, WorkspaceName: ws.Name, GroupPath: ws.GroupPath, Permission: string(permission)})
+		r.rows = append(r.rows, []string{ws.ID, ws.Name, ws.GroupPath, string(permission)})
+	}
+	r.json = access
+	return r.write(c.stdout, *format)
+}
+
+// runWorkspaceUsers prints the users with an explicit permission on the workspace with the given name
+func (c *cli) runWorkspaceUsers(ctx context.Context, args []string) error {
+	fs := c.newFlagSet("workspace users")
+	format := reportFormat(fs)
+	positional, err := parseArgs(fs, args, 1)
+	if err != nil {
+		return err
+	}
+	if err := checkReportFormat(*format); err != nil {
+		return err
+	}
+	name := positional[0]
+
+	client, err := c.client()
+	if err != nil {
+		return err
+	}
+	workspaces, err := client.ListWorkspaces(ctx)
+	if err != nil {
+		return err
+	}
+	workspaceID := ""
+	for _, ws := range workspaces {
+		if strings.EqualFold(ws.Name, name) {
+			workspaceID = ws.ID
+			break
+		}
+	}
+	if workspaceID == "" {
+		return fmt.Errorf("no workspace named %q", name)
+	}
+
+	users, err := client.GetWorkspaceUsers(ctx, workspaceID)
+	if err != nil {
+		return err
+	}
+	r := report{header: []string{"USER ID", "NAME", "EMAIL", "PERMISSION"}, json: users}
+	for _, user := range users {
+		r.rows = append(r.rows, []string{user.UserID, user.FullName, user.Email, user.Permission})
+	}
+	return r.write(c.stdout, *format)
+}
+
+// runFieldsList prints all fields sorted by name, or with -unused only those not used in any workspace
+func (c *cli) runFieldsList(ctx context.Context, args []string) error {
+	fs := c.newFlagSet("fields list")
+	format := reportFormat(fs)
+	unused := fs.Bool("unused", false, "only list fields that are not used in any workspace")
+	if _, err := parseArgs(fs, args, 0); err != nil {
+		return err
+	}
+	if err := checkReportFormat(*format); err != nil {
+		return err
+	}
+
+	client, err := c.client()
+	if err != nil {
+		return err
+	}
+	fields, err := client.ListFields(ctx)
+	if err != nil {
+		return err
+	}
+	sort.Slice(fields, func(i, j int) bool {
+		return strings.ToLower(fields[i].Name) < strings.ToLower(fields[j].Name)
+	})
+
+	r := report{header: []string{"ID", "NAME", "TYPE", "TEAM FIELD", "WORKSPACES"}}
+	result := []apiField{}
+	for _, field := range fields {
+		if *unused && len(field.WorkspaceNames) > 0 {
+			continue
+		}
+		result = append(result, newAPIField(field))
+		r.rows = append(r.rows, []string{field.ID, field.Name, string(field.Type), strconv.FormatBool(field.IsTeamField), strings.Join(field.WorkspaceNames, ", ")})
+	}
+	r.json = result
+	return r.write(c.stdout, *format)
+}
+
+// runLicense prints the seat usage per seat type and the number of users holding each role
+func (c *cli) runLicense(ctx context.Context, args []string) error {
+	fs := c.newFlagSet("license")
+	format := reportFormat(fs)
+	if _, err := parseArgs(fs, args, 0); err != nil {
+		return err
+	}
+	if err := checkReportFormat(*format); err != nil {
+		return err
+	}
+
+	client, err := c.client()
+	if err != nil {
+		return err
+	}
+	team, err := client.GetTeam(ctx)
+	if err != nil {
+		return err
+	}
+	users, err := client.ListUsers(ctx)
+	if err != nil {
+		return err
+	}
+	license := newAPILicense(team, users)
+
+	r := report{header: []string{"SEAT", "TOTAL", "USED", "FREE", "USERS"}, json: license}
+	for _, seat := range []struct {
+		name  string
+		usage airfocus.SeatUsage
+	}{
+		{"admin", license.Seats.Admin},
+		{"editor", license.Seats.Editor},
+		{"contributor", license.Seats.Contributor},
+		{"any", license.Seats.Any},
+	} {
+		holders := license.Roles[seat.name]
+		if seat.name == "any" {
+			holders = len(users)
+		}
+		r.rows = append(r.rows, []string{seat.name, strconv.Itoa(seat.usage.Total), strconv.Itoa(seat.usage.Used), strconv.Itoa(seat.usage.Free), strconv.Itoa(holders)})
+	}
+	return r.write(c.stdout, *format)
+}
+
+// runExportAccessMatrix writes the access matrix to a file or stdout
+func (c *cli) runExportAccessMatrix(ctx context.Context, args []string) error {
+	fs := c.newFlagSet("export access-matrix")
+	format := fs.String("format", "csv", "output format: csv or xlsx")
+	output := fs.String("o", "", "output file (default stdout)")
+	if _, err := parseArgs(fs, args, 0); err != nil {
+		return err
+	}
+	if _, ok := exportFormats[*format]; !ok {
+		return fmt.Errorf("%w: unsupported format %q (use csv or xlsx)", errUsage, *format)
+	}
+
+	client, err := c.client()
+	if err != nil {
+		return err
+	}
+	matrix, err := client.AccessMatrix(ctx)
+	if err != nil {
+		return err
+	}
+
+	if *output == "" {
+		return writeAccessMatrix(c.stdout, matrix, *format)
+	}
+	f, err := os.Create(*output)
+	if err != nil {
+		return fmt.Errorf("failed to create output file: %w", err)
+	}
+	if err := writeAccessMatrix(f, matrix, *format); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// snapshotStore registers the -dir flag of a snapshot command and returns a
+// function opening the store after the flags are parsed
+func snapshotStore(fs *flag.FlagSet) func() (*snapshot.Store, error) {
+	dir := fs.String("dir", os.Getenv("AIRFOCUS_SNAPSHOT_DIR"), "snapshot directory")
+	return func() (*snapshot.Store, error) {
+		if *dir == "" {
+			return nil, fmt.Errorf("%w: no snapshot directory (set AIRFOCUS_SNAPSHOT_DIR or -dir)", errUsage)
+		}
+		return snapshot.Open(*dir)
+	}
+}
+
+// runSnapshotTake captures the team into the snapshot directory and applies
+// the retention configured in the environment
+func (c *cli) runSnapshotTake(ctx context.Context, args []string) error {
+	fs := c.newFlagSet("snapshot take")
+	openStore := snapshotStore(fs)
+	if _, err := parseArgs(fs, args, 0); err != nil {
+		return err
+	}
+	config, err := snapshotConfigFromEnv()
+	if err != nil {
+		return err
+	}
+	store, err := openStore()
+	if err != nil {
+		return err
+	}
+
+	client, err := c.client()
+	if err != nil {
+		return err
+	}
+	info, pruned, err := takeSnapshot(ctx, store, client, config.retention)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(c.stdout, "Stored snapshot %s (%d bytes); deleted %d old snapshots\n", info.ID, info.Size, pruned)
+	return nil
+}
+
+// cliSnapshot is one stored snapshot, as printed by "snapshot list"
+type cliSnapshot struct {
+	ID      string `json:"id"`
+	TakenAt string `json:"takenAt"`
+	Size    int64  `json:"size"`
+}
+
+// runSnapshotList prints the stored snapshots, oldest first
+func (c *cli) runSnapshotList(ctx context.Context, args []string) error {
+	fs := c.newFlagSet("snapshot list")
+	format := reportFormat(fs)
+	openStore := snapshotStore(fs)
+	if _, err := parseArgs(fs, args, 0); err != nil {
+		return err
+	}
+	if err := checkReportFormat(*format); err != nil {
+		return err
+	}
+	store, err := openStore()
+	if err != nil {
+		return err
+	}
+
+	infos, err := store.List()
+	if err != nil {
+		return err
+	}
+	r := report{header: []string{"ID", "TAKEN AT", "SIZE"}}
+	result := []cliSnapshot{}
+	for _, info := range infos {
+		takenAt := info.TakenAt.Format(time.RFC3339)
+		result = append(result, cliSnapshot{ID: info.ID, TakenAt: takenAt, Size: info.Size})
+		r.rows = append(r.rows, []string{info.ID, takenAt, strconv.FormatInt(info.Size, 10)})
+	}
+	r.json = result
+	return r.write(c.stdout, *format)
+}
+
+// runSnapshotDiff prints who gained, lost or changed explicit access between
+// two snapshots, either of which may be the live team
+func (c *cli) runSnapshotDiff(ctx context.Context, args []string) error {
+	fs := c.newFlagSet("snapshot diff")
+	format := reportFormat(fs)
+	openStore := snapshotStore(fs)
+	var filter snapshot.Filter
+	fs.StringVar(&filter.User, "user", "", "only changes of users whose ID, name or email matches")
+	fs.StringVar(&filter.Target, "target", "", "only changes on workspaces or groups whose ID or name matches")
+	permission := fs.String("permission", "", "only changes from or to this permission level")
+	ids, err := parseArgs(fs, args, 2)
+	if err != nil {
+		return err
+	}
+	if err := checkReportFormat(*format); err != nil {
+		return err
+	}
+	filter.Permission = airfocus.Permission(*permission)
+	if filter.Permission != "" && !filter.Permission.Valid() {
+		return fmt.Errorf("%w: unknown permission %q", errUsage, *permission)
+	}
+	store, err := openStore()
+	if err != nil {
+		return err
+	}
+
+	var snaps [2]*airfocus.Snapshot
+	for i, id := range ids {
+		if id != liveSnapshot {
+			if snaps[i], err = store.Load(id); err != nil {
+				return err
+			}
+			continue
+		}
+		client, err := c.client()
+		if err != nil {
+			return err
+		}
+		if snaps[i], err = client.Snapshot(ctx); err != nil {
+			return err
+		}
+	}
+	from, to := snaps[0], snaps[1]
+	if from.TakenAt.After(to.TakenAt) {
+		from, to = to, from
+	}
+	return changesReport(filter.Apply(snapshot.Diff(from, to))).write(c.stdout, *format)
+}
+
+// reviewConfigFlag registers the -config flag of a review command
+func reviewConfigFlag(fs *flag.FlagSet) *string {
+	return fs.String("config", os.Getenv("AIRFOCUS_REVIEW_CONFIG"), "access review configuration file")
+}
+
+// runReviewPreview prints an access review without delivering it or
+// recording it as the last review. With a configuration, changes since the
+// last review are included.
+func (c *cli) runReviewPreview(ctx context.Context, args []string) error {
+	fs := c.newFlagSet("review preview")
+	configPath := reviewConfigFlag(fs)
+	format := fs.String("format", string(review.Markdown), "output format: markdown or html")
+	if _, err := parseArgs(fs, args, 0); err != nil {
+		return err
+	}
+	if !review.Format(*format).Valid() {
+		return fmt.Errorf("%w: unsupported format %q (use markdown or html)", errUsage, *format)
+	}
+
+	var previous *review.Report
+	if *configPath != "" {
+		config, err := loadReviewConfig(*configPath)
+		if err != nil {
+			return err
+		}
+		if previous, err = review.Load(config.StateFile); err != nil {
+			return err
+		}
+	}
+	client, err := c.client()
+	if err != nil {
+		return err
+	}
+	report, err := review.Build(ctx, client, previous)
+	if err != nil {
+		return err
+	}
+	body, err := report.Render(review.Format(*format))
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(c.stdout, body)
+	return err
+}
+
+// runReviewSend builds an access review and delivers it as configured
+func (c *cli) runReviewSend(ctx context.Context, args []string) error {
+	fs := c.newFlagSet("review send")
+	configPath := reviewConfigFlag(fs)
+	if _, err := parseArgs(fs, args, 0); err != nil {
+		return err
+	}
+	if *configPath == "" {
+		return fmt.Errorf("%w: no review configuration (set AIRFOCUS_REVIEW_CONFIG or -config)", errUsage)
+	}
+	config, err := loadReviewConfig(*configPath)
+	if err != nil {
+		return err
+	}
+
+	client, err := c.client()
+	if err != nil {
+		return err
+	}
+	report, ownerErrs, err := sendReview(ctx, client, config)
+	for _, ownerErr := range ownerErrs {
+		fmt.Fprintf(c.stderr, "Warning: %v\n", ownerErr)
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(c.stdout, "Sent access review of %d workspaces with %d changes\n", len(report.Workspaces), report.Changes())
+	return nil
+}
+
+// runComplianceCheck prints the violations of the policy rules and fails
+// with errViolations when there are any
+func (c *cli) runComplianceCheck(ctx context.Context, args []string) error {
+	fs := c.newFlagSet("compliance check")
+	format := reportFormat(fs)
+	path := fs.String("policy", os.Getenv("AIRFOCUS_POLICY_FILE"), "policy file")
+	if _, err := parseArgs(fs, args, 0); err != nil {
+		return err
+	}
+	if err := checkReportFormat(*format); err != nil {
+		return err
+	}
+	if *path == "" {
+		return fmt.Errorf("%w: no policy file (set AIRFOCUS_POLICY_FILE or -policy)", errUsage)
+	}
+	p, err := policy.Load(*path)
+	if err != nil {
+		return err
+	}
+
+	client, err := c.client()
+	if err != nil {
+		return err
+	}
+	violations, err := p.Check(ctx, client)
+	if err != nil {
+		return err
+	}
+	r := report{header: []string{"RULE", "TARGET", "USER", "MESSAGE"}, json: violations}
+	if violations == nil {
+		r.json = []policy.Violation{}
+	}
+	for _, v := range violations {
+		r.rows = append(r.rows, []string{v.Rule, v.Target, v.User, v.Message})
+	}
+	if err := r.write(c.stdout, *format); err != nil {
+		return err
+	}
+	if len(violations) > 0 {
+		return fmt.Errorf("%w: %d violations of %d rules", errViolations, len(violations), len(p.Rules))
+	}
+	return nil
+}
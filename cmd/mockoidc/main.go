@@ -0,0 +1,44 @@
+// Command mockoidc runs the fake OpenID Connect provider for trying out SSO
+// locally. It signs in without a password as the first user, or as the user
+// named by login_hint (e.g. http://localhost:8080/auth/login?login_hint=bob@example.com):
+//
+//	go run ./cmd/mockoidc -addr localhost:9000
+//	AIRFOCUS_API_KEY=... AIRFOCUS_OIDC_ISSUER=http://localhost:9000 \
+//	AIRFOCUS_OIDC_CLIENT_ID=goairfocus AIRFOCUS_OIDC_CLIENT_SECRET=fake-client-secret \
+//	AIRFOCUS_OIDC_REDIRECT_URL=http://localhost:8080/auth/callback \
+//	AIRFOCUS_ALLOWED_DOMAINS=example.com go run .
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+	"os/signal"
+
+	"github.com/tibuski/goAirfocus/oidc/fake"
+)
+
+func main() {
+	addr := flag.String("addr", "localhost:9000", "address to listen on")
+	flag.Parse()
+
+	users := []fake.User{
+		{Subject: "u-alice", Email: "alice@example.com", EmailVerified: true, Name: "Alice Admin", Groups: []string{"airfocus-admins"}},
+		{Subject: "u-bob", Email: "bob@example.com", EmailVerified: true, Name: "Bob Editor", Groups: []string{"airfocus-editors"}},
+		{Subject: "u-mallory", Email: "mallory@outsider.test", EmailVerified: true, Name: "Mallory Outsider"},
+	}
+	srv, err := fake.Listen(*addr, users...)
+	if err != nil {
+		log.Fatalf("Failed to start provider: %v", err)
+	}
+	defer srv.Close()
+
+	log.Printf("Mock OIDC provider listening on %s (client ID %q, secret %q)", srv.URL, fake.ClientID, fake.ClientSecret)
+	for _, user := range users {
+		log.Printf("  /auth/login?login_hint=%s signs in %s", user.Email, user.Name)
+	}
+
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt)
+	<-stop
+}
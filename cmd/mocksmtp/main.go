@@ -0,0 +1,38 @@
+// Command mocksmtp runs the fake SMTP server for trying out access-review
+// emails locally. Received messages are printed instead of delivered:
+//
+//	go run ./cmd/mocksmtp -addr localhost:2525
+//	AIRFOCUS_API_KEY=... go run . review send -config review.json
+//
+// with "smtp": {"addr": "localhost:2525", ...} in review.json.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+
+	"github.com/tibuski/goAirfocus/review/fake"
+)
+
+func main() {
+	addr := flag.String("addr", "localhost:2525", "address to listen on")
+	flag.Parse()
+
+	srv, err := fake.Listen(*addr, func(msg fake.Message) {
+		fmt.Printf("--- Message from %s to %s: %s\n%s\n", msg.From, strings.Join(msg.To, ", "), msg.Subject(), msg.Body())
+	})
+	if err != nil {
+		log.Fatalf("Failed to start SMTP server: %v", err)
+	}
+	defer srv.Close()
+
+	log.Printf("Mock SMTP server listening on %s", srv.Addr)
+
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt)
+	<-stop
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"os"
+
+	"github.com/tibuski/goAirfocus/policy"
+)
+
+// errNoPolicy is reported when the server has no policy to check
+var errNoPolicy = errors.New("no policy configured")
+
+// ruleResult is one policy rule with its violations, as shown on the Compliance section
+type ruleResult struct {
+	Rule       policy.Rule
+	Violations []policy.Violation
+}
+
+// ruleResults groups violations by the rule they break, in rule order
+func ruleResults(p *policy.Policy, violations []policy.Violation) []ruleResult {
+	results := make([]ruleResult, len(p.Rules))
+	index := make(map[string]int, len(p.Rules))
+	for i, rule := range p.Rules {
+		results[i].Rule = rule
+		index[rule.Name] = i
+	}
+	for _, v := range violations {
+		i := index[v.Rule]
+		results[i].Violations = append(results[i].Violations, v)
+	}
+	return results
+}
+
+// policyFromEnv loads the policy file named by AIRFOCUS_POLICY_FILE, or
+// returns nil when it is not set
+func policyFromEnv() (*policy.Policy, error) {
+	path := os.Getenv("AIRFOCUS_POLICY_FILE")
+	if path == "" {
+		return nil, nil
+	}
+	return policy.Load(path)
+}
+
+// handleComplianceHTMX checks the session's team against the policy and
+// lists the violations of every rule
+func (s *Server) handleComplianceHTMX(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	client, ok := s.sessionClient(w, r)
+	if !ok {
+		return
+	}
+	if s.policy == nil {
+		s.renderError(w, errNoPolicy, "No policy configured")
+		return
+	}
+	violations, err := s.policy.Check(r.Context(), client)
+	if err != nil {
+		s.renderError(w, err, "Failed to check compliance")
+		return
+	}
+
+	data := map[string]interface{}{
+		"Results":    ruleResults(s.policy, violations),
+		"Violations": len(violations),
+		"StaleSince": client.StaleSince(),
+	}
+	w.Header().Set("Content-Type", "text/html")
+	if err := s.templates.ExecuteTemplate(w, "compliance_partial.html", data); err != nil {
+		log.Printf("Error executing template: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/tibuski/goAirfocus/airfocus"
+	"github.com/tibuski/goAirfocus/snapshot"
+)
+
+// errNoSession is reported when a request has no live session
+var errNoSession = errors.New("no session")
+
+// errPlanChanged is reported when the changes to apply differ from the ones previewed
+var errPlanChanged = errors.New("plan changed since the preview")
+
+// errorResponse maps an error returned by the airfocus client to an HTTP
+// status and a message that is safe to show in the browser. fallback is
+// used for errors that have no friendlier explanation.
+func errorResponse(err error, fallback string) (int, string) {
+	switch {
+	case errors.Is(err, errNoSession):
+		return http.StatusUnauthorized, "You are not logged in or your session has expired. Please log in with your API key."
+	case errors.Is(err, errInsufficientRole):
+		return http.StatusForbidden, "Your role does not allow this action. Ask an administrator of this tool for access."
+	case errors.Is(err, airfocus.ErrUnauthorized):
+		return http.StatusUnauthorized, "Invalid API key. Check the key and try again."
+	case errors.Is(err, airfocus.ErrForbidden):
+		return http.StatusForbidden, "This API key is not allowed to access the requested data."
+	case errors.Is(err, errPlanChanged):
+		return http.StatusConflict, "Access has changed since the preview. Preview again to review the new changes."
+	case errors.Is(err, airfocus.ErrSkipped):
+		return http.StatusFailedDependency, "Skipped because a change it depends on failed."
+	case errors.Is(err, errNoPolicy):
+		return http.StatusNotFound, "No compliance policy is configured on this server."
+	case errors.Is(err, errSnapshotsDisabled):
+		return http.StatusNotFound, "Snapshots are not enabled on this server."
+	case errors.Is(err, airfocus.ErrNotFound), errors.Is(err, snapshot.ErrNotFound):
+		return http.StatusNotFound, fallback + " (not found)."
+	case errors.Is(err, airfocus.ErrInvalidPermission):
+		return http.StatusBadRequest, "Unknown permission level."
+	case errors.Is(err, airfocus.ErrRateLimited):
+		return http.StatusTooManyRequests, "Airfocus is rate limiting requests. Please wait a moment and try again."
+	case errors.Is(err, context.DeadlineExceeded):
+		return http.StatusGatewayTimeout, "Airfocus took too long to respond. Please try again."
+	default:
+		return http.StatusInternalServerError, fallback + "."
+	}
+}
+
+// renderError logs err and writes an HTML error fragment with the matching status code
+func (s *Server) renderError(w http.ResponseWriter, err error, fallback string) {
+	status, message := errorResponse(err, fallback)
+	log.Printf("%s: %v", fallback, err)
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	if err := s.templates.ExecuteTemplate(w, "error_partial.html", map[string]interface{}{
+		"Message": message,
+	}); err != nil {
+		log.Printf("Error executing template: %v", err)
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/tibuski/goAirfocus/airfocus"
+)
+
+// exportFormats maps the supported access matrix export formats to their content type
+var exportFormats = map[string]string{
+	"csv":  "text/csv; charset=utf-8",
+	"xlsx": "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+}
+
+// writeAccessMatrix writes the matrix in the given format ("csv" or "xlsx")
+func writeAccessMatrix(w io.Writer, matrix airfocus.AccessMatrix, format string) error {
+	switch format {
+	case "csv":
+		return matrix.WriteCSV(w)
+	case "xlsx":
+		return matrix.WriteXLSX(w)
+	default:
+		return fmt.Errorf("unsupported format %q (use csv or xlsx)", format)
+	}
+}
+
+// accessMatrixFilename returns the download name of an export taken at the given time
+func accessMatrixFilename(format string, at time.Time) string {
+	return fmt.Sprintf("access-matrix-%s.%s", at.Format("20060102-150405"), format)
+}
+
+// handleExportAccessMatrix downloads the effective permission of every user
+// on every workspace as a CSV file or an Excel workbook. It is submitted as a
+// regular form rather than through HTMX so the browser saves the response.
+func (s *Server) handleExportAccessMatrix(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	format := r.FormValue("format")
+	contentType, ok := exportFormats[format]
+	if !ok {
+		http.Error(w, "Unsupported export format", http.StatusBadRequest)
+		return
+	}
+
+	client, ok := s.sessionClient(w, r)
+	if !ok {
+		return
+	}
+	matrix, err := client.AccessMatrix(r.Context())
+	if err != nil {
+		s.renderError(w, err, "Failed to build access matrix")
+		return
+	}
+
+	// Render into a buffer first so a failure can still be reported as an error
+	var buf bytes.Buffer
+	if err := writeAccessMatrix(&buf, matrix, format); err != nil {
+		s.renderError(w, err, "Failed to export access matrix")
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, accessMatrixFilename(format, time.Now())))
+	w.Write(buf.Bytes())
+}
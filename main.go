@@ -3,17 +3,20 @@ package main
 import (
 	"context"
 	"embed"
-	"encoding/json"
 	"fmt"
 	"html/template"
 	"log"
+	"os"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
 
 	"net/http"
 
 	"github.com/tibuski/goAirfocus/airfocus"
+	"github.com/tibuski/goAirfocus/policy"
+	"github.com/tibuski/goAirfocus/snapshot"
 )
 
 //go:embed templates/*
@@ -25,14 +28,26 @@ var staticFS embed.FS
 // Server represents the HTTP server for the Airfocus API Tools application
 type Server struct {
 	templates *template.Template
+	clients   *ClientRegistry // Shared Airfocus clients keyed by API key
+	sessions  *SessionStore   // Logged in browser sessions
+	sso       *SSO            // Single sign-on with a server-configured API key, nil when users log in with their own key
+	roles     *RoleConfig     // Roles of SSO operators, nil when every operator is an admin
+	snapshots *snapshot.Store // Stored snapshots of the team, nil when snapshots are disabled
+	policy    *policy.Policy  // Access rules checked on the Compliance section, nil if none
 }
 
-// NewServer creates and initializes a new Server instance
-func NewServer() (*Server, error) {
+// NewServer creates and initializes a new Server instance. The given options
+// are applied to every Airfocus client the server creates.
+func NewServer(opts ...airfocus.Option) (*Server, error) {
 	tmpl, err := template.New("").Funcs(template.FuncMap{
 		"getPermissionColorClass": getPermissionColorClass,
+		"getStatusColorClass":     getStatusColorClass,
 		"join":                    strings.Join,
 		"permToString":            permToString,
+		"permissionControl":       newPermissionControl,
+		"permissions": func() []airfocus.Permission {
+			return airfocus.Permissions
+		},
 		"mul": func(a, b int) int {
 			return a * b
 		},
@@ -41,8 +56,18 @@ func NewServer() (*Server, error) {
 		return nil, fmt.Errorf("failed to parse templates: %w", err)
 	}
 
+	sessions, err := NewSessionStore(defaultSessionIdleTTL)
+	if err != nil {
+		return nil, err
+	}
+
+	clients := NewClientRegistry(defaultClientIdleTTL, defaultMaxCachedClients, opts...)
+	sessions.OnRelease(clients.Remove)
+
 	return &Server{
 		templates: tmpl,
+		clients:   clients,
+		sessions:  sessions,
 	}, nil
 }
 
@@ -52,111 +77,42 @@ func (s *Server) handleIndex(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := s.templates.ExecuteTemplate(w, "index.html", nil); err != nil {
-		log.Printf("Error executing template: %v", err)
-		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
-	}
-}
-
-// TeamLicenseInfo represents the license information for a team
-type TeamLicenseInfo struct {
-	TeamID string `json:"teamId"` // Unique identifier for the team
-	Slug   string `json:"slug"`   // Team slug
-	Name   string `json:"name"`   // Team name
-	State  struct {
-		Features []string `json:"features"` // List of enabled features
-		Seats    struct {
-			Admin struct {
-				Total int `json:"total"` // Total number of admin seats
-				Used  int `json:"used"`  // Number of used admin seats
-				Free  int `json:"free"`  // Number of free admin seats
-			} `json:"admin"`
-			Editor struct {
-				Total int `json:"total"` // Total number of editor seats
-				Used  int `json:"used"`  // Number of used editor seats
-				Free  int `json:"free"`  // Number of free editor seats
-			} `json:"editor"`
-			Contributor struct {
-				Total int `json:"total"` // Total number of contributor seats
-				Used  int `json:"used"`  // Number of used contributor seats
-				Free  int `json:"free"`  // Number of free contributor seats
-			} `json:"contributor"`
-			Any struct {
-				Total int `json:"total"` // Total number of any-type seats
-				Used  int `json:"used"`  // Number of used any-type seats
-				Free  int `json:"free"`  // Number of free any-type seats
-			} `json:"any"`
-		} `json:"seats"`
-		Workspaces struct {
-			Total int `json:"total"` // Total number of workspaces allowed
-		} `json:"workspaces"`
-		Subscription struct {
-			Type string `json:"type"` // Type of subscription
-		} `json:"subscription"`
-	} `json:"state"`
-	Flags struct {
-		EnableAi                  struct{ Value, Enforced, Explicit bool } `json:"enableAi"`                  // AI feature flag
-		EnableOkrApp              struct{ Value, Enforced, Explicit bool } `json:"enableOkrApp"`              // OKR app feature flag
-		RemoveBranding            struct{ Value, Enforced, Explicit bool } `json:"removeBranding"`            // Branding removal flag
-		ForbidShareLinkCreation   struct{ Value, Enforced, Explicit bool } `json:"forbidShareLinkCreation"`   // Share link creation restriction flag
-		RestrictShareLinkCreation struct{ Value, Enforced, Explicit bool } `json:"restrictShareLinkCreation"` // Share link creation restriction flag
-		RequireShareLinkPassword  struct{ Value, Enforced, Explicit bool } `json:"requireShareLinkPassword"`  // Share link password requirement flag
-		RequirePortalLogin        struct{ Value, Enforced, Explicit bool } `json:"requirePortalLogin"`        // Portal login requirement flag
-		RequirePortalPassword     struct{ Value, Enforced, Explicit bool } `json:"requirePortalPassword"`     // Portal password requirement flag
-	} `json:"flags"`
-	CreatedAt string `json:"createdAt"` // Creation timestamp
-	UpdatedAt string `json:"updatedAt"` // Last update timestamp
-}
-
-// handleGetTeamLicense handles GET requests to retrieve team license information
-func handleGetTeamLicense(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
-
-	apiKey := r.FormValue("api_key")
-	if apiKey == "" {
-		http.Error(w, "API key is required", http.StatusBadRequest)
-		return
-	}
-
-	// Make request to Airfocus API
-	req, err := http.NewRequest("GET", "https://api.airfocus.com/api/team", nil)
-	if err != nil {
-		http.Error(w, "Error creating request", http.StatusInternalServerError)
-		return
-	}
-
-	req.Header.Set("Authorization", "Bearer "+apiKey)
-	req.Header.Set("Accept", "application/json")
-
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		http.Error(w, "Error making request to Airfocus API", http.StatusInternalServerError)
-		return
+	// Show the tools only to a live session, greeting it with the team name
+	// and, with SSO, the signed in operator
+	data := struct {
+		SSO      bool
+		LoggedIn bool
+		TeamName string
+		Operator *Operator
+		Role     Role
+
+		SnapshotsEnabled bool
+		Snapshots        []snapshot.Info // Newest first
+		PolicyEnabled    bool
+	}{SSO: s.sso != nil, Role: s.role(r), SnapshotsEnabled: s.snapshots != nil, PolicyEnabled: s.policy != nil}
+	if cookie, err := r.Cookie(sessionCookieName); err == nil {
+		if apiKey, operator, ok := s.sessions.Lookup(cookie.Value); ok {
+			data.LoggedIn = true
+			data.Operator = operator
+			if team, err := s.clients.Get(apiKey).GetTeam(r.Context()); err == nil {
+				data.TeamName = team.Name
+			}
+			if s.snapshots != nil {
+				infos, err := s.snapshots.List()
+				if err != nil {
+					log.Printf("Error listing snapshots: %v", err)
+				}
+				for i := len(infos) - 1; i >= 0; i-- {
+					data.Snapshots = append(data.Snapshots, infos[i])
+				}
+			}
+		}
 	}
-	defer resp.Body.Close()
 
-	var licenseInfo TeamLicenseInfo
-	if err := json.NewDecoder(resp.Body).Decode(&licenseInfo); err != nil {
-		http.Error(w, "Error decoding response", http.StatusInternalServerError)
-		return
+	if err := s.templates.ExecuteTemplate(w, "index.html", data); err != nil {
+		log.Printf("Error executing template: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 	}
-
-	// Set response headers and return the license info
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]interface{}{
-		"status": "success",
-		"data": map[string]interface{}{
-			"state": map[string]interface{}{
-				"seats": map[string]interface{}{
-					"any": licenseInfo.State.Seats.Any,
-				},
-			},
-		},
-	})
 }
 
 // handleGetLicenseInfoHTMX handles POST requests to get license information and return HTML
@@ -170,47 +126,21 @@ func (s *Server) handleGetLicenseInfoHTMX(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	apiKey := r.FormValue("api_key")
-	if apiKey == "" {
-		log.Printf("API key is missing")
-		http.Error(w, "API key is required", http.StatusBadRequest)
+	client, ok := s.sessionClient(w, r)
+	if !ok {
 		return
 	}
 
-	log.Printf("Making request to Airfocus API for license info")
-
-	// Make request to Airfocus API for license info
-	req, err := http.NewRequest("GET", "https://api.airfocus.com/api/team", nil)
+	licenseInfo, err := client.GetTeam(r.Context())
 	if err != nil {
-		log.Printf("Error creating request: %v", err)
-		http.Error(w, "Error creating request", http.StatusInternalServerError)
-		return
-	}
-
-	req.Header.Set("Authorization", "Bearer "+apiKey)
-	req.Header.Set("Accept", "application/json")
-
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		log.Printf("Error making request to Airfocus API: %v", err)
-		http.Error(w, "Error making request to Airfocus API", http.StatusInternalServerError)
-		return
-	}
-	defer resp.Body.Close()
-
-	var licenseInfo TeamLicenseInfo
-	if err := json.NewDecoder(resp.Body).Decode(&licenseInfo); err != nil {
-		log.Printf("Error decoding response: %v", err)
-		http.Error(w, "Error decoding response", http.StatusInternalServerError)
+		s.renderError(w, err, "Failed to retrieve license information")
 		return
 	}
 
 	log.Printf("Successfully retrieved license info for HTMX")
 
 	// Get actual user data for role statistics
-	airfocusClient := airfocus.NewClient(apiKey)
-	users, err := airfocusClient.FormatUsersWithRoles(r.Context())
+	users, err := client.FormatUsersWithRoles(r.Context())
 	if err != nil {
 		log.Printf("Error getting users with roles: %v", err)
 		// Continue with license info only if user data fails
@@ -295,16 +225,14 @@ func (s *Server) handleListFieldsHTMX(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	apiKey := r.FormValue("api_key")
-	if apiKey == "" {
-		http.Error(w, "API key is required", http.StatusBadRequest)
+	client, ok := s.sessionClient(w, r)
+	if !ok {
 		return
 	}
 
-	client := airfocus.NewClient(apiKey)
 	fields, err := client.ListFields(r.Context())
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Failed to list fields: %v", err), http.StatusInternalServerError)
+		s.renderError(w, err, "Failed to list fields")
 		return
 	}
 
@@ -368,16 +296,14 @@ func (s *Server) handleGetUsersWithRolesHTMX(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
-	apiKey := r.FormValue("api_key")
-	if apiKey == "" {
-		http.Error(w, "API key is required", http.StatusBadRequest)
+	client, ok := s.sessionClient(w, r)
+	if !ok {
 		return
 	}
 
-	client := airfocus.NewClient(apiKey)
 	users, err := client.FormatUsersWithRoles(r.Context())
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Failed to get users: %v", err), http.StatusInternalServerError)
+		s.renderError(w, err, "Failed to retrieve users")
 		return
 	}
 
@@ -417,15 +343,17 @@ func (s *Server) handleGetUserWorkspacesHTMX(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
-	apiKey := r.FormValue("api_key")
 	userID := r.FormValue("user_id")
 
-	if apiKey == "" || userID == "" {
-		http.Error(w, "API key and user ID are required", http.StatusBadRequest)
+	if userID == "" {
+		http.Error(w, "User ID is required", http.StatusBadRequest)
 		return
 	}
 
-	client := airfocus.NewClient(apiKey)
+	client, ok := s.sessionClient(w, r)
+	if !ok {
+		return
+	}
 
 	// Add context with timeout
 	ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
@@ -433,14 +361,14 @@ func (s *Server) handleGetUserWorkspacesHTMX(w http.ResponseWriter, r *http.Requ
 
 	// Refresh the workspace cache before getting user workspaces
 	if err := client.RefreshCacheIfNeeded(ctx); err != nil {
-		http.Error(w, fmt.Sprintf("Failed to refresh workspace cache: %v", err), http.StatusInternalServerError)
+		s.renderError(w, err, "Failed to refresh workspace cache")
 		return
 	}
 
 	// Get user workspaces
 	userWorkspaces, err := client.GetUserWorkspaces(ctx, userID)
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Failed to get user workspaces: %v", err), http.StatusInternalServerError)
+		s.renderError(w, err, "Failed to retrieve user workspaces")
 		return
 	}
 
@@ -542,22 +470,21 @@ func (s *Server) handleGetWorkspacesHTMX(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	apiKey := r.FormValue("api_key")
-	if apiKey == "" {
-		http.Error(w, "API key is required", http.StatusBadRequest)
+	client, ok := s.sessionClient(w, r)
+	if !ok {
 		return
 	}
 
-	client := airfocus.NewClient(apiKey)
 	workspaces, err := client.ListWorkspaces(r.Context())
 	if err != nil {
-		log.Printf("Error listing workspaces for HTMX: %v", err)
-		http.Error(w, "Failed to retrieve workspaces", http.StatusInternalServerError)
+		s.renderError(w, err, "Failed to retrieve workspaces")
 		return
 	}
 
 	data := map[string]interface{}{
 		"Workspaces": workspaces,
+		"Incomplete": client.IncompleteResults(),
+		"StaleSince": client.StaleSince(),
 	}
 
 	// It's crucial to specify the partial template here.
@@ -574,19 +501,20 @@ func (s *Server) handleGetWorkspaceIDHTMX(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	apiKey := r.FormValue("api_key")
 	workspaceID := r.FormValue("workspace_select") // Get selected workspace ID from the form
 
-	if apiKey == "" || workspaceID == "" {
-		http.Error(w, "API key and Workspace ID are required", http.StatusBadRequest)
+	if workspaceID == "" {
+		http.Error(w, "Workspace ID is required", http.StatusBadRequest)
 		return
 	}
 
-	client := airfocus.NewClient(apiKey)
+	client, ok := s.sessionClient(w, r)
+	if !ok {
+		return
+	}
 	workspace, err := client.GetWorkspaceByID(r.Context(), workspaceID)
 	if err != nil {
-		log.Printf("Error getting workspace ID %s: %v", workspaceID, err)
-		http.Error(w, "Failed to retrieve workspace ID", http.StatusInternalServerError)
+		s.renderError(w, err, "Failed to retrieve workspace")
 		return
 	}
 
@@ -609,19 +537,20 @@ func (s *Server) handleGetWorkspaceUsersHTMX(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
-	apiKey := r.FormValue("api_key")
 	workspaceID := r.FormValue("workspace_select") // Get selected workspace ID from the form
 
-	if apiKey == "" || workspaceID == "" {
-		http.Error(w, "API key and Workspace ID are required", http.StatusBadRequest)
+	if workspaceID == "" {
+		http.Error(w, "Workspace ID is required", http.StatusBadRequest)
 		return
 	}
 
-	client := airfocus.NewClient(apiKey)
+	client, ok := s.sessionClient(w, r)
+	if !ok {
+		return
+	}
 	users, err := client.GetWorkspaceUsers(r.Context(), workspaceID)
 	if err != nil {
-		log.Printf("Error getting workspace users for ID %s: %v", workspaceID, err)
-		http.Error(w, "Failed to retrieve workspace users", http.StatusInternalServerError)
+		s.renderError(w, err, "Failed to retrieve workspace users")
 		return
 	}
 
@@ -632,8 +561,31 @@ func (s *Server) handleGetWorkspaceUsersHTMX(w http.ResponseWriter, r *http.Requ
 		groupedUsers[permission] = append(groupedUsers[permission], user)
 	}
 
+	// Offer the team members without explicit access for granting
+	allUsers, err := client.ListUsers(r.Context())
+	if err != nil {
+		s.renderError(w, err, "Failed to retrieve users")
+		return
+	}
+	hasAccess := make(map[string]bool)
+	for _, user := range users {
+		hasAccess[user.UserID] = true
+	}
+	var candidates []airfocus.User
+	for _, user := range allUsers {
+		if !hasAccess[user.UserID] && !user.Disabled {
+			candidates = append(candidates, user)
+		}
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		return strings.ToLower(candidates[i].FullName) < strings.ToLower(candidates[j].FullName)
+	})
+
 	data := map[string]interface{}{
 		"GroupedWorkspaces": groupedUsers,
+		"WorkspaceID":       workspaceID,
+		"Candidates":        candidates,
+		"CanEdit":           s.role(r).Allows(RoleEditor),
 	}
 
 	// Render only the partial for workspace users
@@ -643,6 +595,76 @@ func (s *Server) handleGetWorkspaceUsersHTMX(w http.ResponseWriter, r *http.Requ
 	}
 }
 
+// workspaceItemRow is an item together with the names of its assignees
+type workspaceItemRow struct {
+	Item      airfocus.Item
+	Assignees []string
+}
+
+// handleGetWorkspaceItemsHTMX lists the items of the selected workspace and renders HTML.
+func (s *Server) handleGetWorkspaceItemsHTMX(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	workspaceID := r.FormValue("workspace_select") // Get selected workspace ID from the form
+	itemQuery := strings.TrimSpace(r.FormValue("item_query"))
+
+	if workspaceID == "" {
+		http.Error(w, "Workspace ID is required", http.StatusBadRequest)
+		return
+	}
+
+	client, ok := s.sessionClient(w, r)
+	if !ok {
+		return
+	}
+	result, err := client.SearchItems(r.Context(), workspaceID, airfocus.ItemNameQuery(itemQuery))
+	if err != nil {
+		s.renderError(w, err, "Failed to retrieve workspace items")
+		return
+	}
+
+	users, err := client.ListUsers(r.Context())
+	if err != nil {
+		s.renderError(w, err, "Failed to retrieve users")
+		return
+	}
+	userNames := make(map[string]string)
+	for _, user := range users {
+		userNames[user.UserID] = user.FullName
+	}
+
+	rows := make([]workspaceItemRow, 0, len(result.Items))
+	for _, item := range result.Items {
+		row := workspaceItemRow{Item: item}
+		for _, userID := range item.AssigneeUserIDs {
+			name, ok := userNames[userID]
+			if !ok {
+				name = "Unknown User"
+			}
+			row.Assignees = append(row.Assignees, name)
+		}
+		rows = append(rows, row)
+	}
+
+	var incomplete []string
+	if !result.Complete {
+		incomplete = append(incomplete, "items")
+	}
+
+	data := map[string]interface{}{
+		"Items":      rows,
+		"Incomplete": incomplete,
+	}
+
+	if err := s.templates.ExecuteTemplate(w, "workspace_items_partial.html", data); err != nil {
+		log.Printf("Error executing template: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	}
+}
+
 // handleGetUsersHTMX fetches users and renders a select dropdown
 func (s *Server) handleGetUsersHTMX(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
@@ -650,17 +672,14 @@ func (s *Server) handleGetUsersHTMX(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	apiKey := r.FormValue("api_key")
-	if apiKey == "" {
-		http.Error(w, "API key is required", http.StatusBadRequest)
+	client, ok := s.sessionClient(w, r)
+	if !ok {
 		return
 	}
 
-	client := airfocus.NewClient(apiKey)
 	users, err := client.FormatUsersWithRoles(r.Context())
 	if err != nil {
-		log.Printf("Error getting users with roles for HTMX: %v", err)
-		http.Error(w, "Failed to retrieve users", http.StatusInternalServerError)
+		s.renderError(w, err, "Failed to retrieve users")
 		return
 	}
 
@@ -681,27 +700,27 @@ func (s *Server) handleGetUserInfoHTMX(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	apiKey := r.FormValue("api_key")
 	userID := r.FormValue("user_select") // Get selected user ID from the form
 
-	if apiKey == "" || userID == "" {
-		http.Error(w, "API key and User ID are required", http.StatusBadRequest)
+	if userID == "" {
+		http.Error(w, "User ID is required", http.StatusBadRequest)
 		return
 	}
 
-	client := airfocus.NewClient(apiKey)
+	client, ok := s.sessionClient(w, r)
+	if !ok {
+		return
+	}
 	user, err := client.GetUser(r.Context(), userID)
 	if err != nil {
-		log.Printf("Error getting user %s: %v", userID, err)
-		http.Error(w, "Failed to retrieve user info", http.StatusInternalServerError)
+		s.renderError(w, err, "Failed to retrieve user")
 		return
 	}
 
 	// Fetch user's group access (this includes workspaces within groups)
 	userGroups, err := client.GetUserGroupAccess(r.Context(), userID)
 	if err != nil {
-		log.Printf("Error getting user group access for user %s: %v", userID, err)
-		http.Error(w, "Failed to retrieve user group access", http.StatusInternalServerError)
+		s.renderError(w, err, "Failed to retrieve user group access")
 		return
 	}
 
@@ -727,10 +746,31 @@ func (s *Server) handleGetUserInfoHTMX(w http.ResponseWriter, r *http.Request) {
 		groupedWorkspaces[permission] = append(groupedWorkspaces[permission], ws)
 	}
 
+	// Offer the other active team members as targets for copying this user's access and as successors
+	teamUsers, err := client.ListUsers(r.Context())
+	if err != nil {
+		s.renderError(w, err, "Failed to retrieve users")
+		return
+	}
+	var copyTargets []airfocus.User
+	for _, u := range teamUsers {
+		if u.UserID != user.UserID && !u.Disabled {
+			copyTargets = append(copyTargets, u)
+		}
+	}
+	sort.Slice(copyTargets, func(i, j int) bool {
+		return strings.ToLower(copyTargets[i].FullName) < strings.ToLower(copyTargets[j].FullName)
+	})
+
 	data := map[string]interface{}{
-		"User":       user,
-		"Workspaces": groupedWorkspaces,  // Renamed from GroupedWorkspaces to avoid confusion if it's not grouped by permission here
-		"UserGroups": hierarchicalGroups, // Pass the hierarchical list of user groups
+		"User":        user,
+		"CopyTargets": copyTargets,
+		"Workspaces":  groupedWorkspaces,  // Renamed from GroupedWorkspaces to avoid confusion if it's not grouped by permission here
+		"UserGroups":  hierarchicalGroups, // Pass the hierarchical list of user groups
+		"Incomplete":  client.IncompleteResults(),
+		"StaleSince":  client.StaleSince(),
+		"CanEdit":     s.role(r).Allows(RoleEditor),
+		"CanOffboard": s.role(r).Allows(RoleAdmin),
 	}
 
 	if err := s.templates.ExecuteTemplate(w, "user_details_partial.html", data); err != nil {
@@ -755,6 +795,18 @@ func getPermissionColorClass(permission string) string {
 	}
 }
 
+// getStatusColorClass returns the appropriate CSS class for an item status category
+func getStatusColorClass(category string) string {
+	switch strings.ToLower(category) {
+	case "active":
+		return "bg-blue-100 text-blue-800"
+	case "done":
+		return "bg-green-100 text-green-800"
+	default:
+		return "bg-gray-100 text-gray-800"
+	}
+}
+
 func permToString(p airfocus.Permission) string {
 	return string(p)
 }
@@ -781,7 +833,8 @@ func buildGroupTree(groups []airfocus.WorkspaceGroup) []HierarchicalGroup {
 
 	var rootGroups []HierarchicalGroup
 	for _, group := range groups {
-		if group.ParentID == "" {
+		// Groups whose parent is not in the list are shown at the root as well
+		if _, hasParent := groupMap[group.ParentID]; !hasParent {
 			hg := HierarchicalGroup{WorkspaceGroup: group, Level: 0} // Root level is 0
 			rootGroups = append(rootGroups, hg)
 		}
@@ -820,16 +873,14 @@ func (s *Server) handleGetFieldSelectHTMX(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	apiKey := r.FormValue("api_key")
-	if apiKey == "" {
-		http.Error(w, "API key is required", http.StatusBadRequest)
+	client, ok := s.sessionClient(w, r)
+	if !ok {
 		return
 	}
 
-	client := airfocus.NewClient(apiKey)
 	fields, err := client.ListFields(r.Context())
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Failed to list fields: %v", err), http.StatusInternalServerError)
+		s.renderError(w, err, "Failed to list fields")
 		return
 	}
 
@@ -852,6 +903,12 @@ func (s *Server) handleGetFieldSelectHTMX(w http.ResponseWriter, r *http.Request
 
 	// Generate HTML for the field dropdown
 	var html strings.Builder
+	if err := s.templates.ExecuteTemplate(&html, "stale_warning", client.StaleSince()); err != nil {
+		log.Printf("Error executing template: %v", err)
+	}
+	if err := s.templates.ExecuteTemplate(&html, "incomplete_warning", client.IncompleteResults()); err != nil {
+		log.Printf("Error executing template: %v", err)
+	}
 	html.WriteString(`<div class="mb-4">
 		<label for="fieldSelect" class="block text-sm font-medium text-gray-700 mb-2">Choose a field to view details:</label>
 		<select id="fieldSelect" name="fieldSelect"
@@ -859,7 +916,7 @@ func (s *Server) handleGetFieldSelectHTMX(w http.ResponseWriter, r *http.Request
 				hx-target="#fieldDetailsResult"
 				hx-swap="innerHTML"
 				hx-trigger="change"
-				hx-include="#apiKey, #fieldSelect"
+				hx-include="#fieldSelect"
 				class="w-full px-4 py-2 border border-gray-300 rounded-md focus:outline-none focus:ring-2 focus:ring-blue-500">
 			<option value="">Select a field...</option>`)
 
@@ -890,6 +947,65 @@ func (s *Server) handleGetFieldSelectHTMX(w http.ResponseWriter, r *http.Request
 	w.Write([]byte(html.String()))
 }
 
+// fieldSetting is one labelled configuration value shown on the field details page
+type fieldSetting struct {
+	Label string
+	Value string
+}
+
+// fieldSettingsView is the data rendered by the field_settings template
+type fieldSettingsView struct {
+	Settings []fieldSetting
+	Options  []airfocus.SelectOption
+}
+
+// describeFieldSettings turns typed field settings into displayable rows
+func describeFieldSettings(settings airfocus.FieldSettings) fieldSettingsView {
+	var view fieldSettingsView
+	add := func(label, value string) {
+		view.Settings = append(view.Settings, fieldSetting{Label: label, Value: value})
+	}
+	number := func(v float64) string {
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+
+	switch s := settings.(type) {
+	case *airfocus.TextSettings:
+		add("Multiline", strconv.FormatBool(s.Multiline))
+		if s.MaxLength != nil {
+			add("Max Length", strconv.Itoa(*s.MaxLength))
+		}
+	case *airfocus.NumberSettings:
+		if s.Min != nil {
+			add("Minimum", number(*s.Min))
+		}
+		if s.Max != nil {
+			add("Maximum", number(*s.Max))
+		}
+		add("Precision", strconv.Itoa(s.Precision))
+		if s.Unit != "" {
+			add("Unit", s.Unit)
+		}
+	case *airfocus.DateSettings:
+		add("Includes Time", strconv.FormatBool(s.IncludeTime))
+	case *airfocus.SelectSettings:
+		add("Multiple Selection", strconv.FormatBool(s.Multiple))
+		view.Options = s.Options
+	case *airfocus.PeopleSettings:
+		add("Multiple Selection", strconv.FormatBool(s.Multiple))
+	case *airfocus.FormulaSettings:
+		add("Expression", s.Expression)
+		if s.ResultType != "" {
+			add("Result Type", string(s.ResultType))
+		}
+	case *airfocus.UnknownSettings:
+		if len(s.Raw) > 0 {
+			add("Raw Settings", string(s.Raw))
+		}
+	}
+	return view
+}
+
 // handleGetFieldInfoHTMX handles POST requests to get field details and renders HTML
 func (s *Server) handleGetFieldInfoHTMX(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
@@ -897,21 +1013,23 @@ func (s *Server) handleGetFieldInfoHTMX(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	apiKey := r.FormValue("api_key")
 	fieldName := r.FormValue("fieldSelect")
 
 	// Debug logging (never log API key)
 	log.Printf("Field info request - Field Name: '%s'", fieldName)
 
-	if apiKey == "" || fieldName == "" {
-		http.Error(w, "API key and field name are required", http.StatusBadRequest)
+	if fieldName == "" {
+		http.Error(w, "Field name is required", http.StatusBadRequest)
 		return
 	}
 
-	client := airfocus.NewClient(apiKey)
+	client, ok := s.sessionClient(w, r)
+	if !ok {
+		return
+	}
 	fields, err := client.ListFields(r.Context())
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Failed to fetch fields: %v", err), http.StatusInternalServerError)
+		s.renderError(w, err, "Failed to retrieve fields")
 		return
 	}
 
@@ -929,7 +1047,19 @@ func (s *Server) handleGetFieldInfoHTMX(w http.ResponseWriter, r *http.Request)
 	}
 
 	if foundField == nil {
-		http.Error(w, fmt.Sprintf("Field '%s' not found", fieldName), http.StatusNotFound)
+		s.renderError(w, fmt.Errorf("field %q: %w", fieldName, airfocus.ErrNotFound), "Failed to retrieve field")
+		return
+	}
+
+	settings, err := foundField.TypedSettings()
+	if err != nil {
+		s.renderError(w, err, "Failed to read field settings")
+		return
+	}
+	var settingsHTML strings.Builder
+	if err := s.templates.ExecuteTemplate(&settingsHTML, "field_settings", describeFieldSettings(settings)); err != nil {
+		log.Printf("Error executing template: %v", err)
+		http.Error(w, "Failed to render field settings", http.StatusInternalServerError)
 		return
 	}
 
@@ -949,6 +1079,7 @@ func (s *Server) handleGetFieldInfoHTMX(w http.ResponseWriter, r *http.Request)
 			</div>
 		</div>
 
+		`+settingsHTML.String()+`
 		<!-- Field Workspaces Block -->
 		<div class="content-block h-full">
 			<h3 class="text-xl font-semibold mb-2 text-gray-700">Used in Workspaces</h3>
@@ -980,16 +1111,124 @@ func (s *Server) handleGetFieldInfoHTMX(w http.ResponseWriter, r *http.Request)
 	w.Write([]byte(html.String()))
 }
 
+// clientOptionsFromEnv builds Airfocus client options from the environment:
+// AIRFOCUS_BASE_URL overrides the API endpoint and AIRFOCUS_CACHE_TTL the cache lifetime.
+func clientOptionsFromEnv() ([]airfocus.Option, error) {
+	var opts []airfocus.Option
+	if baseURL := os.Getenv("AIRFOCUS_BASE_URL"); baseURL != "" {
+		opts = append(opts, airfocus.WithBaseURL(baseURL))
+	}
+	if ttl := os.Getenv("AIRFOCUS_CACHE_TTL"); ttl != "" {
+		d, err := time.ParseDuration(ttl)
+		if err != nil {
+			return nil, fmt.Errorf("invalid AIRFOCUS_CACHE_TTL %q: %w", ttl, err)
+		}
+		opts = append(opts, airfocus.WithCacheTTL(d))
+	}
+	return opts, nil
+}
+
 // main is the entry point of the application
 func main() {
-	server, err := NewServer()
+	opts, err := clientOptionsFromEnv()
+	if err != nil {
+		log.Fatalf("Invalid configuration: %v", err)
+	}
+
+	// Any arguments select a command line subcommand instead of the web server
+	if len(os.Args) > 1 {
+		os.Exit(runCommand(context.Background(), os.Args[1:], os.Stdout, os.Stderr, opts...))
+	}
+
+	server, err := NewServer(opts...)
 	if err != nil {
 		log.Fatalf("Failed to create server: %v", err)
 	}
 
+	if timeout := os.Getenv("AIRFOCUS_SESSION_IDLE_TIMEOUT"); timeout != "" {
+		d, err := time.ParseDuration(timeout)
+		if err != nil {
+			log.Fatalf("Invalid AIRFOCUS_SESSION_IDLE_TIMEOUT %q: %v", timeout, err)
+		}
+		server.sessions.idleTTL = d
+	}
+
+	if server.sso, err = ssoFromEnv(context.Background()); err != nil {
+		log.Fatalf("Invalid SSO configuration: %v", err)
+	}
+	if server.sso != nil {
+		log.Printf("SSO enabled: operators sign in with %s", os.Getenv("AIRFOCUS_OIDC_ISSUER"))
+	}
+	if path := os.Getenv("AIRFOCUS_ROLES_FILE"); path != "" {
+		if server.roles, err = LoadRoleConfig(path); err != nil {
+			log.Fatalf("Invalid role configuration: %v", err)
+		}
+		if server.sso == nil {
+			log.Printf("AIRFOCUS_ROLES_FILE is ignored without SSO: API key sessions act with the rights of their key")
+		}
+	}
+
+	if server.policy, err = policyFromEnv(); err != nil {
+		log.Fatalf("Invalid compliance policy: %v", err)
+	}
+	if server.policy != nil {
+		log.Printf("Compliance policy loaded: %d rules", len(server.policy.Rules))
+	}
+
+	snapshots, err := snapshotConfigFromEnv()
+	if err != nil {
+		log.Fatalf("Invalid snapshot configuration: %v", err)
+	}
+	if snapshots.dir != "" {
+		apiKey, err := envOrFile("AIRFOCUS_API_KEY")
+		if err != nil {
+			log.Fatalf("Invalid snapshot configuration: %v", err)
+		}
+		if apiKey == "" {
+			log.Fatalf("AIRFOCUS_SNAPSHOT_DIR requires AIRFOCUS_API_KEY or AIRFOCUS_API_KEY_FILE")
+		}
+		if server.snapshots, err = snapshot.Open(snapshots.dir); err != nil {
+			log.Fatalf("Failed to open snapshot store: %v", err)
+		}
+		// With SSO the sessions use the key the snapshots are taken with, so
+		// they can stand in for the API when it fails. Clients of other keys
+		// never see them.
+		if server.sso != nil {
+			server.clients.SetKeyOptions(server.sso.apiKey, airfocus.WithFallback(server.snapshots.Fallback()))
+		}
+		startSnapshotScheduler(context.Background(), server.snapshots, airfocus.NewClient(apiKey, opts...), snapshots)
+		log.Printf("Snapshots enabled: every %s in %s", snapshots.interval, snapshots.dir)
+	}
+
+	if path := os.Getenv("AIRFOCUS_REVIEW_CONFIG"); path != "" {
+		reviews, err := loadReviewConfig(path)
+		if err != nil {
+			log.Fatalf("Invalid access review configuration: %v", err)
+		}
+		apiKey, err := envOrFile("AIRFOCUS_API_KEY")
+		if err != nil {
+			log.Fatalf("Invalid access review configuration: %v", err)
+		}
+		if apiKey == "" {
+			log.Fatalf("AIRFOCUS_REVIEW_CONFIG requires AIRFOCUS_API_KEY or AIRFOCUS_API_KEY_FILE")
+		}
+		startReviewScheduler(context.Background(), airfocus.NewClient(apiKey, opts...), reviews)
+		log.Printf("Access reviews enabled: every %s", reviews.interval)
+	}
+
+	// Drop clients and sessions that have not been used for a while
+	server.clients.StartJanitor(context.Background(), time.Minute)
+	server.sessions.StartJanitor(context.Background(), time.Minute)
+
 	// Serve static files
 	http.Handle("/static/", http.FileServer(http.FS(staticFS)))
 
+	// Session login and logout
+	http.HandleFunc("/api/session/login", server.handleLogin)
+	http.HandleFunc("/api/session/logout", server.handleLogout)
+	http.HandleFunc("/auth/login", server.handleSSOLogin)
+	http.HandleFunc("/auth/callback", server.handleSSOCallback)
+
 	// HTMX endpoints only (removed redundant JSON endpoints)
 	http.HandleFunc("/api/fields/htmx", server.handleListFieldsHTMX)
 	http.HandleFunc("/api/field/select/htmx", server.handleGetFieldSelectHTMX)
@@ -1000,8 +1239,23 @@ func main() {
 	http.HandleFunc("/api/workspaces/htmx", server.handleGetWorkspacesHTMX)
 	http.HandleFunc("/api/workspace/id/htmx", server.handleGetWorkspaceIDHTMX)
 	http.HandleFunc("/api/workspace/users/htmx", server.handleGetWorkspaceUsersHTMX)
+	http.HandleFunc("/api/workspace/items/htmx", server.handleGetWorkspaceItemsHTMX)
 	http.HandleFunc("/api/users/htmx", server.handleGetUsersHTMX)
 	http.HandleFunc("/api/user/info/htmx", server.handleGetUserInfoHTMX)
+	http.HandleFunc("/api/permission/htmx", server.requireRole(RoleEditor, server.handleSetPermissionHTMX))
+	http.HandleFunc("/api/access/copy/preview/htmx", server.requireRole(RoleEditor, server.handleAccessCopyPreviewHTMX))
+	http.HandleFunc("/api/access/copy/apply/htmx", server.requireRole(RoleEditor, server.handleAccessCopyApplyHTMX))
+	http.HandleFunc("/api/offboard/preview/htmx", server.requireRole(RoleAdmin, server.handleOffboardPreviewHTMX))
+	http.HandleFunc("/api/offboard/apply/htmx", server.requireRole(RoleAdmin, server.handleOffboardApplyHTMX))
+	http.HandleFunc("/api/access/matrix/export", server.handleExportAccessMatrix)
+	http.HandleFunc("/api/changes/htmx", server.handleChangesHTMX)
+	http.HandleFunc("/api/changes/export", server.handleExportChanges)
+	http.HandleFunc("/api/compliance/htmx", server.handleComplianceHTMX)
+	http.HandleFunc("/api/stale/htmx", server.handleStaleAccessHTMX)
+	http.HandleFunc("/api/stale/cleanup/htmx", server.requireRole(RoleAdmin, server.handleStaleCleanupHTMX))
+
+	// Read-only JSON API for scripts and dashboards
+	http.HandleFunc("/api/v1/", server.handleAPIv1)
 
 	// Root handler
 	http.HandleFunc("/", server.handleIndex)
@@ -0,0 +1,1389 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tibuski/goAirfocus/airfocus"
+	"github.com/tibuski/goAirfocus/airfocus/fake"
+	"github.com/tibuski/goAirfocus/oidc"
+	oidcfake "github.com/tibuski/goAirfocus/oidc/fake"
+	"github.com/tibuski/goAirfocus/policy"
+	smtpfake "github.com/tibuski/goAirfocus/review/fake"
+	"github.com/tibuski/goAirfocus/snapshot"
+)
+
+var update = flag.Bool("update", false, "update golden files in testdata")
+
+// newTestServer returns a Server whose clients talk to a fake Airfocus API
+func newTestServer(t *testing.T) *Server {
+	t.Helper()
+	srv := fake.NewServer(fake.DefaultFixtures())
+	t.Cleanup(srv.Close)
+
+	server, err := NewServer(airfocus.WithBaseURL(srv.URL))
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+	return server
+}
+
+// login starts a session for the fake API key and returns its cookie
+func login(t *testing.T, s *Server) *http.Cookie {
+	t.Helper()
+	id, err := s.sessions.Create(fake.APIKey, nil)
+	if err != nil {
+		t.Fatalf("failed to create session: %v", err)
+	}
+	return &http.Cookie{Name: sessionCookieName, Value: id}
+}
+
+// postForm sends an HTMX-style form POST with the given cookies to handler and returns the recorded response
+func postForm(handler http.HandlerFunc, form url.Values, cookies ...*http.Cookie) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	for _, cookie := range cookies {
+		req.AddCookie(cookie)
+	}
+	rec := httptest.NewRecorder()
+	handler(rec, req)
+	return rec
+}
+
+// assertGolden compares got with testdata/<name>.golden, rewriting it when -update is set
+func assertGolden(t *testing.T, name string, got string) {
+	t.Helper()
+	path := filepath.Join("testdata", name+".golden")
+	if *update {
+		if err := os.WriteFile(path, []byte(got), 0o644); err != nil {
+			t.Fatalf("failed to update golden file: %v", err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read golden file (run go test -update): %v", err)
+	}
+	if got != string(want) {
+		t.Errorf("output does not match %s\n--- got ---\n%s\n--- want ---\n%s", path, got, want)
+	}
+}
+
+func TestHTMXHandlersGolden(t *testing.T) {
+	s := newTestServer(t)
+	session := login(t, s)
+
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		form    url.Values
+	}{
+		{"license", s.handleGetLicenseInfoHTMX, url.Values{}},
+		{"workspace_select", s.handleGetWorkspacesHTMX, url.Values{}},
+		{"workspace_id", s.handleGetWorkspaceIDHTMX, url.Values{"workspace_select": {"w-roadmap"}}},
+		{"workspace_users", s.handleGetWorkspaceUsersHTMX, url.Values{"workspace_select": {"w-roadmap"}}},
+		{"workspace_items", s.handleGetWorkspaceItemsHTMX, url.Values{"workspace_select": {"w-ios"}}},
+		{"user_select", s.handleGetUsersHTMX, url.Values{}},
+		{"user_details", s.handleGetUserInfoHTMX, url.Values{"user_select": {"u-carol"}}},
+		{"field_select", s.handleGetFieldSelectHTMX, url.Values{}},
+		{"field_info", s.handleGetFieldInfoHTMX, url.Values{"fieldSelect": {"Priority"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := postForm(tt.handler, tt.form, session)
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+			}
+			assertGolden(t, tt.name, rec.Body.String())
+		})
+	}
+}
+
+func TestHTMXHandlersRequireSession(t *testing.T) {
+	s := newTestServer(t)
+
+	rec := postForm(s.handleGetWorkspacesHTMX, url.Values{})
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("without cookie: status = %d, want %d", rec.Code, http.StatusUnauthorized)
+	}
+
+	rec = postForm(s.handleGetWorkspacesHTMX, url.Values{}, &http.Cookie{Name: sessionCookieName, Value: "forged"})
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("unknown session: status = %d, want %d", rec.Code, http.StatusUnauthorized)
+	}
+	if !strings.Contains(rec.Body.String(), "log in") {
+		t.Errorf("body does not ask to log in: %q", rec.Body.String())
+	}
+
+	// Form fields are no longer accepted in place of a session
+	rec = postForm(s.handleGetWorkspacesHTMX, url.Values{"api_key": {fake.APIKey}})
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("api_key form field: status = %d, want %d", rec.Code, http.StatusUnauthorized)
+	}
+}
+
+func TestHTMXHandlersMapErrors(t *testing.T) {
+	s := newTestServer(t)
+
+	tests := []struct {
+		name       string
+		apiKey     string
+		handler    http.HandlerFunc
+		form       url.Values
+		wantStatus int
+		wantText   string
+	}{
+		{"invalid API key", "wrong-key", s.handleGetWorkspacesHTMX, url.Values{}, http.StatusUnauthorized, "Invalid API key"},
+		{"unknown workspace", fake.APIKey, s.handleGetWorkspaceIDHTMX, url.Values{"workspace_select": {"w-missing"}}, http.StatusNotFound, "not found"},
+		{"unknown field", fake.APIKey, s.handleGetFieldInfoHTMX, url.Values{"fieldSelect": {"<b>Nope</b>"}}, http.StatusNotFound, "not found"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// A session whose key was revoked after logging in behaves like an invalid key
+			id, err := s.sessions.Create(tt.apiKey, nil)
+			if err != nil {
+				t.Fatalf("failed to create session: %v", err)
+			}
+			rec := postForm(tt.handler, tt.form, &http.Cookie{Name: sessionCookieName, Value: id})
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			body := rec.Body.String()
+			if !strings.Contains(body, tt.wantText) {
+				t.Errorf("body %q does not contain %q", body, tt.wantText)
+			}
+			if strings.Contains(body, "airfocus API") {
+				t.Errorf("body leaks the raw API error: %q", body)
+			}
+		})
+	}
+}
+
+func TestSetPermissionHTMX(t *testing.T) {
+	s := newTestServer(t)
+	session := login(t, s)
+
+	rec := postForm(s.handleSetPermissionHTMX, url.Values{
+		"target_type": {"workspace"},
+		"target_id":   {"w-roadmap"},
+		"user_id":     {"u-carol"},
+		"permission":  {"write"},
+		"view":        {"workspace"},
+	}, session)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	if !strings.Contains(rec.Body.String(), `<option value="write" selected>`) {
+		t.Errorf("re-rendered workspace users do not show the new permission:\n%s", rec.Body.String())
+	}
+
+	rec = postForm(s.handleSetPermissionHTMX, url.Values{
+		"target_type": {"group"},
+		"target_id":   {"g-product"},
+		"user_id":     {"u-bob"},
+		"permission":  {"owner"},
+		"view":        {"user"},
+	}, session)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("invalid permission: status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+}
+
+func TestAccessCopyHTMX(t *testing.T) {
+	s := newTestServer(t)
+	session := login(t, s)
+	form := url.Values{
+		"source_user_id": {"u-bob"},
+		"target_user_id": {"u-carol"},
+	}
+
+	rec := postForm(s.handleAccessCopyPreviewHTMX, form, session)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("preview status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	assertGolden(t, "access_copy_preview", rec.Body.String())
+	digest := regexp.MustCompile(`name="plan_digest" value="([0-9a-f]+)"`).FindStringSubmatch(rec.Body.String())
+	if digest == nil {
+		t.Fatalf("preview has no plan digest:\n%s", rec.Body.String())
+	}
+	form.Set("plan_digest", digest[1])
+
+	// Applying refuses a plan that changed since the preview
+	setSandbox := func(permission string) {
+		t.Helper()
+		rec := postForm(s.handleSetPermissionHTMX, url.Values{
+			"target_type": {"workspace"},
+			"target_id":   {"w-sandbox"},
+			"user_id":     {"u-carol"},
+			"permission":  {permission},
+			"view":        {"workspace"},
+		}, session)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("set permission %q: status = %d, body = %s", permission, rec.Code, rec.Body.String())
+		}
+	}
+	setSandbox("read")
+	rec = postForm(s.handleAccessCopyApplyHTMX, form, session)
+	if rec.Code != http.StatusConflict || !strings.Contains(rec.Body.String(), "Preview again") {
+		t.Errorf("apply after access changed: status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	setSandbox("")
+
+	rec = postForm(s.handleAccessCopyApplyHTMX, form, session)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("apply status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	assertGolden(t, "access_copy_applied", rec.Body.String())
+
+	rec = postForm(s.handleAccessCopyPreviewHTMX, form, session)
+	if !strings.Contains(rec.Body.String(), "Nothing to change") {
+		t.Errorf("preview after applying still lists changes:\n%s", rec.Body.String())
+	}
+}
+
+func TestOffboardHTMX(t *testing.T) {
+	s := newTestServer(t)
+	session := login(t, s)
+	form := url.Values{
+		"user_id":      {"u-alice"},
+		"successor_id": {"u-bob"},
+	}
+
+	rec := postForm(s.handleOffboardPreviewHTMX, form, session)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("preview status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	assertGolden(t, "offboard_preview", rec.Body.String())
+	digest := regexp.MustCompile(`name="plan_digest" value="([0-9a-f]+)"`).FindStringSubmatch(rec.Body.String())
+	if digest == nil {
+		t.Fatalf("preview has no plan digest:\n%s", rec.Body.String())
+	}
+
+	// Applying refuses a plan that differs from the one previewed
+	form.Set("plan_digest", "outdated")
+	rec = postForm(s.handleOffboardApplyHTMX, form, session)
+	if rec.Code != http.StatusConflict || !strings.Contains(rec.Body.String(), "Preview again") {
+		t.Errorf("apply with outdated preview: status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+
+	form.Set("plan_digest", digest[1])
+	rec = postForm(s.handleOffboardApplyHTMX, form, session)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("apply status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	body := rec.Body.String()
+	for _, want := range []string{"Applied 8 changes", `href="data:text/csv;base64,`, `download="offboarding-u-alice-`} {
+		if !strings.Contains(body, want) {
+			t.Errorf("result does not contain %q:\n%s", want, body)
+		}
+	}
+
+	rec = postForm(s.handleOffboardPreviewHTMX, form, session)
+	if !strings.Contains(rec.Body.String(), "Nothing to revoke") {
+		t.Errorf("preview after offboarding still lists changes:\n%s", rec.Body.String())
+	}
+}
+
+func TestOffboardingReport(t *testing.T) {
+	rows := []accessChangeRow{
+		{AccessChange: airfocus.AccessChange{UserID: "u-bob", TargetType: "workspace", TargetID: "w-budget", TargetName: "Budget", To: "full"}, UserName: "Bob Editor"},
+		{AccessChange: airfocus.AccessChange{UserID: "u-alice", TargetType: "workspace", TargetID: "w-budget", TargetName: "Budget", From: "full"}, UserName: "Alice Admin", Error: "Failed to update workspace (not found)."},
+		{AccessChange: airfocus.AccessChange{UserID: "u-alice", TargetType: "workspace", TargetID: "w-ios", TargetName: "iOS App", From: "full"}, UserName: "Alice Admin", Error: "Skipped because a change it depends on failed.", Skipped: true},
+	}
+	report, err := offboardingReport(rows)
+	if err != nil {
+		t.Fatalf("offboardingReport: %v", err)
+	}
+	want := "user_id,user_name,target_type,target_id,target_name,previous_permission,new_permission,result\n" +
+		"u-bob,Bob Editor,workspace,w-budget,Budget,,full,applied\n" +
+		"u-alice,Alice Admin,workspace,w-budget,Budget,full,,failed: Failed to update workspace (not found).\n" +
+		"u-alice,Alice Admin,workspace,w-ios,iOS App,full,,skipped: Skipped because a change it depends on failed.\n"
+	if string(report) != want {
+		t.Errorf("report = %q, want %q", report, want)
+	}
+}
+
+func TestExportAccessMatrix(t *testing.T) {
+	s := newTestServer(t)
+	session := login(t, s)
+
+	rec := postForm(s.handleExportAccessMatrix, url.Values{"format": {"csv"}}, session)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("csv status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	if got := rec.Header().Get("Content-Type"); got != "text/csv; charset=utf-8" {
+		t.Errorf("csv content type = %q", got)
+	}
+	if got := rec.Header().Get("Content-Disposition"); !strings.HasPrefix(got, `attachment; filename="access-matrix-`) || !strings.HasSuffix(got, `.csv"`) {
+		t.Errorf("csv content disposition = %q", got)
+	}
+	if !strings.HasPrefix(rec.Body.String(), "Workspace ID,Workspace,Group Path,") {
+		t.Errorf("csv body = %q", rec.Body.String())
+	}
+
+	rec = postForm(s.handleExportAccessMatrix, url.Values{"format": {"xlsx"}}, session)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("xlsx status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	if !strings.HasPrefix(rec.Body.String(), "PK") {
+		t.Errorf("xlsx body is not a zip archive")
+	}
+
+	rec = postForm(s.handleExportAccessMatrix, url.Values{"format": {"pdf"}}, session)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("unsupported format: status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+}
+
+func TestChanges(t *testing.T) {
+	s := newTestServer(t)
+	session := login(t, s)
+	ctx := context.Background()
+
+	rec := postForm(s.handleChangesHTMX, url.Values{"from": {"20240101T000000Z"}, "to": {"now"}}, session)
+	if rec.Code != http.StatusNotFound || !strings.Contains(rec.Body.String(), "Snapshots are not enabled") {
+		t.Errorf("without snapshots: status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+
+	var err error
+	if s.snapshots, err = snapshot.Open(t.TempDir()); err != nil {
+		t.Fatalf("snapshot.Open: %v", err)
+	}
+	client := s.clients.Get(fake.APIKey)
+	snap, err := client.Snapshot(ctx)
+	if err != nil {
+		t.Fatalf("Snapshot: %v", err)
+	}
+	info, err := s.snapshots.Save(snap)
+	if err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	if err := client.SetWorkspacePermission(ctx, "w-android", "u-carol", airfocus.PermissionWrite); err != nil {
+		t.Fatalf("SetWorkspacePermission: %v", err)
+	}
+
+	rec = postForm(s.handleChangesHTMX, url.Values{"from": {info.ID}, "to": {"now"}}, session)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	if body := rec.Body.String(); !strings.Contains(body, "Granted") || !strings.Contains(body, "Carol Contributor") || !strings.Contains(body, "Android App") {
+		t.Errorf("changes do not show Carol's new access: %s", body)
+	}
+
+	rec = postForm(s.handleChangesHTMX, url.Values{"from": {info.ID}, "to": {"now"}, "user": {"bob"}}, session)
+	if body := rec.Body.String(); strings.Contains(body, "Carol") || !strings.Contains(body, "No permission changes match") {
+		t.Errorf("user filter did not exclude Carol: %s", body)
+	}
+
+	rec = postForm(s.handleExportChanges, url.Values{"from": {info.ID}, "to": {"now"}, "format": {"json"}, "permission": {"write"}}, session)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("json status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	var changes []snapshot.Change
+	if err := json.Unmarshal(rec.Body.Bytes(), &changes); err != nil {
+		t.Fatalf("export is not JSON: %v", err)
+	}
+	if len(changes) != 1 || changes[0].UserID != "u-carol" || changes[0].Kind != snapshot.Granted || changes[0].To != airfocus.PermissionWrite {
+		t.Errorf("exported changes = %+v", changes)
+	}
+
+	rec = postForm(s.handleExportChanges, url.Values{"from": {info.ID}, "to": {"now"}, "format": {"csv"}}, session)
+	if got := rec.Header().Get("Content-Disposition"); !strings.HasPrefix(got, `attachment; filename="access-changes-`) || !strings.HasSuffix(got, `.csv"`) {
+		t.Errorf("csv content disposition = %q", got)
+	}
+	if !strings.HasPrefix(rec.Body.String(), "KIND,TYPE,TARGET ID,") || !strings.Contains(rec.Body.String(), "granted,workspace,w-android,Android App,u-carol,") {
+		t.Errorf("csv body = %q", rec.Body.String())
+	}
+
+	// Snapshots of another team are not shown
+	snap.TakenAt = snap.TakenAt.Add(-time.Hour)
+	snap.Team.TeamID = "other-team"
+	other, err := s.snapshots.Save(snap)
+	if err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	rec = postForm(s.handleChangesHTMX, url.Values{"from": {other.ID}, "to": {"now"}}, session)
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("other team: status = %d, want %d", rec.Code, http.StatusForbidden)
+	}
+
+	rec = postForm(s.handleChangesHTMX, url.Values{"from": {info.ID}, "to": {"now"}})
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("without session: status = %d, want %d", rec.Code, http.StatusUnauthorized)
+	}
+}
+
+func TestRunCommandReview(t *testing.T) {
+	srv := fake.NewServer(fake.DefaultFixtures())
+	t.Cleanup(srv.Close)
+	mail := smtpfake.NewServer()
+	t.Cleanup(func() { mail.Close() })
+	opts := []airfocus.Option{airfocus.WithBaseURL(srv.URL)}
+	t.Setenv("AIRFOCUS_API_KEY", fake.APIKey)
+	t.Setenv("AIRFOCUS_REVIEW_CONFIG", "")
+	ctx := context.Background()
+
+	dir := t.TempDir()
+	writeConfig := func(name, config string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(config), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	config := writeConfig("review.json", `{
+		"format": "markdown",
+		"stateFile": "`+filepath.Join(dir, "state.json")+`",
+		"smtp": {"addr": "`+mail.Addr+`", "from": "reviews@example.com", "to": ["compliance@example.com"], "owners": true}
+	}`)
+
+	var stdout, stderr bytes.Buffer
+	if code := runCommand(ctx, []string{"review", "send", "-config", config}, &stdout, &stderr, opts...); code != 0 {
+		t.Fatalf("review send: exit code = %d, stderr = %s", code, stderr.String())
+	}
+	if !strings.HasPrefix(stdout.String(), "Sent access review of 5 workspaces with 0 changes") {
+		t.Errorf("review send printed %q", stdout.String())
+	}
+	// The full report to compliance, then one per workspace owner: Alice and Bob
+	messages := mail.Messages()
+	if len(messages) != 3 || messages[0].To[0] != "compliance@example.com" || messages[1].To[0] != "alice@example.com" {
+		t.Fatalf("received %+v", messages)
+	}
+	if body := messages[1].Body(); !strings.Contains(body, "## Budget") || strings.Contains(body, "## Android App") {
+		t.Errorf("Alice's review does not cover just her workspaces:\n%s", body)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "state.json")); err != nil {
+		t.Errorf("review state was not saved: %v", err)
+	}
+
+	// A rejected owner address is reported, but the review is still kept so
+	// that the other recipients do not receive it again
+	mail.Reject("bob@example.com")
+	stateBefore, _ := os.ReadFile(filepath.Join(dir, "state.json"))
+	stdout.Reset()
+	if code := runCommand(ctx, []string{"review", "send", "-config", config}, &stdout, &stderr, opts...); code != 0 {
+		t.Fatalf("review send with a rejected owner: exit code = %d, stderr = %s", code, stderr.String())
+	}
+	if !strings.Contains(stderr.String(), "Warning: access review for bob@example.com") {
+		t.Errorf("rejected owner was not reported: stderr = %q", stderr.String())
+	}
+	if stateAfter, _ := os.ReadFile(filepath.Join(dir, "state.json")); bytes.Equal(stateBefore, stateAfter) {
+		t.Error("review state was not saved after an owner delivery failed")
+	}
+	if got := len(mail.Messages()); got != 5 {
+		t.Errorf("received %d messages, want 5", got)
+	}
+
+	stdout.Reset()
+	if code := runCommand(ctx, []string{"review", "preview", "-config", config}, &stdout, &stderr, opts...); code != 0 {
+		t.Fatalf("review preview: exit code = %d, stderr = %s", code, stderr.String())
+	}
+	if !strings.HasPrefix(stdout.String(), "# Access review for Acme") || len(mail.Messages()) != 5 {
+		t.Errorf("review preview printed %q and sent %d messages", stdout.String(), len(mail.Messages())-5)
+	}
+
+	for name, config := range map[string]string{
+		"unknown field":  `{"stateFile": "s", "webhook": {"url": "https://example.com"}, "schedule": "monthly"}`,
+		"no state file":  `{"webhook": {"url": "https://example.com"}}`,
+		"no delivery":    `{"stateFile": "s"}`,
+		"bad format":     `{"stateFile": "s", "format": "pdf", "webhook": {"url": "https://example.com"}}`,
+		"bad interval":   `{"stateFile": "s", "interval": "monthly", "webhook": {"url": "https://example.com"}}`,
+		"no recipients":  `{"stateFile": "s", "smtp": {"addr": "localhost:25", "from": "a@example.com"}}`,
+		"bad webhook":    `{"stateFile": "s", "webhook": {"url": "ftp://example.com"}}`,
+		"both passwords": `{"stateFile": "s", "smtp": {"addr": "localhost:25", "from": "a@example.com", "to": ["b@example.com"], "password": "x", "passwordFile": "y"}}`,
+	} {
+		if _, err := loadReviewConfig(writeConfig("invalid.json", config)); err == nil {
+			t.Errorf("%s: loadReviewConfig succeeded", name)
+		}
+	}
+}
+
+func TestCompliance(t *testing.T) {
+	s := newTestServer(t)
+	session := login(t, s)
+
+	rec := postForm(s.handleComplianceHTMX, url.Values{}, session)
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("without policy: status = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+
+	var err error
+	if s.policy, err = policy.Parse([]byte(`
+rules:
+  - name: Unknown users hold nothing
+    check: no-permissions
+    users: unknown
+  - name: Contributors never have full access
+    check: max-permission
+    role: contributor
+    permission: write
+`)); err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	rec = postForm(s.handleComplianceHTMX, url.Values{}, session)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	body := rec.Body.String()
+	for _, want := range []string{"1 violations of 2 rules", "Unknown User holds read on workspace Budget", "✓</span> Contributors never have full access"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("compliance result does not contain %q:\n%s", want, body)
+		}
+	}
+}
+
+func TestStaleAccessHTMX(t *testing.T) {
+	s := newTestServer(t)
+	session := login(t, s)
+	cleanup := s.requireRole(RoleAdmin, s.handleStaleCleanupHTMX)
+
+	rec := postForm(s.handleStaleAccessHTMX, url.Values{}, session)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	body := rec.Body.String()
+	for _, want := range []string{"1 stale permissions, 1 workspaces without an owner", "Unknown User", "Budget", "<li>Android App</li>", "Revoke All", "Assign Owner"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("report does not contain %q:\n%s", want, body)
+		}
+	}
+	unknown, ownerless := staleDigestOf(t, body, "unknown"), staleDigestOf(t, body, "ownerless")
+
+	tests := []struct {
+		name   string
+		form   url.Values
+		status int
+		want   string
+	}{
+		{"unknown category", url.Values{"category": {"everyone"}}, http.StatusBadRequest, "Unknown category"},
+		{"owner required", url.Values{"category": {"ownerless"}, "plan_digest": {ownerless}}, http.StatusBadRequest, "Owner is required"},
+		{"disabled owner", url.Values{"category": {"ownerless"}, "owner_id": {"u-dave"}, "plan_digest": {ownerless}}, http.StatusBadRequest, "Dave Disabled is disabled"},
+		{"without preview", url.Values{"category": {"unknown"}}, http.StatusConflict, "Preview again"},
+		{"revoke unknown users", url.Values{"category": {"unknown"}, "plan_digest": {unknown}}, http.StatusOK, "Applied 1 changes"},
+		{"assign owner", url.Values{"category": {"ownerless"}, "owner_id": {"u-bob"}, "plan_digest": {ownerless}}, http.StatusOK, "Applied 1 changes"},
+		{"already cleaned up", url.Values{"category": {"unknown"}, "plan_digest": {unknown}}, http.StatusConflict, "Preview again"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := postForm(cleanup, tt.form, session)
+			if rec.Code != tt.status {
+				t.Errorf("status = %d, want %d", rec.Code, tt.status)
+			}
+			if !strings.Contains(rec.Body.String(), tt.want) {
+				t.Errorf("body does not contain %q:\n%s", tt.want, rec.Body.String())
+			}
+		})
+	}
+
+	rec = postForm(s.handleStaleAccessHTMX, url.Values{}, session)
+	if !strings.Contains(rec.Body.String(), "No stale access found") {
+		t.Errorf("report after cleanup still lists stale access:\n%s", rec.Body.String())
+	}
+}
+
+// staleDigestOf returns the plan digest of the cleanup form of a stale access category
+func staleDigestOf(t *testing.T, body, category string) string {
+	t.Helper()
+	match := regexp.MustCompile(`name="category" value="` + category + `">\s*<input type="hidden" name="plan_digest" value="([0-9a-f]+)"`).FindStringSubmatch(body)
+	if match == nil {
+		t.Fatalf("report has no cleanup digest for %s:\n%s", category, body)
+	}
+	return match[1]
+}
+
+func TestStaleCleanupRefusesChangedCategory(t *testing.T) {
+	s := newTestServer(t)
+	session := login(t, s)
+	cleanup := s.requireRole(RoleAdmin, s.handleStaleCleanupHTMX)
+
+	rec := postForm(s.handleStaleAccessHTMX, url.Values{}, session)
+	form := url.Values{"category": {"unknown"}, "plan_digest": {staleDigestOf(t, rec.Body.String(), "unknown")}}
+
+	// Another grant of an unknown user appears after the preview
+	rec = postForm(s.handleSetPermissionHTMX, url.Values{
+		"target_type": {"workspace"},
+		"target_id":   {"w-roadmap"},
+		"user_id":     {"u-ghost"},
+		"permission":  {"read"},
+		"view":        {"workspace"},
+	}, session)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("set permission: status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+
+	rec = postForm(cleanup, form, session)
+	if rec.Code != http.StatusConflict || !strings.Contains(rec.Body.String(), "Preview again") {
+		t.Errorf("cleanup after the category changed: status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	rec = postForm(s.handleStaleAccessHTMX, url.Values{}, session)
+	if !strings.Contains(rec.Body.String(), "2 stale permissions") {
+		t.Errorf("refused cleanup revoked access:\n%s", rec.Body.String())
+	}
+}
+
+func TestRunCommandCompliance(t *testing.T) {
+	srv := fake.NewServer(fake.DefaultFixtures())
+	t.Cleanup(srv.Close)
+	opts := []airfocus.Option{airfocus.WithBaseURL(srv.URL)}
+	t.Setenv("AIRFOCUS_API_KEY", fake.APIKey)
+	ctx := context.Background()
+
+	dir := t.TempDir()
+	failing := filepath.Join(dir, "failing.yaml")
+	passing := filepath.Join(dir, "passing.yaml")
+	for path, rules := range map[string]string{
+		failing: "rules:\n  - name: Unknown users hold nothing\n    check: no-permissions\n    users: unknown\n",
+		passing: "rules:\n  - name: Contributors never have full access\n    check: max-permission\n    role: contributor\n    permission: write\n",
+	} {
+		if err := os.WriteFile(path, []byte(rules), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var stdout, stderr bytes.Buffer
+	if code := runCommand(ctx, []string{"compliance", "check", "-policy", failing, "-format", "csv"}, &stdout, &stderr, opts...); code != 3 {
+		t.Fatalf("failing policy: exit code = %d, want 3, stderr = %s", code, stderr.String())
+	}
+	if !strings.Contains(stdout.String(), "Unknown users hold nothing,Budget,Unknown User,") || !strings.Contains(stderr.String(), "1 violations of 1 rules") {
+		t.Errorf("stdout = %q, stderr = %q", stdout.String(), stderr.String())
+	}
+
+	stdout.Reset()
+	t.Setenv("AIRFOCUS_POLICY_FILE", passing)
+	if code := runCommand(ctx, []string{"compliance", "check", "-format", "json"}, &stdout, &stderr, opts...); code != 0 {
+		t.Fatalf("passing policy: exit code = %d, stderr = %s", code, stderr.String())
+	}
+	if got := strings.TrimSpace(stdout.String()); got != "[]" {
+		t.Errorf("passing policy printed %q", got)
+	}
+}
+
+func TestRunCommandExportAccessMatrix(t *testing.T) {
+	srv := fake.NewServer(fake.DefaultFixtures())
+	t.Cleanup(srv.Close)
+	opts := []airfocus.Option{airfocus.WithBaseURL(srv.URL)}
+	ctx := context.Background()
+
+	t.Setenv("AIRFOCUS_API_KEY", fake.APIKey)
+	var stdout, stderr bytes.Buffer
+	if code := runCommand(ctx, []string{"export", "access-matrix"}, &stdout, &stderr, opts...); code != 0 {
+		t.Fatalf("exit code = %d, stderr = %s", code, stderr.String())
+	}
+	if !strings.Contains(stdout.String(), "w-budget,Budget,Finance,full,") {
+		t.Errorf("stdout = %q", stdout.String())
+	}
+
+	out := filepath.Join(t.TempDir(), "matrix.xlsx")
+	if code := runCommand(ctx, []string{"export", "access-matrix", "-format", "xlsx", "-o", out}, &stdout, &stderr, opts...); code != 0 {
+		t.Fatalf("exit code = %d, stderr = %s", code, stderr.String())
+	}
+	if zr, err := zip.OpenReader(out); err != nil {
+		t.Errorf("output is not a workbook: %v", err)
+	} else {
+		zr.Close()
+	}
+
+	stderr.Reset()
+	if code := runCommand(ctx, []string{"export", "access-matrix", "-format", "pdf"}, &stdout, &stderr, opts...); code != 2 {
+		t.Errorf("unsupported format: exit code = %d, want 2", code)
+	}
+
+	t.Setenv("AIRFOCUS_API_KEY", "")
+	stderr.Reset()
+	if code := runCommand(ctx, []string{"export", "access-matrix"}, &stdout, &stderr, opts...); code != 1 || !strings.Contains(stderr.String(), "AIRFOCUS_API_KEY") {
+		t.Errorf("missing key: exit code = %d, stderr = %q", code, stderr.String())
+	}
+}
+
+// getAPI sends a GET request to the JSON API with the given bearer token
+func getAPI(s *Server, path, apiKey string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	if apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+apiKey)
+	}
+	rec := httptest.NewRecorder()
+	s.handleAPIv1(rec, req)
+	return rec
+}
+
+func TestAPIv1Golden(t *testing.T) {
+	s := newTestServer(t)
+
+	tests := []struct {
+		name string
+		path string
+	}{
+		{"api_users", "/api/v1/users"},
+		{"api_user_workspaces", "/api/v1/users/u-bob/workspaces"},
+		{"api_workspace_users", "/api/v1/workspaces/w-roadmap/users"},
+		{"api_fields", "/api/v1/fields"},
+		{"api_license", "/api/v1/license"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := getAPI(s, tt.path, fake.APIKey)
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+			}
+			if got := rec.Header().Get("Content-Type"); got != "application/json" {
+				t.Errorf("content type = %q", got)
+			}
+			assertGolden(t, tt.name, rec.Body.String())
+		})
+	}
+}
+
+func TestAPIv1Errors(t *testing.T) {
+	s := newTestServer(t)
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		apiKey     string
+		wantStatus int
+	}{
+		{"missing API key", http.MethodGet, "/api/v1/users", "", http.StatusUnauthorized},
+		{"invalid API key", http.MethodGet, "/api/v1/users", "wrong-key", http.StatusUnauthorized},
+		{"unknown user", http.MethodGet, "/api/v1/users/u-missing/workspaces", fake.APIKey, http.StatusNotFound},
+		{"unknown workspace", http.MethodGet, "/api/v1/workspaces/w-missing/users", fake.APIKey, http.StatusNotFound},
+		{"unknown route", http.MethodGet, "/api/v1/items", fake.APIKey, http.StatusNotFound},
+		{"wrong method", http.MethodPost, "/api/v1/users", fake.APIKey, http.StatusMethodNotAllowed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			if tt.apiKey != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.apiKey)
+			}
+			rec := httptest.NewRecorder()
+			s.handleAPIv1(rec, req)
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			var body struct {
+				Error string `json:"error"`
+			}
+			if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil || body.Error == "" {
+				t.Errorf("body is not a JSON error: %q", rec.Body.String())
+			}
+		})
+	}
+
+	// Only the valid key has a client
+	if n := s.clients.Len(); n != 1 {
+		t.Errorf("registry holds %d clients, want 1", n)
+	}
+}
+
+func TestAPIv1OpenAPIDocument(t *testing.T) {
+	s := newTestServer(t)
+
+	rec := getAPI(s, "/api/v1/openapi.json", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d", rec.Code)
+	}
+	var doc struct {
+		OpenAPI string                     `json:"openapi"`
+		Paths   map[string]json.RawMessage `json:"paths"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &doc); err != nil {
+		t.Fatalf("document is not valid JSON: %v", err)
+	}
+	for _, path := range []string{"/users", "/users/{userId}/workspaces", "/workspaces/{workspaceId}/users", "/fields", "/license"} {
+		if _, ok := doc.Paths[path]; !ok {
+			t.Errorf("document does not describe %s", path)
+		}
+	}
+}
+
+func TestRunCommandReports(t *testing.T) {
+	srv := fake.NewServer(fake.DefaultFixtures())
+	t.Cleanup(srv.Close)
+	opts := []airfocus.Option{airfocus.WithBaseURL(srv.URL)}
+	t.Setenv("AIRFOCUS_API_KEY", fake.APIKey)
+
+	tests := []struct {
+		name string
+		args []string
+	}{
+		{"cli_users_list", []string{"users", "list"}},
+		{"cli_user_access", []string{"user", "access", "Carol@Example.com"}},
+		{"cli_user_access_json", []string{"user", "access", "-format", "json", "erin@example.com"}},
+		{"cli_workspace_users_csv", []string{"workspace", "users", "roadmap", "-format", "csv"}},
+		{"cli_fields_list", []string{"fields", "list"}},
+		{"cli_fields_unused", []string{"fields", "list", "--unused", "-format", "json"}},
+		{"cli_license", []string{"license"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			if code := runCommand(context.Background(), tt.args, &stdout, &stderr, opts...); code != 0 {
+				t.Fatalf("exit code = %d, stderr = %s", code, stderr.String())
+			}
+			assertGolden(t, tt.name, stdout.String())
+		})
+	}
+}
+
+func TestRunCommandErrors(t *testing.T) {
+	srv := fake.NewServer(fake.DefaultFixtures())
+	t.Cleanup(srv.Close)
+	opts := []airfocus.Option{airfocus.WithBaseURL(srv.URL)}
+	t.Setenv("AIRFOCUS_API_KEY", fake.APIKey)
+	t.Setenv("AIRFOCUS_SNAPSHOT_DIR", "")
+	t.Setenv("AIRFOCUS_REVIEW_CONFIG", "")
+	t.Setenv("AIRFOCUS_POLICY_FILE", "")
+
+	tests := []struct {
+		name     string
+		args     []string
+		wantCode int
+		wantText string
+	}{
+		{"unknown command", []string{"items", "list"}, 2, "Usage:"},
+		{"missing argument", []string{"user", "access"}, 2, "missing argument"},
+		{"extra argument", []string{"users", "list", "now"}, 2, "unexpected argument"},
+		{"unknown format", []string{"license", "-format", "yaml"}, 2, "unsupported format"},
+		{"unknown user", []string{"user", "access", "nobody@example.com"}, 1, "no user with email"},
+		{"unknown workspace", []string{"workspace", "users", "Nope"}, 1, "no workspace named"},
+		{"no snapshot directory", []string{"snapshot", "list"}, 2, "no snapshot directory"},
+		{"no review configuration", []string{"review", "send"}, 2, "no review configuration"},
+		{"no policy file", []string{"compliance", "check"}, 2, "no policy file"},
+		{"unknown review format", []string{"review", "preview", "-format", "pdf"}, 2, "unsupported format"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			if code := runCommand(context.Background(), tt.args, &stdout, &stderr, opts...); code != tt.wantCode {
+				t.Errorf("exit code = %d, want %d", code, tt.wantCode)
+			}
+			if !strings.Contains(stderr.String(), tt.wantText) {
+				t.Errorf("stderr %q does not contain %q", stderr.String(), tt.wantText)
+			}
+		})
+	}
+}
+
+func TestSessionStore(t *testing.T) {
+	store, err := NewSessionStore(time.Minute)
+	if err != nil {
+		t.Fatalf("NewSessionStore: %v", err)
+	}
+	now := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+	store.now = func() time.Time { return now }
+
+	id, err := store.Create("secret-key", nil)
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if bytes.Contains(store.sessions[id].sealedKey, []byte("secret-key")) {
+		t.Error("API key is stored in plain text")
+	}
+	if key, _, ok := store.Lookup(id); !ok || key != "secret-key" {
+		t.Errorf("Lookup = %q, %v; want secret-key, true", key, ok)
+	}
+
+	// Using a session keeps it alive
+	now = now.Add(50 * time.Second)
+	if _, _, ok := store.Lookup(id); !ok {
+		t.Error("session expired while in use")
+	}
+	now = now.Add(61 * time.Second)
+	if _, _, ok := store.Lookup(id); ok {
+		t.Error("idle session did not expire")
+	}
+	if store.Len() != 0 {
+		t.Errorf("Len = %d after expiry, want 0", store.Len())
+	}
+
+	// A sealed key only opens under the session it was created for
+	first, _ := store.Create("first-key", nil)
+	second, _ := store.Create("second-key", nil)
+	store.sessions[second].nonce = store.sessions[first].nonce
+	store.sessions[second].sealedKey = store.sessions[first].sealedKey
+	if _, _, ok := store.Lookup(second); ok {
+		t.Error("sealed key of another session was accepted")
+	}
+
+	store.Delete(first)
+	if _, _, ok := store.Lookup(first); ok {
+		t.Error("deleted session is still valid")
+	}
+}
+
+func TestSessionStoreReleasesKeys(t *testing.T) {
+	store, err := NewSessionStore(time.Minute)
+	if err != nil {
+		t.Fatalf("NewSessionStore: %v", err)
+	}
+	now := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+	store.now = func() time.Time { return now }
+	clients := NewClientRegistry(time.Hour, 10)
+	store.OnRelease(clients.Remove)
+
+	first, _ := store.Create("shared-key", nil)
+	second, _ := store.Create("shared-key", nil)
+	other, _ := store.Create("other-key", nil)
+	clients.Get("shared-key")
+	clients.Get("other-key")
+
+	store.Delete(first)
+	if clients.Len() != 2 {
+		t.Errorf("registry holds %d clients after one of two sessions ended, want 2", clients.Len())
+	}
+	store.Delete(second)
+	if clients.Len() != 1 {
+		t.Errorf("registry holds %d clients after the last session of a key ended, want 1", clients.Len())
+	}
+
+	now = now.Add(2 * time.Minute)
+	if _, _, ok := store.Lookup(other); ok {
+		t.Error("idle session did not expire")
+	}
+	if clients.Len() != 0 {
+		t.Errorf("registry holds %d clients after the last session expired, want 0", clients.Len())
+	}
+}
+
+func TestLoginAndLogout(t *testing.T) {
+	s := newTestServer(t)
+
+	rec := postForm(s.handleLogin, url.Values{"api_key": {"wrong-key"}})
+	if rec.Code != http.StatusUnauthorized || !strings.Contains(rec.Body.String(), "Invalid API key") {
+		t.Errorf("invalid key: status = %d, body = %q", rec.Code, rec.Body.String())
+	}
+	if len(rec.Result().Cookies()) != 0 {
+		t.Error("invalid key was given a session cookie")
+	}
+	if n := s.clients.Len(); n != 0 {
+		t.Errorf("invalid key left %d clients in the registry, want 0", n)
+	}
+
+	rec = postForm(s.handleLogin, url.Values{"api_key": {fake.APIKey}})
+	if rec.Code != http.StatusNoContent || rec.Header().Get("HX-Refresh") != "true" {
+		t.Fatalf("login: status = %d, HX-Refresh = %q", rec.Code, rec.Header().Get("HX-Refresh"))
+	}
+	cookies := rec.Result().Cookies()
+	if len(cookies) != 1 {
+		t.Fatalf("login set %d cookies, want 1", len(cookies))
+	}
+	session := cookies[0]
+	if session.Name != sessionCookieName || !session.HttpOnly || session.SameSite != http.SameSiteStrictMode {
+		t.Errorf("session cookie = %+v, want HttpOnly SameSite=Strict %s", session, sessionCookieName)
+	}
+	if strings.Contains(session.Value, fake.APIKey) {
+		t.Error("session cookie contains the API key")
+	}
+
+	rec = postForm(s.handleGetWorkspacesHTMX, url.Values{}, session)
+	if rec.Code != http.StatusOK {
+		t.Errorf("request with session: status = %d", rec.Code)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.AddCookie(session)
+	index := httptest.NewRecorder()
+	s.handleIndex(index, req)
+	if body := index.Body.String(); !strings.Contains(body, "Logged in to <span class=\"font-semibold\">Acme</span>") || strings.Contains(body, `name="api_key"`) {
+		t.Errorf("index does not show the logged in state")
+	}
+
+	rec = postForm(s.handleLogout, url.Values{}, session)
+	if rec.Code != http.StatusNoContent {
+		t.Errorf("logout: status = %d", rec.Code)
+	}
+	if cookies := rec.Result().Cookies(); len(cookies) != 1 || cookies[0].MaxAge >= 0 {
+		t.Errorf("logout did not clear the cookie: %+v", cookies)
+	}
+	rec = postForm(s.handleGetWorkspacesHTMX, url.Values{}, session)
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("request after logout: status = %d, want %d", rec.Code, http.StatusUnauthorized)
+	}
+
+	index = httptest.NewRecorder()
+	s.handleIndex(index, httptest.NewRequest(http.MethodGet, "/", nil))
+	if body := index.Body.String(); !strings.Contains(body, `name="api_key"`) || strings.Contains(body, "Load Workspaces") {
+		t.Errorf("index without session does not show only the login form")
+	}
+}
+
+// newSSOTestServer returns a test server that signs in through a fake OIDC
+// provider, allowing the example.com domain and the airfocus-admins group
+func newSSOTestServer(t *testing.T) *Server {
+	t.Helper()
+	s := newTestServer(t)
+	idp := oidcfake.NewServer(
+		oidcfake.User{Subject: "u-alice", Email: "alice@example.com", EmailVerified: true, Name: "Alice Admin"},
+		oidcfake.User{Subject: "u-grace", Email: "grace@partner.test", EmailVerified: true, Name: "Grace", Groups: []string{"airfocus-admins"}},
+		oidcfake.User{Subject: "u-mallory", Email: "mallory@outsider.test", EmailVerified: true, Name: "Mallory"},
+		oidcfake.User{Subject: "u-eve", Email: "eve@example.com", EmailVerified: false, Name: "Eve"},
+		oidcfake.User{Subject: "u-ivan", Email: "ivan@partner.test", EmailVerified: false, Name: "Ivan", Groups: []string{"airfocus-admins"}},
+	)
+	t.Cleanup(idp.Close)
+
+	provider, err := oidc.Discover(context.Background(), oidc.Config{
+		Issuer:       idp.URL,
+		ClientID:     oidcfake.ClientID,
+		ClientSecret: oidcfake.ClientSecret,
+		RedirectURL:  "http://app.test/auth/callback",
+	})
+	if err != nil {
+		t.Fatalf("Discover: %v", err)
+	}
+	if s.sso, err = NewSSO(provider, fake.APIKey, []string{"example.com"}, []string{"airfocus-admins"}); err != nil {
+		t.Fatalf("NewSSO: %v", err)
+	}
+	return s
+}
+
+// ssoLogin signs in as email through the fake provider and returns the callback response
+func ssoLogin(t *testing.T, s *Server, email string) *httptest.ResponseRecorder {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	s.handleSSOLogin(rec, httptest.NewRequest(http.MethodGet, "/auth/login?login_hint="+url.QueryEscape(email), nil))
+	if rec.Code != http.StatusFound {
+		t.Fatalf("login: status = %d, want 302", rec.Code)
+	}
+	stateCookies := rec.Result().Cookies()
+
+	noRedirect := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	resp, err := noRedirect.Get(rec.Header().Get("Location"))
+	if err != nil {
+		t.Fatalf("authorize: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusFound {
+		t.Fatalf("authorize: status = %d, want 302", resp.StatusCode)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, resp.Header.Get("Location"), nil)
+	for _, cookie := range stateCookies {
+		req.AddCookie(cookie)
+	}
+	rec = httptest.NewRecorder()
+	s.handleSSOCallback(rec, req)
+	return rec
+}
+
+func TestSSOLogin(t *testing.T) {
+	s := newSSOTestServer(t)
+
+	index := httptest.NewRecorder()
+	s.handleIndex(index, httptest.NewRequest(http.MethodGet, "/", nil))
+	if body := index.Body.String(); !strings.Contains(body, `href="/auth/login"`) || strings.Contains(body, `name="api_key"`) {
+		t.Errorf("index without session does not offer SSO sign-in only")
+	}
+	if rec := postForm(s.handleLogin, url.Values{"api_key": {fake.APIKey}}); rec.Code != http.StatusNotFound {
+		t.Errorf("API key login with SSO: status = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+
+	for _, email := range []string{"alice@example.com", "grace@partner.test"} {
+		rec := ssoLogin(t, s, email)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: callback status = %d, body = %q", email, rec.Code, rec.Body.String())
+		}
+		var session *http.Cookie
+		for _, cookie := range rec.Result().Cookies() {
+			if cookie.Name == sessionCookieName {
+				session = cookie
+			}
+		}
+		if session == nil {
+			t.Fatalf("%s: no session cookie", email)
+		}
+
+		if _, operator, ok := s.sessions.Lookup(session.Value); !ok || operator == nil || operator.Email != email || !operator.EmailVerified {
+			t.Errorf("%s: session operator = %+v, %v", email, operator, ok)
+		}
+		if rec := postForm(s.handleGetWorkspacesHTMX, url.Values{}, session); rec.Code != http.StatusOK {
+			t.Errorf("%s: request with SSO session: status = %d", email, rec.Code)
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	for _, cookie := range ssoLogin(t, s, "alice@example.com").Result().Cookies() {
+		req.AddCookie(cookie)
+	}
+	index = httptest.NewRecorder()
+	s.handleIndex(index, req)
+	if body := index.Body.String(); !strings.Contains(body, "Signed in as <span class=\"font-semibold\">Alice Admin</span> (alice@example.com) to <span class=\"font-semibold\">Acme</span>") {
+		t.Errorf("index does not show the signed in operator")
+	}
+
+	// Neither the domain nor the groups are allowed, or the email is not verified
+	for _, email := range []string{"mallory@outsider.test", "eve@example.com"} {
+		rec := ssoLogin(t, s, email)
+		if rec.Code != http.StatusForbidden {
+			t.Errorf("%s: callback status = %d, want %d", email, rec.Code, http.StatusForbidden)
+		}
+		for _, cookie := range rec.Result().Cookies() {
+			if cookie.Name == sessionCookieName {
+				t.Errorf("%s: was given a session", email)
+			}
+		}
+	}
+}
+
+func TestSSORolesByEmailNeedVerifiedEmail(t *testing.T) {
+	s := newSSOTestServer(t)
+	s.roles = &RoleConfig{
+		Default:   RoleViewer,
+		Operators: map[string]Role{"alice@example.com": RoleEditor, "ivan@partner.test": RoleAdmin},
+	}
+
+	tests := []struct {
+		email string
+		want  Role
+	}{
+		{"alice@example.com", RoleEditor},
+		// Allowed through a group, but the email claiming the admin role is not verified
+		{"ivan@partner.test", RoleViewer},
+	}
+	for _, tt := range tests {
+		rec := ssoLogin(t, s, tt.email)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: callback status = %d, body = %q", tt.email, rec.Code, rec.Body.String())
+		}
+		req := httptest.NewRequest(http.MethodPost, "/", nil)
+		for _, cookie := range rec.Result().Cookies() {
+			req.AddCookie(cookie)
+		}
+		if got := s.role(req); got != tt.want {
+			t.Errorf("%s: role = %s, want %s", tt.email, got, tt.want)
+		}
+	}
+}
+
+func TestSSOAllowed(t *testing.T) {
+	verified, unverified := true, false
+	tests := []struct {
+		name   string
+		claims oidc.Claims
+		trust  bool
+		want   bool
+	}{
+		{"verified email", oidc.Claims{Email: "alice@example.com", EmailVerified: &verified}, false, true},
+		{"unverified email", oidc.Claims{Email: "eve@example.com", EmailVerified: &unverified}, false, false},
+		{"no email_verified claim", oidc.Claims{Email: "eve@example.com"}, false, false},
+		{"no email_verified claim, trusted", oidc.Claims{Email: "eve@example.com"}, true, true},
+		{"unverified email, trusted", oidc.Claims{Email: "eve@example.com", EmailVerified: &unverified}, true, false},
+		{"other domain", oidc.Claims{Email: "mallory@outsider.test", EmailVerified: &verified}, false, false},
+		{"allowed group without verified email", oidc.Claims{Email: "grace@partner.test", Groups: []string{"airfocus-admins"}}, false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sso, err := NewSSO(nil, fake.APIKey, []string{"example.com"}, []string{"airfocus-admins"})
+			if err != nil {
+				t.Fatalf("NewSSO: %v", err)
+			}
+			sso.trustUnverifiedEmail = tt.trust
+			if got := sso.Allowed(tt.claims); got != tt.want {
+				t.Errorf("Allowed = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSSOCallbackChecksState(t *testing.T) {
+	s := newSSOTestServer(t)
+
+	rec := httptest.NewRecorder()
+	s.handleSSOLogin(rec, httptest.NewRequest(http.MethodGet, "/auth/login", nil))
+	state := rec.Result().Cookies()[0]
+
+	// A callback without the state cookie, e.g. from another browser, is refused
+	req := httptest.NewRequest(http.MethodGet, "/auth/callback?code=x&state="+url.QueryEscape(state.Value), nil)
+	rec = httptest.NewRecorder()
+	s.handleSSOCallback(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("callback without state cookie: status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+
+	// A state is only accepted once
+	req.AddCookie(state)
+	rec = httptest.NewRecorder()
+	s.handleSSOCallback(rec, req)
+	if rec.Code != http.StatusBadGateway {
+		t.Errorf("callback with an unknown code: status = %d, want %d", rec.Code, http.StatusBadGateway)
+	}
+	rec = httptest.NewRecorder()
+	s.handleSSOCallback(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("replayed callback: status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+}
+
+func TestLoadRoleConfig(t *testing.T) {
+	dir := t.TempDir()
+	write := func(content string) string {
+		path := filepath.Join(dir, "roles.json")
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	config, err := LoadRoleConfig(write(`{
+		"operators": {"Alice@Example.com": "editor"},
+		"groups": {"airfocus-admins": "admin", "airfocus-editors": "editor"}
+	}`))
+	if err != nil {
+		t.Fatalf("LoadRoleConfig: %v", err)
+	}
+	tests := []struct {
+		operator Operator
+		want     Role
+	}{
+		{Operator{Email: "alice@example.com", EmailVerified: true}, RoleEditor},
+		{Operator{Email: "alice@example.com", EmailVerified: true, Groups: []string{"airfocus-admins"}}, RoleAdmin},
+		{Operator{Email: "bob@example.com", EmailVerified: true, Groups: []string{"airfocus-editors", "staff"}}, RoleEditor},
+		{Operator{Email: "carol@example.com", EmailVerified: true}, RoleViewer},
+		// An unverified email does not get the role configured for it
+		{Operator{Email: "alice@example.com"}, RoleViewer},
+		{Operator{Email: "alice@example.com", Groups: []string{"airfocus-editors"}}, RoleEditor},
+	}
+	for _, tt := range tests {
+		if got := config.RoleOf(&tt.operator); got != tt.want {
+			t.Errorf("RoleOf(%s %v) = %s, want %s", tt.operator.Email, tt.operator.Groups, got, tt.want)
+		}
+	}
+
+	for _, content := range []string{
+		`{"default": "owner"}`,
+		`{"operators": {"alice@example.com": "superuser"}}`,
+		`{"groups": {"admins": ""}}`,
+		`{"roles": {}}`,
+	} {
+		if _, err := LoadRoleConfig(write(content)); err == nil {
+			t.Errorf("LoadRoleConfig(%s) succeeded", content)
+		}
+	}
+}
+
+func TestRoleEnforcement(t *testing.T) {
+	s := newTestServer(t)
+	s.roles = &RoleConfig{
+		Default:   RoleViewer,
+		Operators: map[string]Role{"ed@example.com": RoleEditor},
+		Groups:    map[string]Role{"airfocus-admins": RoleAdmin},
+	}
+	sessionFor := func(operator *Operator) *http.Cookie {
+		id, err := s.sessions.Create(fake.APIKey, operator)
+		if err != nil {
+			t.Fatalf("failed to create session: %v", err)
+		}
+		return &http.Cookie{Name: sessionCookieName, Value: id}
+	}
+	viewer := sessionFor(&Operator{Email: "vic@example.com", EmailVerified: true})
+	editor := sessionFor(&Operator{Email: "ed@example.com", EmailVerified: true})
+	admin := sessionFor(&Operator{Email: "ada@example.com", EmailVerified: true, Groups: []string{"airfocus-admins"}})
+
+	setPermission := s.requireRole(RoleEditor, s.handleSetPermissionHTMX)
+	offboardPreview := s.requireRole(RoleAdmin, s.handleOffboardPreviewHTMX)
+	permissionForm := url.Values{"target_type": {"workspace"}, "target_id": {"w-roadmap"}, "user_id": {"u-carol"}, "permission": {"write"}, "view": {"workspace"}}
+	offboardForm := url.Values{"user_id": {"u-bob"}}
+
+	tests := []struct {
+		name        string
+		session     *http.Cookie
+		setStatus   int
+		offboard    int
+		writeShown  bool
+		offboardBox bool
+	}{
+		{"viewer", viewer, http.StatusForbidden, http.StatusForbidden, false, false},
+		{"editor", editor, http.StatusOK, http.StatusForbidden, true, false},
+		{"admin", admin, http.StatusOK, http.StatusOK, true, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := postForm(setPermission, permissionForm, tt.session)
+			if rec.Code != tt.setStatus {
+				t.Errorf("set permission: status = %d, want %d", rec.Code, tt.setStatus)
+			}
+			if tt.setStatus == http.StatusForbidden && !strings.Contains(rec.Body.String(), "Your role does not allow this action") {
+				t.Errorf("set permission: body = %q, want the role error", rec.Body.String())
+			}
+			if rec := postForm(offboardPreview, offboardForm, tt.session); rec.Code != tt.offboard {
+				t.Errorf("offboard preview: status = %d, want %d", rec.Code, tt.offboard)
+			}
+
+			body := postForm(s.handleGetUserInfoHTMX, url.Values{"user_select": {"u-bob"}}, tt.session).Body.String()
+			if got := strings.Contains(body, `hx-post="/api/permission/htmx"`); got != tt.writeShown {
+				t.Errorf("user details show permission controls = %v, want %v", got, tt.writeShown)
+			}
+			if got := strings.Contains(body, "Copy Access"); got != tt.writeShown {
+				t.Errorf("user details show copy access = %v, want %v", got, tt.writeShown)
+			}
+			if got := strings.Contains(body, "Offboard User"); got != tt.offboardBox {
+				t.Errorf("user details show offboarding = %v, want %v", got, tt.offboardBox)
+			}
+			body = postForm(s.handleStaleAccessHTMX, url.Values{}, tt.session).Body.String()
+			if got := strings.Contains(body, "Revoke All"); got != tt.offboardBox {
+				t.Errorf("stale access shows cleanup = %v, want %v", got, tt.offboardBox)
+			}
+			body = postForm(s.handleGetWorkspaceUsersHTMX, url.Values{"workspace_select": {"w-roadmap"}}, tt.session).Body.String()
+			if got := strings.Contains(body, `hx-post="/api/permission/htmx"`); got != tt.writeShown {
+				t.Errorf("workspace users show permission controls = %v, want %v", got, tt.writeShown)
+			}
+		})
+	}
+
+	// Personal API key sessions act with the rights of their key
+	if rec := postForm(offboardPreview, offboardForm, login(t, s)); rec.Code != http.StatusOK {
+		t.Errorf("offboard preview with API key session: status = %d, want %d", rec.Code, http.StatusOK)
+	}
+}
+
+func TestRunCommandSnapshots(t *testing.T) {
+	srv := fake.NewServer(fake.DefaultFixtures())
+	t.Cleanup(srv.Close)
+	opts := []airfocus.Option{airfocus.WithBaseURL(srv.URL)}
+	t.Setenv("AIRFOCUS_API_KEY", fake.APIKey)
+	t.Setenv("AIRFOCUS_SNAPSHOT_DIR", t.TempDir())
+	ctx := context.Background()
+
+	var stdout, stderr bytes.Buffer
+	if code := runCommand(ctx, []string{"snapshot", "take"}, &stdout, &stderr, opts...); code != 0 {
+		t.Fatalf("snapshot take: exit code = %d, stderr = %s", code, stderr.String())
+	}
+	if !strings.HasPrefix(stdout.String(), "Stored snapshot ") {
+		t.Errorf("snapshot take printed %q", stdout.String())
+	}
+
+	stdout.Reset()
+	if code := runCommand(ctx, []string{"snapshot", "list", "-format", "json"}, &stdout, &stderr, opts...); code != 0 {
+		t.Fatalf("snapshot list: exit code = %d, stderr = %s", code, stderr.String())
+	}
+	var snapshots []cliSnapshot
+	if err := json.Unmarshal(stdout.Bytes(), &snapshots); err != nil {
+		t.Fatalf("snapshot list output is not JSON: %v", err)
+	}
+	if len(snapshots) != 1 || snapshots[0].Size == 0 {
+		t.Errorf("snapshot list = %+v, want one snapshot", snapshots)
+	}
+
+	stdout.Reset()
+	if code := runCommand(ctx, []string{"snapshot", "diff", snapshots[0].ID, "now", "-format", "csv"}, &stdout, &stderr, opts...); code != 0 {
+		t.Fatalf("snapshot diff: exit code = %d, stderr = %s", code, stderr.String())
+	}
+	if got := stdout.String(); got != "KIND,TYPE,TARGET ID,TARGET,USER ID,USER,EMAIL,FROM,TO\n" {
+		t.Errorf("snapshot diff of an unchanged team printed %q", got)
+	}
+	if code := runCommand(ctx, []string{"snapshot", "diff", snapshots[0].ID, "now", "-permission", "owner"}, &stdout, &stderr, opts...); code != 2 {
+		t.Errorf("snapshot diff with an unknown permission: exit code = %d, want 2", code)
+	}
+}
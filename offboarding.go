@@ -0,0 +1,170 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/csv"
+	"html/template"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/tibuski/goAirfocus/airfocus"
+)
+
+// offboarding is a planned offboarding together with the users involved
+type offboarding struct {
+	client    *airfocus.Client
+	User      airfocus.User
+	Successor *airfocus.User // nil without a successor
+	Plan      airfocus.OffboardingPlan
+	userNames map[string]string
+}
+
+// planOffboarding reads the user and optional successor from the form and
+// plans the offboarding. It writes an error response and returns false on failure.
+func (s *Server) planOffboarding(w http.ResponseWriter, r *http.Request) (offboarding, bool) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return offboarding{}, false
+	}
+
+	userID := r.FormValue("user_id")
+	successorID := r.FormValue("successor_id")
+
+	if userID == "" {
+		http.Error(w, "User is required", http.StatusBadRequest)
+		return offboarding{}, false
+	}
+	if userID == successorID {
+		http.Error(w, "A user cannot be their own successor", http.StatusBadRequest)
+		return offboarding{}, false
+	}
+
+	client, ok := s.sessionClient(w, r)
+	if !ok {
+		return offboarding{}, false
+	}
+	o := offboarding{client: client, userNames: make(map[string]string)}
+	users, err := o.client.ListUsers(r.Context())
+	if err != nil {
+		s.renderError(w, err, "Failed to retrieve users")
+		return offboarding{}, false
+	}
+	for _, user := range users {
+		o.userNames[user.UserID] = user.FullName
+	}
+
+	if o.Plan, err = o.client.PlanOffboarding(r.Context(), userID, successorID); err != nil {
+		s.renderError(w, err, "Failed to plan offboarding")
+		return offboarding{}, false
+	}
+	if o.User, err = o.client.GetUser(r.Context(), userID); err != nil {
+		s.renderError(w, err, "Failed to retrieve user")
+		return offboarding{}, false
+	}
+	if successorID != "" {
+		successor, err := o.client.GetUser(r.Context(), successorID)
+		if err != nil {
+			s.renderError(w, err, "Failed to retrieve successor")
+			return offboarding{}, false
+		}
+		o.Successor = &successor
+	}
+	return o, true
+}
+
+// handleOffboardPreviewHTMX shows every explicit permission offboarding a
+// user would revoke and the ownership handed to the successor, without
+// changing anything.
+func (s *Server) handleOffboardPreviewHTMX(w http.ResponseWriter, r *http.Request) {
+	o, ok := s.planOffboarding(w, r)
+	if !ok {
+		return
+	}
+
+	rows := make([]accessChangeRow, len(o.Plan.Changes))
+	for i, change := range o.Plan.Changes {
+		rows[i] = accessChangeRow{AccessChange: change, UserName: o.userNames[change.UserID]}
+	}
+	s.renderOffboarding(w, o, rows, false)
+}
+
+// handleOffboardApplyHTMX revokes all explicit access of a user, handing
+// "full" to the successor first, and offers a report of what was changed.
+// The plan is computed again and only applied if it is the one previewed,
+// so access changed since the preview is never revoked unseen.
+func (s *Server) handleOffboardApplyHTMX(w http.ResponseWriter, r *http.Request) {
+	o, ok := s.planOffboarding(w, r)
+	if !ok {
+		return
+	}
+	if r.FormValue("plan_digest") != planDigest(o.Plan) {
+		s.renderError(w, errPlanChanged, "Offboarding plan changed")
+		return
+	}
+
+	log.Printf("%s offboarding user %s (successor %q): %d changes", s.actor(r), o.User.UserID, o.Plan.SuccessorID, len(o.Plan.Changes))
+
+	results := o.client.ApplyAccessChanges(r.Context(), o.Plan.Changes)
+	rows := make([]accessChangeRow, len(results))
+	for i, result := range results {
+		rows[i] = resultRow(result, o.userNames[result.UserID])
+	}
+	s.renderOffboarding(w, o, rows, true)
+}
+
+// renderOffboarding renders the offboarding preview or results
+func (s *Server) renderOffboarding(w http.ResponseWriter, o offboarding, rows []accessChangeRow, applied bool) {
+	data := map[string]interface{}{
+		"User":      o.User,
+		"Successor": o.Successor,
+		"Orphaned":  o.Plan.Orphaned,
+		"Rows":      rows,
+		"Applied":   applied,
+		"Failed":    countFailed(rows),
+		"ShowUser":  true,
+		"Digest":    planDigest(o.Plan),
+	}
+	if applied {
+		report, err := offboardingReport(rows)
+		if err != nil {
+			log.Printf("Error building offboarding report: %v", err)
+		} else {
+			data["ReportURL"] = template.URL("data:text/csv;base64," + base64.StdEncoding.EncodeToString(report))
+			data["ReportName"] = "offboarding-" + o.User.UserID + "-" + time.Now().Format("20060102-150405") + ".csv"
+		}
+	}
+
+	if err := s.templates.ExecuteTemplate(w, "offboarding_partial.html", data); err != nil {
+		log.Printf("Error executing template: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	}
+}
+
+// offboardingReport writes the applied changes as CSV, one row per change
+func offboardingReport(rows []accessChangeRow) ([]byte, error) {
+	var buf bytes.Buffer
+	cw := csv.NewWriter(&buf)
+	cw.Write([]string{"user_id", "user_name", "target_type", "target_id", "target_name", "previous_permission", "new_permission", "result"})
+	for _, row := range rows {
+		result := "applied"
+		if row.Skipped {
+			result = "skipped: " + row.Error
+		} else if row.Error != "" {
+			result = "failed: " + row.Error
+		}
+		cw.Write([]string{
+			row.UserID,
+			row.UserName,
+			row.TargetType,
+			row.TargetID,
+			row.TargetName,
+			string(row.From),
+			string(row.To),
+			result,
+		})
+	}
+	cw.Flush()
+	return buf.Bytes(), cw.Error()
+}
@@ -0,0 +1,152 @@
+package oidc_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/tibuski/goAirfocus/oidc"
+	"github.com/tibuski/goAirfocus/oidc/fake"
+)
+
+const redirectURL = "http://app.test/auth/callback"
+
+var alice = fake.User{Subject: "u-alice", Email: "alice@example.com", EmailVerified: true, Name: "Alice", Groups: []string{"admins"}}
+
+func discover(t *testing.T, srv *fake.Server, clientID string) *oidc.Provider {
+	t.Helper()
+	provider, err := oidc.Discover(context.Background(), oidc.Config{
+		Issuer:       srv.URL,
+		ClientID:     clientID,
+		ClientSecret: fake.ClientSecret,
+		RedirectURL:  redirectURL,
+		Scopes:       []string{"openid", "email", "profile"},
+	})
+	if err != nil {
+		t.Fatalf("Discover: %v", err)
+	}
+	return provider
+}
+
+// authorize follows the sign-in at the fake provider and returns the code it redirects back with
+func authorize(t *testing.T, provider *oidc.Provider, state, nonce, verifier string) string {
+	t.Helper()
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	resp, err := client.Get(provider.AuthCodeURL(state, nonce, verifier, ""))
+	if err != nil {
+		t.Fatalf("authorize: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusFound {
+		t.Fatalf("authorize status = %d, want 302", resp.StatusCode)
+	}
+	location, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil {
+		t.Fatalf("parse redirect: %v", err)
+	}
+	if got := location.Query().Get("state"); got != state {
+		t.Errorf("state = %q, want %q", got, state)
+	}
+	return location.Query().Get("code")
+}
+
+func TestLoginFlow(t *testing.T) {
+	srv := fake.NewServer(alice)
+	t.Cleanup(srv.Close)
+	provider := discover(t, srv, fake.ClientID)
+	ctx := context.Background()
+
+	code := authorize(t, provider, "state-1", "nonce-1", "verifier-1")
+	raw, err := provider.Exchange(ctx, code, "verifier-1")
+	if err != nil {
+		t.Fatalf("Exchange: %v", err)
+	}
+	claims, err := provider.Verify(ctx, raw, "nonce-1")
+	if err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	if claims.Subject != "u-alice" || claims.Email != "alice@example.com" || claims.Name != "Alice" {
+		t.Errorf("claims = %+v, want alice", claims)
+	}
+	if claims.EmailVerified == nil || !*claims.EmailVerified {
+		t.Errorf("email_verified = %v, want true", claims.EmailVerified)
+	}
+	if len(claims.Groups) != 1 || claims.Groups[0] != "admins" {
+		t.Errorf("groups = %v, want [admins]", claims.Groups)
+	}
+
+	// Codes are single use
+	if _, err := provider.Exchange(ctx, code, "verifier-1"); err == nil {
+		t.Error("second Exchange of the same code succeeded")
+	}
+}
+
+func TestExchangeRequiresVerifier(t *testing.T) {
+	srv := fake.NewServer(alice)
+	t.Cleanup(srv.Close)
+	provider := discover(t, srv, fake.ClientID)
+
+	code := authorize(t, provider, "state", "nonce", "verifier")
+	if _, err := provider.Exchange(context.Background(), code, "another-verifier"); err == nil {
+		t.Error("Exchange with the wrong PKCE verifier succeeded")
+	}
+}
+
+func TestVerifyRejects(t *testing.T) {
+	tests := []struct {
+		name     string
+		clientID string        // Client the verifying provider is configured with
+		lifetime time.Duration // Token lifetime at the fake provider
+		nonce    string        // Nonce expected when verifying
+		tamper   bool          // Whether the signature is altered
+	}{
+		{name: "wrong nonce", nonce: "other-nonce"},
+		{name: "expired", lifetime: -time.Hour},
+		{name: "wrong audience", clientID: "someone-else"},
+		{name: "bad signature", tamper: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := fake.NewServer(alice)
+			t.Cleanup(srv.Close)
+			if tt.lifetime != 0 {
+				srv.SetTokenLifetime(tt.lifetime)
+			}
+			provider := discover(t, srv, fake.ClientID)
+			ctx := context.Background()
+
+			raw, err := provider.Exchange(ctx, authorize(t, provider, "state", "nonce", "verifier"), "verifier")
+			if err != nil {
+				t.Fatalf("Exchange: %v", err)
+			}
+			if tt.tamper {
+				raw = raw[:len(raw)-4] + "AAAA"
+			}
+			if tt.clientID != "" {
+				provider = discover(t, srv, tt.clientID)
+			}
+			nonce := "nonce"
+			if tt.nonce != "" {
+				nonce = tt.nonce
+			}
+
+			if _, err := provider.Verify(ctx, raw, nonce); !errors.Is(err, oidc.ErrInvalidToken) {
+				t.Errorf("Verify error = %v, want ErrInvalidToken", err)
+			}
+		})
+	}
+}
+
+func TestDiscoverChecksIssuer(t *testing.T) {
+	srv := fake.NewServer(alice)
+	t.Cleanup(srv.Close)
+
+	_, err := oidc.Discover(context.Background(), oidc.Config{Issuer: srv.URL + "/other", ClientID: fake.ClientID})
+	if err == nil {
+		t.Error("Discover succeeded for a mismatched issuer")
+	}
+}
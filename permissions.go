@@ -0,0 +1,91 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/tibuski/goAirfocus/airfocus"
+)
+
+// permissionControl is the data rendered by the permission_select template:
+// an inline dropdown changing one user's explicit permission on a workspace
+// or workspace group.
+type permissionControl struct {
+	TargetType string // "workspace" or "group"
+	TargetID   string
+	TargetName string
+	UserID     string
+	UserName   string
+	Current    string // Explicit permission, empty if the user has none
+	View       string // Panel re-rendered after a change: "user" or "workspace"
+}
+
+// newPermissionControl is exposed to templates as permissionControl
+func newPermissionControl(targetType, targetID, targetName, userID, userName, current, view string) permissionControl {
+	return permissionControl{
+		TargetType: targetType,
+		TargetID:   targetID,
+		TargetName: targetName,
+		UserID:     userID,
+		UserName:   userName,
+		Current:    current,
+		View:       view,
+	}
+}
+
+// handleSetPermissionHTMX grants, changes or revokes a user's explicit
+// permission on a workspace or workspace group, then re-renders the panel
+// the change was made from. An empty permission revokes the grant.
+func (s *Server) handleSetPermissionHTMX(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	targetType := r.FormValue("target_type")
+	targetID := r.FormValue("target_id")
+	userID := r.FormValue("user_id")
+	permission := airfocus.Permission(r.FormValue("permission"))
+	view := r.FormValue("view")
+
+	if targetID == "" || userID == "" {
+		http.Error(w, "Target and user are required", http.StatusBadRequest)
+		return
+	}
+
+	log.Printf("Permission change by %s - %s %s, user %s: %q", s.actor(r), targetType, targetID, userID, permission)
+
+	client, ok := s.sessionClient(w, r)
+	if !ok {
+		return
+	}
+	var err error
+	switch {
+	case targetType == "workspace" && permission == "":
+		err = client.RemoveWorkspacePermission(r.Context(), targetID, userID)
+	case targetType == "workspace":
+		err = client.SetWorkspacePermission(r.Context(), targetID, userID, permission)
+	case targetType == "group" && permission == "":
+		err = client.RemoveGroupPermission(r.Context(), targetID, userID)
+	case targetType == "group":
+		err = client.SetGroupPermission(r.Context(), targetID, userID, permission)
+	default:
+		http.Error(w, fmt.Sprintf("Unknown target type %q", targetType), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		s.renderError(w, err, "Failed to update permission")
+		return
+	}
+
+	// Re-render the panel the change came from with the updated permissions
+	switch view {
+	case "workspace":
+		r.Form.Set("workspace_select", targetID)
+		s.handleGetWorkspaceUsersHTMX(w, r)
+	default:
+		r.Form.Set("user_select", userID)
+		s.handleGetUserInfoHTMX(w, r)
+	}
+}
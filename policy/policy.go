@@ -0,0 +1,358 @@
+// Package policy checks the access of an Airfocus team against rules
+// declared in YAML, such as "no contributor has full permission on any
+// workspace" or "every workspace has at least two owners".
+//
+//	rules:
+//	  - name: Contributors never have full access
+//	    check: max-permission
+//	    role: contributor
+//	    permission: write
+//	  - name: Finance workspaces are private
+//	    check: max-default-permission
+//	    group: Finance
+//	    permission: none
+package policy
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/tibuski/goAirfocus/airfocus"
+	"gopkg.in/yaml.v3"
+)
+
+// Checks a rule can make
+const (
+	// CheckMaxPermission: the selected users hold at most Permission, effectively, on every workspace in scope
+	CheckMaxPermission = "max-permission"
+	// CheckMinHolders: every workspace in scope has at least Count active users holding Permission or higher
+	CheckMinHolders = "min-holders"
+	// CheckNoPermissions: the selected users hold no explicit workspace or group permissions
+	CheckNoPermissions = "no-permissions"
+	// CheckMaxDefaultPermission: every workspace in scope gives all team members at most Permission by default
+	CheckMaxDefaultPermission = "max-default-permission"
+)
+
+// User states a rule can select with Users
+const (
+	UsersDisabled = "disabled" // Disabled users
+	UsersPending  = "pending"  // Users who have not accepted their invitation
+	UsersUnseated = "unseated" // Users without a seat
+	UsersUnknown  = "unknown"  // Permission entries whose user no longer exists
+)
+
+// Rule is one access rule
+type Rule struct {
+	Name       string              `yaml:"name"`
+	Check      string              `yaml:"check"`      // One of the Check constants
+	Role       string              `yaml:"role"`       // Selects users by Airfocus role, e.g. contributor
+	Users      string              `yaml:"users"`      // Selects users by state: one of the Users constants
+	Permission airfocus.Permission `yaml:"permission"` // Level the check compares with; none is allowed for maximums
+	Count      int                 `yaml:"count"`      // Minimum number of holders for min-holders
+	Group      string              `yaml:"group"`      // Limits the rule to workspaces in this group or its subgroups
+	Workspaces []string            `yaml:"workspaces"` // Limits the rule to the workspaces with these names
+}
+
+// Policy is a set of rules
+type Policy struct {
+	Rules []Rule `yaml:"rules"`
+}
+
+// Violation is one place where the team breaks a rule
+type Violation struct {
+	Rule     string `json:"rule"`
+	TargetID string `json:"targetId,omitempty"` // ID of the workspace or group
+	Target   string `json:"target,omitempty"`   // Name of the workspace or group
+	UserID   string `json:"userId,omitempty"`
+	User     string `json:"user,omitempty"`
+	Message  string `json:"message"`
+}
+
+// Load reads and validates a policy file
+func Load(path string) (*Policy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read policy: %w", err)
+	}
+	p, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("policy %s: %w", path, err)
+	}
+	return p, nil
+}
+
+// Parse reads and validates a policy. Unknown keys are rejected, so a
+// misspelt rule does not silently check nothing, and so are rule names used
+// twice, as violations are reported by rule name.
+func Parse(data []byte) (*Policy, error) {
+	var p Policy
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&p); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("failed to parse: %w", err)
+	}
+	if len(p.Rules) == 0 {
+		return nil, errors.New("no rules")
+	}
+	names := make(map[string]bool, len(p.Rules))
+	for i, rule := range p.Rules {
+		if err := rule.validate(); err != nil {
+			return nil, fmt.Errorf("rule %d (%s): %w", i+1, rule.Name, err)
+		}
+		if names[strings.ToLower(rule.Name)] {
+			return nil, fmt.Errorf("rule %d (%s): name is already used by another rule", i+1, rule.Name)
+		}
+		names[strings.ToLower(rule.Name)] = true
+	}
+	return &p, nil
+}
+
+// validate checks that the rule has what its check needs
+func (r Rule) validate() error {
+	if r.Name == "" {
+		return errors.New("name is required")
+	}
+	switch r.Users {
+	case "", UsersDisabled, UsersPending, UsersUnseated, UsersUnknown:
+	default:
+		return fmt.Errorf("unknown users %q (use disabled, pending, unseated or unknown)", r.Users)
+	}
+	permissionValid := r.Permission.Valid() || r.Permission == airfocus.PermissionNone
+
+	switch r.Check {
+	case CheckMaxPermission:
+		if r.Role == "" && r.Users == "" {
+			return errors.New("select users with role or users")
+		}
+		if !permissionValid {
+			return fmt.Errorf("invalid permission %q", r.Permission)
+		}
+	case CheckMinHolders:
+		if r.Count < 1 {
+			return errors.New("count must be at least 1")
+		}
+		if !r.Permission.Valid() {
+			return fmt.Errorf("invalid permission %q", r.Permission)
+		}
+	case CheckNoPermissions:
+		if r.Role == "" && r.Users == "" {
+			return errors.New("select users with role or users")
+		}
+	case CheckMaxDefaultPermission:
+		if !permissionValid {
+			return fmt.Errorf("invalid permission %q", r.Permission)
+		}
+	default:
+		return fmt.Errorf("unknown check %q", r.Check)
+	}
+	return nil
+}
+
+// Check evaluates the policy against the team the client belongs to
+func (p *Policy) Check(ctx context.Context, client *airfocus.Client) ([]Violation, error) {
+	users, err := client.ListUsers(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list users: %w", err)
+	}
+	workspaces, err := client.ListWorkspaces(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list workspaces: %w", err)
+	}
+	groups, err := client.ListWorkspaceGroups(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list workspace groups: %w", err)
+	}
+	return p.Evaluate(users, workspaces, groups), nil
+}
+
+// Evaluate returns the violations of every rule, in rule order and then by
+// target and user name
+func (p *Policy) Evaluate(users []airfocus.User, workspaces []airfocus.Workspace, groups []airfocus.WorkspaceGroup) []Violation {
+	t := newTeam(users, workspaces, groups)
+	var violations []Violation
+	for _, rule := range p.Rules {
+		found := t.evaluate(rule)
+		sort.SliceStable(found, func(i, j int) bool {
+			if !strings.EqualFold(found[i].Target, found[j].Target) {
+				return strings.ToLower(found[i].Target) < strings.ToLower(found[j].Target)
+			}
+			return strings.ToLower(found[i].User) < strings.ToLower(found[j].User)
+		})
+		violations = append(violations, found...)
+	}
+	return violations
+}
+
+// team indexes the data rules are evaluated against
+type team struct {
+	users      []airfocus.User
+	usersByID  map[string]airfocus.User
+	workspaces []airfocus.Workspace
+	groups     []airfocus.WorkspaceGroup
+	groupsByID map[string]airfocus.WorkspaceGroup
+}
+
+func newTeam(users []airfocus.User, workspaces []airfocus.Workspace, groups []airfocus.WorkspaceGroup) *team {
+	t := &team{
+		users:      users,
+		usersByID:  make(map[string]airfocus.User, len(users)),
+		workspaces: workspaces,
+		groups:     groups,
+		groupsByID: make(map[string]airfocus.WorkspaceGroup, len(groups)),
+	}
+	for _, user := range users {
+		t.usersByID[user.UserID] = user
+	}
+	for _, group := range groups {
+		t.groupsByID[group.ID] = group
+	}
+	return t
+}
+
+// evaluate returns the violations of one rule. Group grants are reported
+// with the group as the target.
+func (t *team) evaluate(rule Rule) []Violation {
+	var violations []Violation
+	violation := func(ws airfocus.Workspace, userID, userName, message string) {
+		violations = append(violations, Violation{Rule: rule.Name, TargetID: ws.ID, Target: ws.Name, UserID: userID, User: userName, Message: message})
+	}
+
+	switch rule.Check {
+	case CheckMaxPermission:
+		for _, ws := range t.inScope(rule) {
+			for _, user := range t.users {
+				if !selects(rule, user) {
+					continue
+				}
+				if permission, _ := airfocus.EffectivePermission(user.UserID, ws, t.groups); !rule.Permission.AtLeast(permission) {
+					violation(ws, user.UserID, user.FullName, fmt.Sprintf("%s has %s on %s; at most %s is allowed", user.FullName, permission, ws.Name, rule.Permission))
+				}
+			}
+			if rule.Users == UsersUnknown && rule.Role == "" {
+				for userID, permission := range ws.Embedded.Permissions {
+					if _, known := t.usersByID[userID]; !known && !rule.Permission.AtLeast(airfocus.Permission(permission)) {
+						violation(ws, userID, "Unknown User", fmt.Sprintf("Unknown user %s has %s on %s; at most %s is allowed", userID, permission, ws.Name, rule.Permission))
+					}
+				}
+			}
+		}
+
+	case CheckMinHolders:
+		for _, ws := range t.inScope(rule) {
+			holders := 0
+			for _, user := range t.users {
+				if airfocus.StaleCategory(user) != "" {
+					continue // Disabled, pending and unseated users cannot use their access
+				}
+				if permission, _ := airfocus.EffectivePermission(user.UserID, ws, t.groups); permission.AtLeast(rule.Permission) {
+					holders++
+				}
+			}
+			if holders < rule.Count {
+				violation(ws, "", "", fmt.Sprintf("%s has %d users with %s; at least %d are required", ws.Name, holders, rule.Permission, rule.Count))
+			}
+		}
+
+	case CheckNoPermissions:
+		held := func(target airfocus.Workspace, kind string, permissions map[string]string) {
+			for userID, permission := range permissions {
+				user, known := t.usersByID[userID]
+				name := user.FullName
+				if !known {
+					name = "Unknown User"
+				}
+				if (known && selects(rule, user)) || (!known && rule.Users == UsersUnknown && rule.Role == "") {
+					violation(target, userID, name, fmt.Sprintf("%s holds %s on %s %s", name, permission, kind, target.Name))
+				}
+			}
+		}
+		for _, ws := range t.inScope(rule) {
+			held(ws, "workspace", ws.Embedded.Permissions)
+		}
+		if rule.Group == "" && len(rule.Workspaces) == 0 {
+			for _, group := range t.groups {
+				held(airfocus.Workspace{ID: group.ID, Name: group.Name}, "group", group.Embedded.Permissions)
+			}
+		}
+
+	case CheckMaxDefaultPermission:
+		for _, ws := range t.inScope(rule) {
+			if permission, trace := airfocus.EffectiveDefaultPermission(ws, t.groups); !rule.Permission.AtLeast(permission) {
+				source := ""
+				for _, s := range trace {
+					if s.Winning {
+						source = fmt.Sprintf(" (from %s %s)", s.Kind, s.Name)
+					}
+				}
+				violation(ws, "", "", fmt.Sprintf("Every team member has %s on %s by default%s; at most %s is allowed", permission, ws.Name, source, rule.Permission))
+			}
+		}
+	}
+	return violations
+}
+
+// selects reports whether the rule's role and users selectors both match the user
+func selects(rule Rule, user airfocus.User) bool {
+	if rule.Role != "" && !strings.EqualFold(user.Role, rule.Role) {
+		return false
+	}
+	switch rule.Users {
+	case UsersDisabled:
+		return user.Disabled
+	case UsersPending:
+		return user.State != nil && user.State.Pending
+	case UsersUnseated:
+		return user.State != nil && user.State.Unseated
+	case UsersUnknown:
+		return false // Known users never match
+	}
+	return true
+}
+
+// inScope returns the workspaces the rule applies to
+func (t *team) inScope(rule Rule) []airfocus.Workspace {
+	var scoped []airfocus.Workspace
+	for _, ws := range t.workspaces {
+		if rule.Group != "" && !t.inGroup(ws.GroupID, rule.Group) {
+			continue
+		}
+		if len(rule.Workspaces) > 0 && !containsFold(rule.Workspaces, ws.Name) {
+			continue
+		}
+		scoped = append(scoped, ws)
+	}
+	return scoped
+}
+
+// inGroup reports whether the group with groupID, or one of its ancestors, is named name
+func (t *team) inGroup(groupID, name string) bool {
+	visited := make(map[string]bool)
+	for groupID != "" && !visited[groupID] {
+		visited[groupID] = true
+		group, ok := t.groupsByID[groupID]
+		if !ok {
+			return false
+		}
+		if strings.EqualFold(group.Name, name) {
+			return true
+		}
+		groupID = group.ParentID
+	}
+	return false
+}
+
+// containsFold reports whether list contains s, ignoring case
+func containsFold(list []string, s string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, s) {
+			return true
+		}
+	}
+	return false
+}
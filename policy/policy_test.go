@@ -0,0 +1,150 @@
+package policy
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/tibuski/goAirfocus/airfocus"
+	"github.com/tibuski/goAirfocus/airfocus/fake"
+)
+
+// testTeam returns users, workspaces and groups covering every check
+func testTeam() ([]airfocus.User, []airfocus.Workspace, []airfocus.WorkspaceGroup) {
+	users := []airfocus.User{
+		{UserID: "u-alice", FullName: "Alice", Role: "admin"},
+		{UserID: "u-bob", FullName: "Bob", Role: "editor"},
+		{UserID: "u-carol", FullName: "Carol", Role: "contributor"},
+		{UserID: "u-dave", FullName: "Dave", Role: "editor", Disabled: true},
+		{UserID: "u-erin", FullName: "Erin", Role: "contributor", State: &airfocus.UserState{Pending: true}},
+		{UserID: "u-frank", FullName: "Frank", Role: "editor", State: &airfocus.UserState{Unseated: true}},
+	}
+	groups := []airfocus.WorkspaceGroup{
+		{ID: "g-finance", Name: "Finance", DefaultPermission: "read"},
+		{ID: "g-payroll", Name: "Payroll", ParentID: "g-finance"},
+		{ID: "g-product", Name: "Product"},
+	}
+	groups[2].Embedded.Permissions = map[string]string{"u-dave": "write"}
+	workspaces := []airfocus.Workspace{
+		{ID: "w-budget", Name: "Budget", GroupID: "g-finance"},
+		{ID: "w-salaries", Name: "Salaries", GroupID: "g-payroll", DefaultPermission: "none"},
+		{ID: "w-roadmap", Name: "Roadmap", GroupID: "g-product"},
+	}
+	workspaces[0].Embedded.Permissions = map[string]string{"u-alice": "full", "u-bob": "full"}
+	workspaces[1].Embedded.Permissions = map[string]string{"u-alice": "full", "u-dave": "full"}
+	workspaces[2].Embedded.Permissions = map[string]string{"u-alice": "full", "u-carol": "full", "u-erin": "read", "u-frank": "full", "u-ghost": "write"}
+	return users, workspaces, groups
+}
+
+func TestEvaluate(t *testing.T) {
+	users, workspaces, groups := testTeam()
+	tests := []struct {
+		name string
+		rule string
+		want []string // Messages of the expected violations, in order
+	}{
+		{
+			"contributors never have full",
+			"check: max-permission\n    role: contributor\n    permission: write",
+			[]string{"Carol has full on Roadmap; at most write is allowed"},
+		},
+		{
+			"two owners, disabled users do not count",
+			"check: min-holders\n    permission: full\n    count: 2",
+			[]string{"Salaries has 1 users with full; at least 2 are required"},
+		},
+		{
+			"pending and unseated users do not count",
+			"check: min-holders\n    permission: read\n    count: 3\n    workspaces: [Roadmap]",
+			[]string{"Roadmap has 2 users with read; at least 3 are required"},
+		},
+		{
+			"disabled users hold nothing",
+			"check: no-permissions\n    users: disabled",
+			[]string{"Dave holds write on group Product", "Dave holds full on workspace Salaries"},
+		},
+		{
+			"unknown users hold nothing",
+			"check: no-permissions\n    users: unknown",
+			[]string{"Unknown User holds write on workspace Roadmap"},
+		},
+		{
+			"pending users have no access, including by default",
+			"check: max-permission\n    users: pending\n    permission: none",
+			[]string{
+				"Erin has read on Budget; at most none is allowed",
+				"Erin has read on Roadmap; at most none is allowed",
+				"Erin has read on Salaries; at most none is allowed",
+			},
+		},
+		{
+			"finance is private, including subgroups",
+			"check: max-default-permission\n    group: finance\n    permission: none",
+			[]string{
+				"Every team member has read on Budget by default (from group default Finance); at most none is allowed",
+				"Every team member has read on Salaries by default (from group default Finance); at most none is allowed",
+			},
+		},
+		{
+			"scoped to named workspaces",
+			"check: min-holders\n    permission: full\n    count: 3\n    workspaces: [Budget]",
+			[]string{"Budget has 2 users with full; at least 3 are required"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := Parse([]byte("rules:\n  - name: " + tt.name + "\n    " + tt.rule + "\n"))
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			var got []string
+			for _, v := range p.Evaluate(users, workspaces, groups) {
+				if v.Rule != tt.name {
+					t.Errorf("violation of rule %q, want %q", v.Rule, tt.name)
+				}
+				got = append(got, v.Message)
+			}
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("violations:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := map[string]string{
+		"empty":              "",
+		"unknown key":        "rules:\n  - name: x\n    check: min-holders\n    permission: full\n    count: 2\n    owners: 2\n",
+		"no name":            "rules:\n  - check: min-holders\n    permission: full\n    count: 2\n",
+		"unknown check":      "rules:\n  - name: x\n    check: everything-fine\n",
+		"no selector":        "rules:\n  - name: x\n    check: max-permission\n    permission: write\n",
+		"bad permission":     "rules:\n  - name: x\n    check: max-permission\n    role: contributor\n    permission: owner\n",
+		"no count":           "rules:\n  - name: x\n    check: min-holders\n    permission: full\n",
+		"min none":           "rules:\n  - name: x\n    check: min-holders\n    permission: none\n    count: 1\n",
+		"unknown user state": "rules:\n  - name: x\n    check: no-permissions\n    users: deleted\n",
+		"duplicate name":     "rules:\n  - name: Owners\n    check: min-holders\n    permission: full\n    count: 2\n  - name: owners\n    check: no-permissions\n    users: disabled\n",
+	}
+	for name, data := range tests {
+		if _, err := Parse([]byte(data)); err == nil {
+			t.Errorf("%s: Parse succeeded", name)
+		}
+	}
+}
+
+func TestCheck(t *testing.T) {
+	srv := fake.NewServer(fake.DefaultFixtures())
+	t.Cleanup(srv.Close)
+	client := airfocus.NewClient(fake.APIKey, airfocus.WithBaseURL(srv.URL))
+
+	p, err := Parse([]byte("rules:\n  - name: Unknown users hold nothing\n    check: no-permissions\n    users: unknown\n"))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	violations, err := p.Check(context.Background(), client)
+	if err != nil {
+		t.Fatalf("Check: %v", err)
+	}
+	if len(violations) != 1 || violations[0].UserID != "u-ghost" || violations[0].TargetID != "w-budget" {
+		t.Errorf("violations = %+v, want the unknown user on Budget", violations)
+	}
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"sync"
+	"time"
+
+	"github.com/tibuski/goAirfocus/airfocus"
+)
+
+const (
+	defaultClientIdleTTL    = 30 * time.Minute // How long an unused client is kept around
+	defaultMaxCachedClients = 64               // Upper bound on the number of cached clients
+)
+
+// ClientRegistry keeps one airfocus.Client per API key so that the client's
+// cache survives between HTTP requests. Entries are keyed by a hash of the
+// API key, evicted after being idle for idleTTL, and bounded to maxEntries
+// by evicting the least recently used client.
+type ClientRegistry struct {
+	mu         sync.Mutex
+	entries    map[string]*registryEntry
+	idleTTL    time.Duration
+	maxEntries int
+	options    []airfocus.Option            // Options applied to every client created by the registry
+	keyOptions map[string][]airfocus.Option // Further options for the clients of single keys, by key hash
+	now        func() time.Time             // Clock, overridable for eviction logic
+}
+
+// registryEntry is a cached client together with its last access time
+type registryEntry struct {
+	client   *airfocus.Client
+	lastUsed time.Time
+}
+
+// NewClientRegistry creates a registry that evicts clients idle for longer
+// than idleTTL and never holds more than maxEntries clients. The given
+// options are passed to every client the registry creates.
+func NewClientRegistry(idleTTL time.Duration, maxEntries int, opts ...airfocus.Option) *ClientRegistry {
+	if idleTTL <= 0 {
+		idleTTL = defaultClientIdleTTL
+	}
+	if maxEntries <= 0 {
+		maxEntries = defaultMaxCachedClients
+	}
+	return &ClientRegistry{
+		entries:    make(map[string]*registryEntry),
+		idleTTL:    idleTTL,
+		maxEntries: maxEntries,
+		options:    opts,
+		keyOptions: make(map[string][]airfocus.Option),
+		now:        time.Now,
+	}
+}
+
+// Get returns the shared client for the given API key, creating it if needed
+func (r *ClientRegistry) Get(apiKey string) *airfocus.Client {
+	key := hashAPIKey(apiKey)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := r.now()
+	if entry, ok := r.entries[key]; ok {
+		entry.lastUsed = now
+		return entry.client
+	}
+	return r.addLocked(key, r.newClientLocked(apiKey), now)
+}
+
+// Verify returns the shared client for the given API key. A key the registry
+// holds no client for is first checked against the API with a client of its
+// own, which is only added once the key is accepted, so keys the API rejects
+// never take a place in the registry or evict the clients of valid keys.
+func (r *ClientRegistry) Verify(ctx context.Context, apiKey string) (*airfocus.Client, error) {
+	key := hashAPIKey(apiKey)
+
+	r.mu.Lock()
+	if entry, ok := r.entries[key]; ok {
+		entry.lastUsed = r.now()
+		r.mu.Unlock()
+		return entry.client, nil
+	}
+	client := r.newClientLocked(apiKey)
+	r.mu.Unlock()
+
+	if _, err := client.GetTeam(ctx); err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := r.now()
+	if entry, ok := r.entries[key]; ok {
+		// Another request verified the key in the meantime
+		entry.lastUsed = now
+		return entry.client, nil
+	}
+	return r.addLocked(key, client, now), nil
+}
+
+// SetKeyOptions adds options that only apply to the client of apiKey, such
+// as a fallback to snapshots taken with that key. Call it before the key's
+// client is created.
+func (r *ClientRegistry) SetKeyOptions(apiKey string, opts ...airfocus.Option) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	key := hashAPIKey(apiKey)
+	r.keyOptions[key] = append(r.keyOptions[key], opts...)
+}
+
+// Remove drops the client of the API key with the given hash, if any, so
+// the key it holds is no longer kept in memory
+func (r *ClientRegistry) Remove(keyHash string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.entries, keyHash)
+}
+
+// Len returns the number of clients currently held by the registry
+func (r *ClientRegistry) Len() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.entries)
+}
+
+// EvictIdle drops all clients that have not been used within the idle TTL
+func (r *ClientRegistry) EvictIdle() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.evictIdleLocked(r.now())
+}
+
+// StartJanitor periodically evicts idle clients until ctx is cancelled
+func (r *ClientRegistry) StartJanitor(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				r.EvictIdle()
+			}
+		}
+	}()
+}
+
+// newClientLocked creates a client for apiKey with the registry's options
+// and those set for the key; the caller must hold r.mu
+func (r *ClientRegistry) newClientLocked(apiKey string) *airfocus.Client {
+	opts := append(append([]airfocus.Option(nil), r.options...), r.keyOptions[hashAPIKey(apiKey)]...)
+	return airfocus.NewClient(apiKey, opts...)
+}
+
+// addLocked stores client under key, making room first; the caller must hold r.mu
+func (r *ClientRegistry) addLocked(key string, client *airfocus.Client, now time.Time) *airfocus.Client {
+	r.evictIdleLocked(now)
+	for len(r.entries) >= r.maxEntries {
+		r.evictOldestLocked()
+	}
+	r.entries[key] = &registryEntry{client: client, lastUsed: now}
+	return client
+}
+
+// evictIdleLocked removes expired entries; the caller must hold r.mu
+func (r *ClientRegistry) evictIdleLocked(now time.Time) {
+	for key, entry := range r.entries {
+		if now.Sub(entry.lastUsed) > r.idleTTL {
+			delete(r.entries, key)
+		}
+	}
+}
+
+// evictOldestLocked removes the least recently used entry; the caller must hold r.mu
+func (r *ClientRegistry) evictOldestLocked() {
+	var oldestKey string
+	var oldest time.Time
+	for key, entry := range r.entries {
+		if oldestKey == "" || entry.lastUsed.Before(oldest) {
+			oldestKey = key
+			oldest = entry.lastUsed
+		}
+	}
+	if oldestKey != "" {
+		delete(r.entries, oldestKey)
+	}
+}
+
+// hashAPIKey derives the registry key so raw API keys are never used as map keys
+func hashAPIKey(apiKey string) string {
+	sum := sha256.Sum256([]byte(apiKey))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/tibuski/goAirfocus/airfocus"
+	"github.com/tibuski/goAirfocus/airfocus/fake"
+)
+
+// newTestRegistry returns a registry whose clock is controlled by the returned function
+func newTestRegistry(idleTTL time.Duration, maxEntries int) (*ClientRegistry, func(time.Duration)) {
+	r := NewClientRegistry(idleTTL, maxEntries)
+	now := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+	r.now = func() time.Time { return now }
+	return r, func(d time.Duration) { now = now.Add(d) }
+}
+
+func TestClientRegistrySharesClientsPerKey(t *testing.T) {
+	r, _ := newTestRegistry(time.Minute, 10)
+
+	first := r.Get("key-a")
+	if r.Get("key-a") != first {
+		t.Error("the same key got a different client")
+	}
+	if r.Get("key-b") == first {
+		t.Error("a different key got the same client")
+	}
+	if r.Len() != 2 {
+		t.Errorf("Len = %d, want 2", r.Len())
+	}
+	for key := range r.entries {
+		if key == "key-a" || key == "key-b" {
+			t.Errorf("registry is keyed by the raw API key %q", key)
+		}
+	}
+}
+
+func TestClientRegistryEvictsIdleClients(t *testing.T) {
+	r, advance := newTestRegistry(time.Minute, 10)
+
+	first := r.Get("key-a")
+	r.Get("key-b")
+
+	// Using a client keeps it alive
+	advance(50 * time.Second)
+	if r.Get("key-a") != first {
+		t.Error("client was replaced while in use")
+	}
+	advance(30 * time.Second)
+	r.EvictIdle()
+	if r.Len() != 1 {
+		t.Errorf("Len = %d after key-b was idle, want 1", r.Len())
+	}
+
+	// Adding a client evicts idle ones first
+	advance(2 * time.Minute)
+	r.Get("key-c")
+	if r.Len() != 1 {
+		t.Errorf("Len = %d after adding a client, want 1", r.Len())
+	}
+	if r.Get("key-a") == first {
+		t.Error("evicted client was reused")
+	}
+}
+
+func TestClientRegistryStaysWithinMaximum(t *testing.T) {
+	r, advance := newTestRegistry(time.Hour, 2)
+
+	first := r.Get("key-a")
+	advance(time.Second)
+	second := r.Get("key-b")
+	advance(time.Second)
+	r.Get("key-a") // key-b is now the least recently used
+	advance(time.Second)
+	r.Get("key-c")
+
+	if r.Len() != 2 {
+		t.Errorf("Len = %d, want 2", r.Len())
+	}
+	if r.Get("key-a") != first {
+		t.Error("recently used client was evicted")
+	}
+	if r.Get("key-b") == second {
+		t.Error("least recently used client was not evicted")
+	}
+	if r.Len() != 2 {
+		t.Errorf("Len = %d after re-adding key-b, want 2", r.Len())
+	}
+}
+
+func TestClientRegistryRemove(t *testing.T) {
+	r, _ := newTestRegistry(time.Hour, 10)
+
+	first := r.Get("key-a")
+	r.Remove(hashAPIKey("key-a"))
+	if r.Len() != 0 {
+		t.Errorf("Len = %d after Remove, want 0", r.Len())
+	}
+	if r.Get("key-a") == first {
+		t.Error("removed client was reused")
+	}
+}
+
+func TestClientRegistryVerify(t *testing.T) {
+	srv := fake.NewServer(fake.DefaultFixtures())
+	defer srv.Close()
+	r := NewClientRegistry(time.Hour, 10, airfocus.WithBaseURL(srv.URL))
+	ctx := context.Background()
+
+	if _, err := r.Verify(ctx, "wrong-key"); !errors.Is(err, airfocus.ErrUnauthorized) {
+		t.Errorf("Verify with a rejected key: error = %v, want ErrUnauthorized", err)
+	}
+	if r.Len() != 0 {
+		t.Errorf("Len = %d after a rejected key, want 0", r.Len())
+	}
+
+	client, err := r.Verify(ctx, fake.APIKey)
+	if err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	if r.Get(fake.APIKey) != client {
+		t.Error("verified client was not kept")
+	}
+
+	// A known key is not checked again
+	before := srv.Requests("GET", "/team")
+	if again, err := r.Verify(ctx, fake.APIKey); err != nil || again != client {
+		t.Errorf("Verify of a known key = %p, %v, want the kept client", again, err)
+	}
+	if n := srv.Requests("GET", "/team"); n != before {
+		t.Errorf("known key was checked against the API again (%d requests, want %d)", n, before)
+	}
+}
+
+func TestClientRegistryKeyOptions(t *testing.T) {
+	r, _ := newTestRegistry(time.Hour, 10)
+	applied := 0
+	r.SetKeyOptions("key-a", func(*airfocus.Client) { applied++ })
+
+	r.Get("key-b")
+	if applied != 0 {
+		t.Error("options of key-a were applied to the client of key-b")
+	}
+	r.Get("key-a")
+	if applied != 1 {
+		t.Errorf("options of key-a were applied %d times, want 1", applied)
+	}
+}
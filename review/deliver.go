@@ -0,0 +1,173 @@
+package review
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/http"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+// Message is a rendered report ready to be delivered
+type Message struct {
+	Subject string
+	Format  Format
+	Body    string
+	Report  *Report
+}
+
+// NewMessage renders the report in the given format
+func NewMessage(report *Report, format Format) (Message, error) {
+	body, err := report.Render(format)
+	if err != nil {
+		return Message{}, err
+	}
+	return Message{Subject: Subject(report), Format: format, Body: body, Report: report}, nil
+}
+
+// SMTP delivers messages through an SMTP server. The connection is upgraded
+// with STARTTLS when the server offers it; credentials are only sent over
+// TLS or to localhost.
+type SMTP struct {
+	Addr     string // Server address as host:port
+	Username string // Empty to send without authentication
+	Password string
+	From     string // Sender address
+}
+
+// Send emails msg to the recipients
+func (s SMTP) Send(ctx context.Context, to []string, msg Message) error {
+	if len(to) == 0 {
+		return nil
+	}
+	host, _, err := net.SplitHostPort(s.Addr)
+	if err != nil {
+		return fmt.Errorf("invalid SMTP address %q: %w", s.Addr, err)
+	}
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", s.Addr)
+	if err != nil {
+		return fmt.Errorf("failed to connect to SMTP server: %w", err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	client, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("failed to connect to SMTP server: %w", err)
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: host}); err != nil {
+			return fmt.Errorf("SMTP STARTTLS failed: %w", err)
+		}
+	}
+	if s.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", s.Username, s.Password, host)); err != nil {
+			return fmt.Errorf("SMTP authentication failed: %w", err)
+		}
+	}
+	if err := client.Mail(s.From); err != nil {
+		return fmt.Errorf("SMTP server rejected sender %s: %w", s.From, err)
+	}
+	for _, rcpt := range to {
+		if err := client.Rcpt(rcpt); err != nil {
+			return fmt.Errorf("SMTP server rejected recipient %s: %w", rcpt, err)
+		}
+	}
+	w, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("SMTP DATA failed: %w", err)
+	}
+	if err := writeEmail(w, s.From, to, msg); err != nil {
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+	return client.Quit()
+}
+
+// writeEmail writes msg as a quoted-printable email. Markdown is sent as
+// plain text, which reads well in any mail client.
+func writeEmail(w io.Writer, from string, to []string, msg Message) error {
+	contentType := "text/plain; charset=utf-8"
+	if msg.Format == HTML {
+		contentType = "text/html; charset=utf-8"
+	}
+	headers := []string{
+		"From: " + from,
+		"To: " + strings.Join(to, ", "),
+		"Subject: " + mime.QEncoding.Encode("utf-8", msg.Subject),
+		"Date: " + time.Now().Format(time.RFC1123Z),
+		"MIME-Version: 1.0",
+		"Content-Type: " + contentType,
+		"Content-Transfer-Encoding: quoted-printable",
+	}
+	if _, err := io.WriteString(w, strings.Join(headers, "\r\n")+"\r\n\r\n"); err != nil {
+		return err
+	}
+	qp := quotedprintable.NewWriter(w)
+	if _, err := io.WriteString(qp, msg.Body); err != nil {
+		return err
+	}
+	return qp.Close()
+}
+
+// Webhook posts messages as JSON to a URL, e.g. a chat integration or a
+// ticketing system
+type Webhook struct {
+	URL     string
+	Headers map[string]string // Extra request headers, e.g. Authorization
+	Client  *http.Client      // http.DefaultClient if nil
+}
+
+// webhookPayload is the JSON body posted to a webhook
+type webhookPayload struct {
+	Subject string  `json:"subject"`
+	Format  Format  `json:"format"`
+	Body    string  `json:"body"`   // The rendered report
+	Report  *Report `json:"report"` // The report as data
+}
+
+// Send posts msg to the webhook. Any 2xx response is a success.
+func (h Webhook) Send(ctx context.Context, msg Message) error {
+	payload, err := json.Marshal(webhookPayload{Subject: msg.Subject, Format: msg.Format, Body: msg.Body, Report: msg.Report})
+	if err != nil {
+		return fmt.Errorf("failed to encode webhook payload: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.URL, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("invalid webhook URL: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for name, value := range h.Headers {
+		req.Header.Set(name, value)
+	}
+
+	client := h.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("webhook request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return nil
+}
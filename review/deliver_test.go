@@ -0,0 +1,82 @@
+package review
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	smtpfake "github.com/tibuski/goAirfocus/review/fake"
+)
+
+// testMessage returns a rendered report of one workspace
+func testMessage(t *testing.T, format Format) Message {
+	t.Helper()
+	report := &Report{Team: "Acme", Workspaces: []Workspace{{ID: "w-ios", Name: "iOS App"}}}
+	msg, err := NewMessage(report, format)
+	if err != nil {
+		t.Fatalf("NewMessage: %v", err)
+	}
+	return msg
+}
+
+func TestSMTPSend(t *testing.T) {
+	srv := smtpfake.NewServer()
+	t.Cleanup(func() { srv.Close() })
+	ctx := context.Background()
+
+	mailer := SMTP{Addr: srv.Addr, Username: "reviews", Password: "secret", From: "reviews@example.com"}
+	if err := mailer.Send(ctx, []string{"alice@example.com", "bob@example.com"}, testMessage(t, HTML)); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+
+	messages := srv.Messages()
+	if len(messages) != 1 {
+		t.Fatalf("received %d messages, want 1", len(messages))
+	}
+	msg := messages[0]
+	if msg.Username != "reviews" || msg.From != "reviews@example.com" || strings.Join(msg.To, ",") != "alice@example.com,bob@example.com" {
+		t.Errorf("envelope = %+v", msg)
+	}
+	if got := msg.Subject(); got != "Access review for Acme - 0001-01-01" {
+		t.Errorf("subject = %q", got)
+	}
+	if !strings.Contains(msg.Data, "Content-Type: text/html; charset=utf-8") || !strings.Contains(msg.Body(), "<h2 style=\"font-size: 16px; margin-top: 24px;\">iOS App</h2>") {
+		t.Errorf("message is not the HTML report:\n%s", msg.Data)
+	}
+
+	if err := mailer.Send(ctx, nil, testMessage(t, Markdown)); err != nil || len(srv.Messages()) != 1 {
+		t.Errorf("Send without recipients: %v, %d messages, want nothing sent", err, len(srv.Messages()))
+	}
+}
+
+func TestWebhookSend(t *testing.T) {
+	var payload webhookPayload
+	var auth string
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		}
+	}))
+	t.Cleanup(hook.Close)
+	ctx := context.Background()
+
+	webhook := Webhook{URL: hook.URL, Headers: map[string]string{"Authorization": "Bearer token"}}
+	if err := webhook.Send(ctx, testMessage(t, Markdown)); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	if auth != "Bearer token" || payload.Format != Markdown || !strings.Contains(payload.Body, "## iOS App") || payload.Report == nil || payload.Report.Team != "Acme" {
+		t.Errorf("webhook received auth %q and payload %+v", auth, payload)
+	}
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "down", http.StatusServiceUnavailable)
+	}))
+	t.Cleanup(failing.Close)
+	if err := (Webhook{URL: failing.URL}).Send(ctx, testMessage(t, Markdown)); err == nil || !strings.Contains(err.Error(), "503") {
+		t.Errorf("Send to a failing webhook: %v, want the status", err)
+	}
+}
@@ -0,0 +1,230 @@
+// Package fake provides a minimal SMTP server that catches mail instead of
+// delivering it, for tests and for trying out access-review emails locally.
+// It accepts any sender, recipient and AUTH PLAIN credentials.
+//
+//	srv := fake.NewServer()
+//	defer srv.Close()
+//	err := review.SMTP{Addr: srv.Addr, From: "reviews@example.com"}.Send(ctx, to, msg)
+//	messages := srv.Messages()
+package fake
+
+import (
+	"encoding/base64"
+	"io"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"sync"
+)
+
+// Message is an email the server received
+type Message struct {
+	Username string   // AUTH PLAIN user name, empty without authentication
+	From     string   // Envelope sender
+	To       []string // Envelope recipients
+	Data     string   // The message as sent, with headers
+}
+
+// Subject returns the decoded Subject header
+func (m Message) Subject() string {
+	msg, err := mail.ReadMessage(strings.NewReader(m.Data))
+	if err != nil {
+		return ""
+	}
+	subject, err := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	if err != nil {
+		return msg.Header.Get("Subject")
+	}
+	return subject
+}
+
+// Body returns the message body, decoding quoted-printable
+func (m Message) Body() string {
+	msg, err := mail.ReadMessage(strings.NewReader(m.Data))
+	if err != nil {
+		return ""
+	}
+	var body io.Reader = msg.Body
+	if strings.EqualFold(msg.Header.Get("Content-Transfer-Encoding"), "quoted-printable") {
+		body = quotedprintable.NewReader(body)
+	}
+	data, _ := io.ReadAll(body)
+	return string(data)
+}
+
+// Server is a running fake SMTP server
+type Server struct {
+	Addr string // Address the server listens on, as host:port
+
+	listener net.Listener
+	notify   func(Message)
+	wg       sync.WaitGroup
+
+	mu       sync.Mutex
+	messages []Message
+	conns    map[net.Conn]bool // Open connections, closed by Close
+	rejected map[string]bool   // Recipients refused with a permanent error
+}
+
+// NewServer starts a server on a random local port. It panics if it cannot
+// listen, like httptest.NewServer.
+func NewServer() *Server {
+	srv, err := Listen("127.0.0.1:0", nil)
+	if err != nil {
+		panic("fake: failed to listen: " + err.Error())
+	}
+	return srv
+}
+
+// Listen starts a server on addr. notify, if not nil, is called with every
+// message received.
+func Listen(addr string, notify func(Message)) (*Server, error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	srv := &Server{Addr: listener.Addr().String(), listener: listener, notify: notify, conns: make(map[net.Conn]bool), rejected: make(map[string]bool)}
+	srv.wg.Add(1)
+	go srv.serve()
+	return srv, nil
+}
+
+// Close stops the server and closes open connections
+func (s *Server) Close() error {
+	err := s.listener.Close()
+	s.mu.Lock()
+	for conn := range s.conns {
+		conn.Close()
+	}
+	s.mu.Unlock()
+	s.wg.Wait()
+	return err
+}
+
+// Reject makes the server refuse the given recipient addresses, as a mail
+// server does for mailboxes that do not exist
+func (s *Server) Reject(addrs ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, addr := range addrs {
+		s.rejected[strings.ToLower(addr)] = true
+	}
+}
+
+// Messages returns the messages received so far
+func (s *Server) Messages() []Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Message(nil), s.messages...)
+}
+
+func (s *Server) serve() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		s.mu.Lock()
+		s.conns[conn] = true
+		s.mu.Unlock()
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.handle(textproto.NewConn(conn))
+			conn.Close()
+			s.mu.Lock()
+			delete(s.conns, conn)
+			s.mu.Unlock()
+		}()
+	}
+}
+
+// handle speaks just enough SMTP for net/smtp and common mail libraries
+func (s *Server) handle(conn *textproto.Conn) {
+	var msg Message
+	reply := func(line string) bool {
+		return conn.PrintfLine("%s", line) == nil
+	}
+	if !reply("220 localhost fake SMTP ready") {
+		return
+	}
+	for {
+		line, err := conn.ReadLine()
+		if err != nil {
+			return
+		}
+		verb, arg, _ := strings.Cut(line, " ")
+		switch strings.ToUpper(verb) {
+		case "EHLO", "HELO":
+			msg = Message{}
+			if !reply("250-localhost") || !reply("250-8BITMIME") || !reply("250 AUTH PLAIN") {
+				return
+			}
+		case "AUTH":
+			mechanism, initial, _ := strings.Cut(arg, " ")
+			if !strings.EqualFold(mechanism, "PLAIN") {
+				reply("504 Unrecognized authentication type")
+				continue
+			}
+			if decoded, err := base64.StdEncoding.DecodeString(initial); err == nil {
+				if parts := strings.Split(string(decoded), "\x00"); len(parts) == 3 {
+					msg.Username = parts[1]
+				}
+			}
+			reply("235 Authentication successful")
+		case "MAIL":
+			msg.From = envelopeAddress(arg)
+			msg.To = nil
+			reply("250 OK")
+		case "RCPT":
+			addr := envelopeAddress(arg)
+			s.mu.Lock()
+			rejected := s.rejected[strings.ToLower(addr)]
+			s.mu.Unlock()
+			if rejected {
+				reply("550 No such user")
+				continue
+			}
+			msg.To = append(msg.To, addr)
+			reply("250 OK")
+		case "DATA":
+			if !reply("354 End data with <CR><LF>.<CR><LF>") {
+				return
+			}
+			data, err := io.ReadAll(conn.DotReader())
+			if err != nil {
+				return
+			}
+			received := msg
+			received.Data = string(data)
+			s.mu.Lock()
+			s.messages = append(s.messages, received)
+			s.mu.Unlock()
+			if s.notify != nil {
+				s.notify(received)
+			}
+			reply("250 OK")
+		case "RSET":
+			msg.From, msg.To = "", nil
+			reply("250 OK")
+		case "NOOP":
+			reply("250 OK")
+		case "QUIT":
+			reply("221 Bye")
+			return
+		default:
+			reply("502 Command not implemented")
+		}
+	}
+}
+
+// envelopeAddress extracts the address from "FROM:<a@b>" or "TO:<a@b>"
+func envelopeAddress(arg string) string {
+	_, addr, _ := strings.Cut(arg, ":")
+	addr, _, _ = strings.Cut(strings.TrimSpace(addr), " ") // Drop parameters such as BODY=8BITMIME
+	return strings.Trim(addr, "<>")
+}
@@ -0,0 +1,100 @@
+package review
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	"strings"
+	texttemplate "text/template"
+
+	"github.com/tibuski/goAirfocus/airfocus"
+	"github.com/tibuski/goAirfocus/snapshot"
+)
+
+// Format is how a report is rendered
+type Format string
+
+const (
+	HTML     Format = "html"
+	Markdown Format = "markdown"
+)
+
+// Valid reports whether f is a supported format
+func (f Format) Valid() bool {
+	return f == HTML || f == Markdown
+}
+
+//go:embed templates/*
+var templateFS embed.FS
+
+var templateFuncs = map[string]interface{}{
+	"subject": Subject,
+	"change":  describeChange,
+	"md":      escapeMarkdown,
+}
+
+var (
+	htmlTemplate     = htmltemplate.Must(htmltemplate.New("report.html").Funcs(templateFuncs).ParseFS(templateFS, "templates/report.html"))
+	markdownTemplate = texttemplate.Must(texttemplate.New("report.md").Funcs(templateFuncs).ParseFS(templateFS, "templates/report.md"))
+)
+
+// Render returns the report as an HTML document or as Markdown
+func (r *Report) Render(format Format) (string, error) {
+	var buf bytes.Buffer
+	var err error
+	switch format {
+	case HTML:
+		err = htmlTemplate.Execute(&buf, r)
+	case Markdown:
+		err = markdownTemplate.Execute(&buf, r)
+	default:
+		return "", fmt.Errorf("unsupported report format %q (use html or markdown)", format)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to render report: %w", err)
+	}
+	return buf.String(), nil
+}
+
+// Subject returns the title of the report, used as the email subject
+func Subject(r *Report) string {
+	return fmt.Sprintf("Access review for %s - %s", r.Team, r.GeneratedAt.Format("2006-01-02"))
+}
+
+// describeChange explains a change in one sentence
+func describeChange(change snapshot.Change) string {
+	user := change.UserName
+	if change.UserEmail != "" {
+		user += " (" + change.UserEmail + ")"
+	}
+	switch change.Kind {
+	case snapshot.Granted:
+		return fmt.Sprintf("%s was granted %s", user, change.To)
+	case snapshot.Revoked:
+		return fmt.Sprintf("%s lost %s", user, change.From)
+	case snapshot.DefaultChanged:
+		return fmt.Sprintf("The default permission for %s changed from %s to %s", strings.ToLower(change.UserName), permissionOrNone(change.From), permissionOrNone(change.To))
+	default:
+		return fmt.Sprintf("%s changed from %s to %s", user, change.From, change.To)
+	}
+}
+
+// permissionOrNone returns the permission, or "none" if it is empty
+func permissionOrNone(p airfocus.Permission) string {
+	if p == "" {
+		return string(airfocus.PermissionNone)
+	}
+	return string(p)
+}
+
+// markdownEscaper escapes the characters that would format names in Markdown
+var markdownEscaper = strings.NewReplacer(
+	`\`, `\\`, "`", "\\`", "*", `\*`, "_", `\_`, "[", `\[`, "]", `\]`,
+	"<", `\<`, ">", `\>`, "|", `\|`, "#", `\#`,
+)
+
+// escapeMarkdown makes s render literally in Markdown
+func escapeMarkdown(s string) string {
+	return markdownEscaper.Replace(s)
+}
@@ -0,0 +1,188 @@
+// Package review builds periodic access-review reports: for every workspace,
+// its owners, who holds explicit access at what level, and what changed since
+// the previous report. Reports render as HTML or Markdown and are delivered
+// by email or to a webhook.
+package review
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/tibuski/goAirfocus/airfocus"
+	"github.com/tibuski/goAirfocus/snapshot"
+)
+
+// Workspace is the review of one workspace
+type Workspace struct {
+	ID      string                   `json:"id"`
+	Name    string                   `json:"name"`
+	Owners  []airfocus.WorkspaceUser `json:"owners"`            // Users holding full permission
+	Users   []airfocus.WorkspaceUser `json:"users"`             // Every user with explicit access, by name
+	Changes []snapshot.Change        `json:"changes,omitempty"` // Changes since the previous report
+}
+
+// Report is an access review of every workspace of a team
+type Report struct {
+	Team        string      `json:"team"`
+	GeneratedAt time.Time   `json:"generatedAt"`
+	Since       time.Time   `json:"since,omitempty"` // When the previous report was generated, zero for the first
+	Workspaces  []Workspace `json:"workspaces"`      // Sorted by name
+}
+
+// Build reviews every workspace with GetWorkspaceUsers. When previous is not
+// nil, each workspace also lists the changes since that report.
+func Build(ctx context.Context, client *airfocus.Client, previous *Report) (*Report, error) {
+	team, err := client.GetTeam(ctx)
+	if err != nil {
+		return nil, err
+	}
+	workspaces, err := client.ListWorkspaces(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &Report{Team: team.Name, GeneratedAt: time.Now().UTC()}
+	for _, ws := range workspaces {
+		users, err := client.GetWorkspaceUsers(ctx, ws.ID)
+		if err != nil {
+			return nil, err
+		}
+		review := Workspace{ID: ws.ID, Name: ws.Name, Owners: []airfocus.WorkspaceUser{}, Users: users}
+		for _, user := range users {
+			if airfocus.Permission(user.Permission) == airfocus.PermissionFull {
+				review.Owners = append(review.Owners, user)
+			}
+		}
+		report.Workspaces = append(report.Workspaces, review)
+	}
+	sort.Slice(report.Workspaces, func(i, j int) bool {
+		return strings.ToLower(report.Workspaces[i].Name) < strings.ToLower(report.Workspaces[j].Name)
+	})
+
+	if previous != nil {
+		report.Since = previous.GeneratedAt
+		changes := snapshot.Diff(previous.snapshot(), report.snapshot())
+		byWorkspace := make(map[string][]snapshot.Change)
+		for _, change := range changes {
+			byWorkspace[change.TargetID] = append(byWorkspace[change.TargetID], change)
+		}
+		for i := range report.Workspaces {
+			report.Workspaces[i].Changes = byWorkspace[report.Workspaces[i].ID]
+		}
+	}
+	return report, nil
+}
+
+// snapshot returns the explicit workspace permissions of the report in the
+// shape snapshot.Diff compares
+func (r *Report) snapshot() *airfocus.Snapshot {
+	snap := &airfocus.Snapshot{TakenAt: r.GeneratedAt}
+	seen := make(map[string]bool)
+	for _, review := range r.Workspaces {
+		ws := airfocus.Workspace{ID: review.ID, Name: review.Name}
+		ws.Embedded.Permissions = make(map[string]string, len(review.Users))
+		for _, user := range review.Users {
+			ws.Embedded.Permissions[user.UserID] = user.Permission
+			if !seen[user.UserID] && user.Email != "" {
+				seen[user.UserID] = true
+				snap.Users = append(snap.Users, airfocus.User{UserID: user.UserID, FullName: user.FullName, Email: user.Email})
+			}
+		}
+		snap.Workspaces = append(snap.Workspaces, ws)
+	}
+	return snap
+}
+
+// Changes returns the number of changes since the previous report
+func (r *Report) Changes() int {
+	n := 0
+	for _, review := range r.Workspaces {
+		n += len(review.Changes)
+	}
+	return n
+}
+
+// ForOwner returns the part of the report covering the workspaces the user
+// with the given email owns, or nil if they own none
+func (r *Report) ForOwner(email string) *Report {
+	owned := *r
+	owned.Workspaces = nil
+	for _, review := range r.Workspaces {
+		for _, owner := range review.Owners {
+			if strings.EqualFold(owner.Email, email) {
+				owned.Workspaces = append(owned.Workspaces, review)
+				break
+			}
+		}
+	}
+	if len(owned.Workspaces) == 0 {
+		return nil
+	}
+	return &owned
+}
+
+// OwnerEmails returns the email addresses of all workspace owners, sorted
+func (r *Report) OwnerEmails() []string {
+	seen := make(map[string]bool)
+	var emails []string
+	for _, review := range r.Workspaces {
+		for _, owner := range review.Owners {
+			email := strings.ToLower(owner.Email)
+			if email != "" && !seen[email] {
+				seen[email] = true
+				emails = append(emails, email)
+			}
+		}
+	}
+	sort.Strings(emails)
+	return emails
+}
+
+// Load reads a report saved with Save. A missing file returns nil and no
+// error, as there is no previous report yet.
+func Load(path string) (*Report, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read previous report: %w", err)
+	}
+	var report Report
+	if err := json.Unmarshal(data, &report); err != nil {
+		return nil, fmt.Errorf("failed to parse previous report %s: %w", path, err)
+	}
+	return &report, nil
+}
+
+// Save writes the report to path, so the next report can list the changes
+// since this one. The file is replaced atomically.
+func (r *Report) Save(path string) error {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return fmt.Errorf("failed to encode report: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".review-*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to save report: %w", err)
+	}
+	defer os.Remove(tmp.Name()) // No-op once renamed
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to save report: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to save report: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to save report: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,120 @@
+package review
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/tibuski/goAirfocus/airfocus"
+	"github.com/tibuski/goAirfocus/airfocus/fake"
+	"github.com/tibuski/goAirfocus/snapshot"
+)
+
+// findWorkspace returns the review of the workspace with the given ID
+func findWorkspace(t *testing.T, report *Report, id string) Workspace {
+	t.Helper()
+	for _, ws := range report.Workspaces {
+		if ws.ID == id {
+			return ws
+		}
+	}
+	t.Fatalf("workspace %s is not in the report", id)
+	return Workspace{}
+}
+
+func TestBuild(t *testing.T) {
+	srv := fake.NewServer(fake.DefaultFixtures())
+	t.Cleanup(srv.Close)
+	client := airfocus.NewClient(fake.APIKey, airfocus.WithBaseURL(srv.URL))
+	ctx := context.Background()
+
+	first, err := Build(ctx, client, nil)
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+	if first.Team != "Acme" || !first.Since.IsZero() {
+		t.Errorf("report team = %q, since = %v", first.Team, first.Since)
+	}
+	ios := findWorkspace(t, first, "w-ios")
+	if len(ios.Owners) != 1 || ios.Owners[0].UserID != "u-alice" || len(ios.Users) != 2 || ios.Changes != nil {
+		t.Errorf("iOS review = %+v", ios)
+	}
+	if android := findWorkspace(t, first, "w-android"); len(android.Owners) != 0 {
+		t.Errorf("Android owners = %+v, want none", android.Owners)
+	}
+
+	path := filepath.Join(t.TempDir(), "review.json")
+	if previous, err := Load(path); previous != nil || err != nil {
+		t.Errorf("Load of a missing file = %v, %v, want nil, nil", previous, err)
+	}
+	if err := first.Save(path); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	previous, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+
+	if err := client.SetWorkspacePermission(ctx, "w-ios", "u-carol", airfocus.PermissionFull); err != nil {
+		t.Fatalf("SetWorkspacePermission: %v", err)
+	}
+	second, err := Build(ctx, client, previous)
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+	if !second.Since.Equal(first.GeneratedAt) || second.Changes() != 1 {
+		t.Fatalf("second report since %v with %d changes, want one change since %v", second.Since, second.Changes(), first.GeneratedAt)
+	}
+	change := findWorkspace(t, second, "w-ios").Changes[0]
+	if change.Kind != snapshot.Changed || change.UserID != "u-carol" || change.From != "comment" || change.To != "full" {
+		t.Errorf("change = %+v", change)
+	}
+
+	if got := second.OwnerEmails(); strings.Join(got, ",") != "alice@example.com,bob@example.com,carol@example.com" {
+		t.Errorf("OwnerEmails = %v", got)
+	}
+	carol := second.ForOwner("Carol@Example.com")
+	if carol == nil || len(carol.Workspaces) != 1 || carol.Workspaces[0].ID != "w-ios" {
+		t.Errorf("ForOwner(carol) = %+v, want only iOS App", carol)
+	}
+	if second.ForOwner("dave@example.com") != nil {
+		t.Errorf("ForOwner of a user owning nothing is not nil")
+	}
+}
+
+func TestRender(t *testing.T) {
+	report := &Report{Team: "Acme", Workspaces: []Workspace{{
+		ID:     "w-ios",
+		Name:   "iOS <App>",
+		Owners: []airfocus.WorkspaceUser{{UserID: "u-alice", FullName: "Alice_Admin", Email: "alice@example.com", Permission: "full"}},
+		Users:  []airfocus.WorkspaceUser{{UserID: "u-alice", FullName: "Alice_Admin", Email: "alice@example.com", Permission: "full"}},
+		Changes: []snapshot.Change{
+			{Kind: snapshot.Granted, UserID: "u-alice", UserName: "Alice_Admin", UserEmail: "alice@example.com", To: "full"},
+		},
+	}}}
+
+	html, err := report.Render(HTML)
+	if err != nil {
+		t.Fatalf("Render(HTML): %v", err)
+	}
+	for _, want := range []string{"<title>Access review for Acme - 0001-01-01</title>", "iOS &lt;App&gt;", "Alice_Admin (alice@example.com) was granted full"} {
+		if !strings.Contains(html, want) {
+			t.Errorf("HTML report does not contain %q:\n%s", want, html)
+		}
+	}
+
+	markdown, err := report.Render(Markdown)
+	if err != nil {
+		t.Fatalf("Render(Markdown): %v", err)
+	}
+	for _, want := range []string{"## iOS \\<App\\>", "| Alice\\_Admin | alice@example.com | full |", "- Alice\\_Admin (alice@example.com) was granted full"} {
+		if !strings.Contains(markdown, want) {
+			t.Errorf("Markdown report does not contain %q:\n%s", want, markdown)
+		}
+	}
+
+	if _, err := report.Render("pdf"); err == nil {
+		t.Errorf("Render of an unknown format succeeded")
+	}
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/tibuski/goAirfocus/airfocus"
+	"github.com/tibuski/goAirfocus/review"
+)
+
+const (
+	defaultReviewInterval = 30 * 24 * time.Hour // Reviews are monthly unless configured otherwise
+	reviewRetryDelay      = time.Hour           // Wait before retrying a failed review
+)
+
+// reviewConfig says how often access reviews are sent, how they are
+// rendered and where they are delivered. It is read from the JSON file
+// named by AIRFOCUS_REVIEW_CONFIG.
+type reviewConfig struct {
+	Interval  string               `json:"interval"`  // Go duration between reviews, 720h if empty
+	Format    review.Format        `json:"format"`    // "html" (default) or "markdown"
+	StateFile string               `json:"stateFile"` // Where the last report is kept, to list changes and schedule the next
+	SMTP      *reviewSMTPConfig    `json:"smtp"`
+	Webhook   *reviewWebhookConfig `json:"webhook"`
+
+	interval time.Duration
+}
+
+// reviewSMTPConfig delivers reviews by email
+type reviewSMTPConfig struct {
+	Addr         string   `json:"addr"` // host:port of the SMTP server
+	Username     string   `json:"username"`
+	Password     string   `json:"password"`
+	PasswordFile string   `json:"passwordFile"` // Alternative to password, e.g. a mounted secret
+	From         string   `json:"from"`
+	To           []string `json:"to"`     // Recipients of the full report
+	Owners       bool     `json:"owners"` // Also send each workspace owner the part covering their workspaces
+}
+
+// reviewWebhookConfig delivers reviews to a webhook as JSON
+type reviewWebhookConfig struct {
+	URL     string            `json:"url"`
+	Headers map[string]string `json:"headers"`
+}
+
+// loadReviewConfig reads and validates an access review configuration file
+func loadReviewConfig(path string) (*reviewConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read review configuration: %w", err)
+	}
+	var config reviewConfig
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&config); err != nil {
+		return nil, fmt.Errorf("failed to parse review configuration %s: %w", path, err)
+	}
+
+	config.interval = defaultReviewInterval
+	if config.Interval != "" {
+		d, err := time.ParseDuration(config.Interval)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("review configuration: invalid interval %q: use a Go duration such as 720h", config.Interval)
+		}
+		config.interval = d
+	}
+	if config.Format == "" {
+		config.Format = review.HTML
+	}
+	if !config.Format.Valid() {
+		return nil, fmt.Errorf("review configuration: unknown format %q (use html or markdown)", config.Format)
+	}
+	if config.StateFile == "" {
+		return nil, errors.New("review configuration: stateFile is required")
+	}
+	if config.SMTP == nil && config.Webhook == nil {
+		return nil, errors.New("review configuration: configure smtp, webhook or both")
+	}
+
+	if smtp := config.SMTP; smtp != nil {
+		if smtp.Addr == "" || smtp.From == "" {
+			return nil, errors.New("review configuration: smtp needs addr and from")
+		}
+		if len(smtp.To) == 0 && !smtp.Owners {
+			return nil, errors.New("review configuration: smtp needs recipients in to, or owners")
+		}
+		if smtp.PasswordFile != "" {
+			if smtp.Password != "" {
+				return nil, errors.New("review configuration: set smtp password or passwordFile, not both")
+			}
+			secret, err := os.ReadFile(smtp.PasswordFile)
+			if err != nil {
+				return nil, fmt.Errorf("review configuration: failed to read smtp passwordFile: %w", err)
+			}
+			smtp.Password = strings.TrimSpace(string(secret))
+		}
+	}
+	if webhook := config.Webhook; webhook != nil {
+		u, err := url.Parse(webhook.URL)
+		if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+			return nil, fmt.Errorf("review configuration: invalid webhook url %q", webhook.URL)
+		}
+	}
+	return &config, nil
+}
+
+// sendReview builds an access review, delivers it everywhere configured and
+// keeps it as the base of the next review's changes. When delivery to the
+// main recipients or the webhook fails the report is not kept, so its
+// changes are reported again. Failed deliveries to individual workspace
+// owners are returned separately and do not stop the report being kept, so
+// one bad owner address does not make everyone receive the review again.
+func sendReview(ctx context.Context, client *airfocus.Client, config *reviewConfig) (*review.Report, []error, error) {
+	previous, err := review.Load(config.StateFile)
+	if err != nil {
+		return nil, nil, err
+	}
+	report, err := review.Build(ctx, client, previous)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to build access review: %w", err)
+	}
+	msg, err := review.NewMessage(report, config.Format)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var errs []error
+	if config.SMTP != nil {
+		if err := config.SMTP.mailer().Send(ctx, config.SMTP.To, msg); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if config.Webhook != nil {
+		if err := (review.Webhook{URL: config.Webhook.URL, Headers: config.Webhook.Headers}).Send(ctx, msg); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		return report, nil, fmt.Errorf("failed to deliver access review: %w", errors.Join(errs...))
+	}
+
+	var ownerErrs []error
+	if config.SMTP != nil && config.SMTP.Owners {
+		for _, email := range report.OwnerEmails() {
+			ownerMsg, err := review.NewMessage(report.ForOwner(email), config.Format)
+			if err == nil {
+				err = config.SMTP.mailer().Send(ctx, []string{email}, ownerMsg)
+			}
+			if err != nil {
+				ownerErrs = append(ownerErrs, fmt.Errorf("access review for %s: %w", email, err))
+			}
+		}
+	}
+	return report, ownerErrs, report.Save(config.StateFile)
+}
+
+// mailer returns the SMTP client for the configuration
+func (c *reviewSMTPConfig) mailer() review.SMTP {
+	return review.SMTP{Addr: c.Addr, Username: c.Username, Password: c.Password, From: c.From}
+}
+
+// startReviewScheduler sends an access review whenever the configured
+// interval has passed since the last one, until ctx is cancelled. Failed
+// reviews are logged and retried after an hour.
+func startReviewScheduler(ctx context.Context, client *airfocus.Client, config *reviewConfig) {
+	// nextReview returns how long to wait until the next review is due
+	nextReview := func() time.Duration {
+		last, err := review.Load(config.StateFile)
+		if err != nil {
+			log.Printf("Access review: %v", err)
+			return reviewRetryDelay
+		}
+		if last == nil {
+			return 0
+		}
+		return time.Until(last.GeneratedAt.Add(config.interval))
+	}
+
+	go func() {
+		for {
+			wait := nextReview()
+			if wait < 0 {
+				wait = 0
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(wait):
+			}
+
+			report, ownerErrs, err := sendReview(ctx, client, config)
+			for _, ownerErr := range ownerErrs {
+				log.Printf("Access review not delivered: %v", ownerErr)
+			}
+			if err != nil {
+				log.Printf("Access review failed: %v", err)
+				select {
+				case <-ctx.Done():
+					return
+				case <-time.After(reviewRetryDelay):
+				}
+				continue
+			}
+			log.Printf("Access review sent: %d workspaces, %d changes", len(report.Workspaces), report.Changes())
+		}
+	}()
+}
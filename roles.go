@@ -0,0 +1,129 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// Role is what an operator may do in the tool
+type Role string
+
+const (
+	RoleViewer Role = "viewer" // Views and exports data
+	RoleEditor Role = "editor" // Also changes permissions and copies access
+	RoleAdmin  Role = "admin"  // Also offboards users
+)
+
+// Roles lists the operator roles from least to most privileged
+var Roles = []Role{RoleViewer, RoleEditor, RoleAdmin}
+
+// errInsufficientRole is reported when the operator's role does not allow a request
+var errInsufficientRole = errors.New("insufficient role")
+
+// Valid reports whether r is a known role
+func (r Role) Valid() bool {
+	return r.rank() > 0
+}
+
+// rank orders roles; unknown roles rank lowest
+func (r Role) rank() int {
+	for i, known := range Roles {
+		if r == known {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// Allows reports whether r includes everything the required role may do
+func (r Role) Allows(required Role) bool {
+	return r.rank() >= required.rank()
+}
+
+// RoleConfig assigns roles to SSO operators, by email address or by the
+// groups reported by the identity provider. An operator matched by several
+// entries gets the most privileged role.
+type RoleConfig struct {
+	Default   Role            `json:"default"`   // Role of operators matched by no entry, viewer if empty
+	Operators map[string]Role `json:"operators"` // Roles by email address
+	Groups    map[string]Role `json:"groups"`    // Roles by group name
+}
+
+// LoadRoleConfig reads and validates a role configuration file
+func LoadRoleConfig(path string) (*RoleConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read role configuration: %w", err)
+	}
+	var config RoleConfig
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&config); err != nil {
+		return nil, fmt.Errorf("failed to parse role configuration %s: %w", path, err)
+	}
+
+	if config.Default == "" {
+		config.Default = RoleViewer
+	}
+	if !config.Default.Valid() {
+		return nil, fmt.Errorf("role configuration: unknown default role %q", config.Default)
+	}
+	operators := make(map[string]Role, len(config.Operators))
+	for email, role := range config.Operators {
+		if !role.Valid() {
+			return nil, fmt.Errorf("role configuration: unknown role %q for operator %s", role, email)
+		}
+		operators[strings.ToLower(email)] = role
+	}
+	config.Operators = operators
+	for group, role := range config.Groups {
+		if !role.Valid() {
+			return nil, fmt.Errorf("role configuration: unknown role %q for group %s", role, group)
+		}
+	}
+	return &config, nil
+}
+
+// RoleOf returns the role of an operator. Entries by email address only
+// apply to operators whose email is verified, so an identity provider that
+// lets users set any address cannot be used to claim someone else's role.
+func (c *RoleConfig) RoleOf(operator *Operator) Role {
+	role := c.Default
+	if r, ok := c.Operators[strings.ToLower(operator.Email)]; ok && operator.EmailVerified && r.rank() > role.rank() {
+		role = r
+	}
+	for _, group := range operator.Groups {
+		if r, ok := c.Groups[group]; ok && r.rank() > role.rank() {
+			role = r
+		}
+	}
+	return role
+}
+
+// role returns the role of the request's operator. Sessions with a personal
+// API key, and all operators when no role configuration is loaded, are
+// admins: Airfocus itself limits what their key may change.
+func (s *Server) role(r *http.Request) Role {
+	operator := s.sessionOperator(r)
+	if operator == nil || s.roles == nil {
+		return RoleAdmin
+	}
+	return s.roles.RoleOf(operator)
+}
+
+// requireRole wraps a handler that changes data so it only runs for
+// operators with at least the given role
+func (s *Server) requireRole(required Role, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if role := s.role(r); !role.Allows(required) {
+			s.renderError(w, fmt.Errorf("%s (%s) requested %s, which needs %s: %w", s.actor(r), role, r.URL.Path, required, errInsufficientRole), "Not allowed")
+			return
+		}
+		next(w, r)
+	}
+}
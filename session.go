@@ -0,0 +1,322 @@
+package main
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/tibuski/goAirfocus/airfocus"
+)
+
+const (
+	sessionCookieName     = "airfocus_session"
+	defaultSessionIdleTTL = 30 * time.Minute // How long an unused session stays valid
+)
+
+// Operator is the person signed in through SSO
+type Operator struct {
+	Subject       string   // Stable identifier at the identity provider
+	Email         string   // Email address
+	EmailVerified bool     // The email is verified, or trusted as configured for SSO
+	Name          string   // Display name, may be empty
+	Groups        []string // Groups reported by the identity provider
+}
+
+// String returns the operator's name and email, for display and logs
+func (o *Operator) String() string {
+	if o.Name == "" {
+		return o.Email
+	}
+	return o.Name + " <" + o.Email + ">"
+}
+
+// SessionStore maps session IDs to API keys. The store keeps keys encrypted
+// with AES-GCM under a key generated at startup, and sessions expire after
+// being idle for idleTTL. Sessions do not survive a restart.
+//
+// The airfocus.Client serving a session necessarily holds its key in plain
+// text. When the last session of a key ends, by logout or expiry, the store
+// reports it to the function set with OnRelease so that client can be dropped.
+type SessionStore struct {
+	mu       sync.Mutex
+	sessions map[string]*session
+	aead     cipher.AEAD
+	idleTTL  time.Duration
+	release  func(keyHash string) // Called when no session uses a key any more, may be nil
+	now      func() time.Time     // Clock, overridable for expiry logic
+}
+
+// session is an encrypted API key together with its operator and last access time
+type session struct {
+	nonce     []byte
+	sealedKey []byte
+	keyHash   string    // hashAPIKey of the key, to tell when its last session ends
+	operator  *Operator // nil when logged in with a personal API key
+	lastUsed  time.Time
+}
+
+// NewSessionStore creates a store whose sessions expire after idleTTL without use
+func NewSessionStore(idleTTL time.Duration) (*SessionStore, error) {
+	if idleTTL <= 0 {
+		idleTTL = defaultSessionIdleTTL
+	}
+
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("failed to generate session key: %w", err)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create session cipher: %w", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create session cipher: %w", err)
+	}
+
+	return &SessionStore{
+		sessions: make(map[string]*session),
+		aead:     aead,
+		idleTTL:  idleTTL,
+		now:      time.Now,
+	}, nil
+}
+
+// Create starts a session for the API key and returns its ID. operator is
+// the SSO identity behind the session, or nil for a personal API key.
+func (s *SessionStore) Create(apiKey string, operator *Operator) (string, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", fmt.Errorf("failed to generate session ID: %w", err)
+	}
+	id := base64.RawURLEncoding.EncodeToString(raw)
+
+	nonce := make([]byte, s.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	// The session ID is bound as additional data so a sealed key only opens for its own session
+	sealed := s.aead.Seal(nil, nonce, []byte(apiKey), []byte(id))
+
+	s.mu.Lock()
+	released := s.evictIdleLocked(s.now())
+	s.sessions[id] = &session{nonce: nonce, sealedKey: sealed, keyHash: hashAPIKey(apiKey), operator: operator, lastUsed: s.now()}
+	s.mu.Unlock()
+	s.releaseKeys(released)
+	return id, nil
+}
+
+// OnRelease sets the function called with the hash of an API key when the
+// last session using that key ends
+func (s *SessionStore) OnRelease(release func(keyHash string)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.release = release
+}
+
+// Lookup returns the API key and operator of a live session and marks it as used
+func (s *SessionStore) Lookup(id string) (string, *Operator, bool) {
+	s.mu.Lock()
+	sess, ok := s.sessions[id]
+	if !ok {
+		s.mu.Unlock()
+		return "", nil, false
+	}
+	now := s.now()
+	if now.Sub(sess.lastUsed) > s.idleTTL {
+		released := s.removeLocked(id)
+		s.mu.Unlock()
+		s.releaseKeys(released)
+		return "", nil, false
+	}
+
+	apiKey, err := s.aead.Open(nil, sess.nonce, sess.sealedKey, []byte(id))
+	if err != nil {
+		released := s.removeLocked(id)
+		s.mu.Unlock()
+		s.releaseKeys(released)
+		return "", nil, false
+	}
+	sess.lastUsed = now
+	s.mu.Unlock()
+	return string(apiKey), sess.operator, true
+}
+
+// Delete ends a session
+func (s *SessionStore) Delete(id string) {
+	s.mu.Lock()
+	released := s.removeLocked(id)
+	s.mu.Unlock()
+	s.releaseKeys(released)
+}
+
+// Len returns the number of sessions currently held by the store
+func (s *SessionStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.sessions)
+}
+
+// EvictIdle drops all sessions that have not been used within the idle TTL
+func (s *SessionStore) EvictIdle() {
+	s.mu.Lock()
+	released := s.evictIdleLocked(s.now())
+	s.mu.Unlock()
+	s.releaseKeys(released)
+}
+
+// StartJanitor periodically evicts idle sessions until ctx is cancelled
+func (s *SessionStore) StartJanitor(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				s.EvictIdle()
+			}
+		}
+	}()
+}
+
+// evictIdleLocked removes expired sessions and returns the hashes of the keys
+// no session uses any more; the caller must hold s.mu
+func (s *SessionStore) evictIdleLocked(now time.Time) []string {
+	var released []string
+	for id, sess := range s.sessions {
+		if now.Sub(sess.lastUsed) > s.idleTTL {
+			released = append(released, s.removeLocked(id)...)
+		}
+	}
+	return released
+}
+
+// removeLocked removes a session and returns the hash of its key if no other
+// session uses it; the caller must hold s.mu
+func (s *SessionStore) removeLocked(id string) []string {
+	sess, ok := s.sessions[id]
+	if !ok {
+		return nil
+	}
+	delete(s.sessions, id)
+	for _, other := range s.sessions {
+		if other.keyHash == sess.keyHash {
+			return nil
+		}
+	}
+	return []string{sess.keyHash}
+}
+
+// releaseKeys reports keys no session uses any more; the caller must not hold s.mu
+func (s *SessionStore) releaseKeys(keyHashes []string) {
+	s.mu.Lock()
+	release := s.release
+	s.mu.Unlock()
+	if release == nil {
+		return
+	}
+	for _, keyHash := range keyHashes {
+		release(keyHash)
+	}
+}
+
+// sessionClient resolves the Airfocus client of the request's session. Without
+// a live session it writes a 401 error fragment and returns false.
+func (s *Server) sessionClient(w http.ResponseWriter, r *http.Request) (*airfocus.Client, bool) {
+	if cookie, err := r.Cookie(sessionCookieName); err == nil {
+		if apiKey, _, ok := s.sessions.Lookup(cookie.Value); ok {
+			return s.clients.Get(apiKey), true
+		}
+	}
+	s.renderError(w, errNoSession, "Not logged in")
+	return nil, false
+}
+
+// sessionOperator returns the SSO operator of the request's session, or nil
+func (s *Server) sessionOperator(r *http.Request) *Operator {
+	if cookie, err := r.Cookie(sessionCookieName); err == nil {
+		if _, operator, ok := s.sessions.Lookup(cookie.Value); ok {
+			return operator
+		}
+	}
+	return nil
+}
+
+// actor describes who made a request, for logging changes
+func (s *Server) actor(r *http.Request) string {
+	if operator := s.sessionOperator(r); operator != nil {
+		return operator.String()
+	}
+	return "API key session"
+}
+
+// setSessionCookie writes the session cookie; an empty id with maxAge -1 clears it
+func setSessionCookie(w http.ResponseWriter, r *http.Request, id string, maxAge int) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookieName,
+		Value:    id,
+		Path:     "/",
+		MaxAge:   maxAge,
+		HttpOnly: true,
+		SameSite: http.SameSiteStrictMode,
+		// Behind Traefik TLS ends at the proxy, which reports the original scheme
+		Secure: r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https",
+	})
+}
+
+// handleLogin validates the posted API key against Airfocus and exchanges it
+// for a session cookie. A rejected key leaves no client behind. On success
+// the page is reloaded so it renders the logged in state.
+func (s *Server) handleLogin(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if s.sso != nil {
+		http.Error(w, "This server signs in with SSO", http.StatusNotFound)
+		return
+	}
+
+	apiKey := r.FormValue("api_key")
+	if apiKey == "" {
+		http.Error(w, "API key is required", http.StatusBadRequest)
+		return
+	}
+	if _, err := s.clients.Verify(r.Context(), apiKey); err != nil {
+		s.renderError(w, err, "Failed to log in")
+		return
+	}
+
+	id, err := s.sessions.Create(apiKey, nil)
+	if err != nil {
+		s.renderError(w, err, "Failed to start session")
+		return
+	}
+	setSessionCookie(w, r, id, 0)
+	w.Header().Set("HX-Refresh", "true")
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleLogout ends the session, clears the cookie and reloads the page
+func (s *Server) handleLogout(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if cookie, err := r.Cookie(sessionCookieName); err == nil {
+		s.sessions.Delete(cookie.Value)
+	}
+	setSessionCookie(w, r, "", -1)
+	w.Header().Set("HX-Refresh", "true")
+	w.WriteHeader(http.StatusNoContent)
+}
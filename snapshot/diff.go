@@ -0,0 +1,206 @@
+package snapshot
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/tibuski/goAirfocus/airfocus"
+)
+
+// ChangeKind says how a permission changed between two snapshots
+type ChangeKind string
+
+const (
+	Granted        ChangeKind = "granted"         // The user had no explicit permission before
+	Revoked        ChangeKind = "revoked"         // The user has no explicit permission anymore
+	Changed        ChangeKind = "changed"         // The permission level changed
+	DefaultChanged ChangeKind = "default-changed" // The permission every team member has by default changed
+)
+
+// defaultUserName is shown as the user of DefaultChanged changes
+const defaultUserName = "All team members"
+
+// Change is one user's explicit permission on a workspace or workspace group
+// that differs between two snapshots, or for DefaultChanged the default
+// permission of the workspace or group, without a user ID
+type Change struct {
+	Kind       ChangeKind          `json:"kind"`
+	TargetType string              `json:"targetType"` // airfocus.TargetWorkspace or airfocus.TargetGroup
+	TargetID   string              `json:"targetId"`
+	TargetName string              `json:"targetName"`
+	UserID     string              `json:"userId,omitempty"`
+	UserName   string              `json:"userName"`
+	UserEmail  string              `json:"userEmail,omitempty"`
+	From       airfocus.Permission `json:"from,omitempty"` // Permission in the older snapshot, empty if none
+	To         airfocus.Permission `json:"to,omitempty"`   // Permission in the newer snapshot, empty if none
+}
+
+// Diff returns the explicit workspace and group permissions, and the default
+// permissions, that differ between the snapshots from and to, sorted by
+// target and then with the default first and by user name. Workspaces and
+// groups that were created or deleted in between show all their permissions
+// as granted or revoked.
+func Diff(from, to *airfocus.Snapshot) []Change {
+	users := make(map[string]airfocus.User)
+	for _, snap := range []*airfocus.Snapshot{from, to} {
+		for _, user := range snap.Users {
+			users[user.UserID] = user // Later snapshots win
+		}
+	}
+
+	var changes []Change
+	diffTargets := func(targetType string, before, after map[string]permissionTarget) {
+		ids := make(map[string]bool)
+		for id := range before {
+			ids[id] = true
+		}
+		for id := range after {
+			ids[id] = true
+		}
+		for id := range ids {
+			old, current := before[id], after[id]
+			name := current.name
+			if name == "" {
+				name = old.name
+			}
+			if from, to := defaultPermission(old.defaultPermission), defaultPermission(current.defaultPermission); from != to {
+				changes = append(changes, Change{
+					Kind:       DefaultChanged,
+					TargetType: targetType,
+					TargetID:   id,
+					TargetName: name,
+					UserName:   defaultUserName,
+					From:       from,
+					To:         to,
+				})
+			}
+			userIDs := make(map[string]bool)
+			for userID := range old.permissions {
+				userIDs[userID] = true
+			}
+			for userID := range current.permissions {
+				userIDs[userID] = true
+			}
+			for userID := range userIDs {
+				change := Change{
+					TargetType: targetType,
+					TargetID:   id,
+					TargetName: name,
+					UserID:     userID,
+					UserName:   "Unknown User",
+					From:       airfocus.Permission(old.permissions[userID]),
+					To:         airfocus.Permission(current.permissions[userID]),
+				}
+				switch {
+				case change.From == change.To:
+					continue
+				case change.From == "":
+					change.Kind = Granted
+				case change.To == "":
+					change.Kind = Revoked
+				default:
+					change.Kind = Changed
+				}
+				if user, ok := users[userID]; ok {
+					change.UserName = user.FullName
+					change.UserEmail = user.Email
+				}
+				changes = append(changes, change)
+			}
+		}
+	}
+	diffTargets(airfocus.TargetWorkspace, workspaceTargets(from), workspaceTargets(to))
+	diffTargets(airfocus.TargetGroup, groupTargets(from), groupTargets(to))
+
+	sort.Slice(changes, func(i, j int) bool {
+		a, b := changes[i], changes[j]
+		if a.TargetType != b.TargetType {
+			return a.TargetType == airfocus.TargetWorkspace // Workspaces first
+		}
+		if !strings.EqualFold(a.TargetName, b.TargetName) {
+			return strings.ToLower(a.TargetName) < strings.ToLower(b.TargetName)
+		}
+		if a.TargetID != b.TargetID {
+			return a.TargetID < b.TargetID
+		}
+		if (a.Kind == DefaultChanged) != (b.Kind == DefaultChanged) {
+			return a.Kind == DefaultChanged
+		}
+		if !strings.EqualFold(a.UserName, b.UserName) {
+			return strings.ToLower(a.UserName) < strings.ToLower(b.UserName)
+		}
+		return a.UserID < b.UserID
+	})
+	return changes
+}
+
+// permissionTarget is a workspace or group with its explicit permissions by user ID
+type permissionTarget struct {
+	name              string
+	permissions       map[string]string
+	defaultPermission string
+}
+
+// defaultPermission normalizes a default permission so that "none" and
+// unset compare equal
+func defaultPermission(permission string) airfocus.Permission {
+	if airfocus.Permission(permission) == airfocus.PermissionNone {
+		return ""
+	}
+	return airfocus.Permission(permission)
+}
+
+// workspaceTargets indexes the workspaces of a snapshot by ID
+func workspaceTargets(snap *airfocus.Snapshot) map[string]permissionTarget {
+	targets := make(map[string]permissionTarget, len(snap.Workspaces))
+	for _, ws := range snap.Workspaces {
+		targets[ws.ID] = permissionTarget{name: ws.Name, permissions: ws.Embedded.Permissions, defaultPermission: ws.DefaultPermission}
+	}
+	return targets
+}
+
+// groupTargets indexes the workspace groups of a snapshot by ID
+func groupTargets(snap *airfocus.Snapshot) map[string]permissionTarget {
+	targets := make(map[string]permissionTarget, len(snap.Groups))
+	for _, group := range snap.Groups {
+		targets[group.ID] = permissionTarget{name: group.Name, permissions: group.Embedded.Permissions, defaultPermission: group.DefaultPermission}
+	}
+	return targets
+}
+
+// Filter selects changes. Empty fields match everything.
+type Filter struct {
+	User       string              // Matches the user's ID, or part of their name or email, ignoring case; default changes affect every user and always match
+	Target     string              // Matches the workspace or group ID, or part of its name, ignoring case
+	Permission airfocus.Permission // Matches changes from or to this level
+}
+
+// Match reports whether the change passes the filter
+func (f Filter) Match(change Change) bool {
+	if f.User != "" && change.Kind != DefaultChanged && change.UserID != f.User && !containsFold(change.UserName, f.User) && !containsFold(change.UserEmail, f.User) {
+		return false
+	}
+	if f.Target != "" && change.TargetID != f.Target && !containsFold(change.TargetName, f.Target) {
+		return false
+	}
+	if f.Permission != "" && change.From != f.Permission && change.To != f.Permission {
+		return false
+	}
+	return true
+}
+
+// Apply returns the changes that pass the filter
+func (f Filter) Apply(changes []Change) []Change {
+	var matched []Change
+	for _, change := range changes {
+		if f.Match(change) {
+			matched = append(matched, change)
+		}
+	}
+	return matched
+}
+
+// containsFold reports whether substr is within s, ignoring case
+func containsFold(s, substr string) bool {
+	return strings.Contains(strings.ToLower(s), strings.ToLower(substr))
+}
@@ -0,0 +1,101 @@
+package snapshot
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/tibuski/goAirfocus/airfocus"
+)
+
+// testSnapshot returns a snapshot with one workspace and one group holding the given permissions
+func testSnapshot(at time.Time, workspace, group map[string]string) *airfocus.Snapshot {
+	snap := &airfocus.Snapshot{
+		TakenAt: at,
+		Users: []airfocus.User{
+			{UserID: "u-alice", FullName: "Alice Admin", Email: "alice@example.com"},
+			{UserID: "u-bob", FullName: "Bob Editor", Email: "bob@example.com"},
+		},
+		Workspaces: []airfocus.Workspace{{ID: "w-roadmap", Name: "Roadmap"}},
+		Groups:     []airfocus.WorkspaceGroup{{ID: "g-product", Name: "Product"}},
+	}
+	snap.Workspaces[0].Embedded.Permissions = workspace
+	snap.Groups[0].Embedded.Permissions = group
+	return snap
+}
+
+func TestDiff(t *testing.T) {
+	start := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+	from := testSnapshot(start,
+		map[string]string{"u-alice": "full", "u-bob": "read", "u-gone": "write"},
+		map[string]string{"u-alice": "full"})
+	to := testSnapshot(start.Add(time.Hour),
+		map[string]string{"u-alice": "full", "u-bob": "full"},
+		map[string]string{"u-bob": "comment"})
+
+	got := Diff(from, to)
+	want := []Change{
+		{Kind: Changed, TargetType: "workspace", TargetID: "w-roadmap", TargetName: "Roadmap", UserID: "u-bob", UserName: "Bob Editor", UserEmail: "bob@example.com", From: "read", To: "full"},
+		{Kind: Revoked, TargetType: "workspace", TargetID: "w-roadmap", TargetName: "Roadmap", UserID: "u-gone", UserName: "Unknown User", From: "write"},
+		{Kind: Revoked, TargetType: "group", TargetID: "g-product", TargetName: "Product", UserID: "u-alice", UserName: "Alice Admin", UserEmail: "alice@example.com", From: "full"},
+		{Kind: Granted, TargetType: "group", TargetID: "g-product", TargetName: "Product", UserID: "u-bob", UserName: "Bob Editor", UserEmail: "bob@example.com", To: "comment"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Diff =\n%+v\nwant\n%+v", got, want)
+	}
+
+	if changes := Diff(to, to); len(changes) != 0 {
+		t.Errorf("Diff of a snapshot with itself = %+v, want none", changes)
+	}
+
+	// Default permissions are compared too, with "none" equal to unset
+	quiet := testSnapshot(start.Add(2*time.Hour),
+		map[string]string{"u-alice": "full", "u-bob": "full"},
+		map[string]string{"u-bob": "comment"})
+	quiet.Workspaces[0].DefaultPermission = "full"
+	quiet.Groups[0].DefaultPermission = "none"
+	got = Diff(to, quiet)
+	want = []Change{
+		{Kind: DefaultChanged, TargetType: "workspace", TargetID: "w-roadmap", TargetName: "Roadmap", UserName: "All team members", To: "full"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Diff of default permissions =\n%+v\nwant\n%+v", got, want)
+	}
+	if changes := (Filter{User: "bob"}).Apply(got); len(changes) != 1 {
+		t.Errorf("user filter dropped a default change that affects every user: %+v", changes)
+	}
+
+	// A deleted workspace revokes everything it held
+	empty := testSnapshot(start.Add(2*time.Hour), nil, nil)
+	empty.Workspaces = nil
+	if changes := (Filter{Target: "roadmap"}).Apply(Diff(from, empty)); len(changes) != 3 {
+		t.Errorf("changes on a deleted workspace = %+v, want 3 revocations", changes)
+	}
+}
+
+func TestFilter(t *testing.T) {
+	changes := []Change{
+		{Kind: Granted, TargetType: "workspace", TargetID: "w-roadmap", TargetName: "Roadmap", UserID: "u-bob", UserName: "Bob Editor", UserEmail: "bob@example.com", To: "full"},
+		{Kind: Changed, TargetType: "group", TargetID: "g-product", TargetName: "Product", UserID: "u-alice", UserName: "Alice Admin", UserEmail: "alice@example.com", From: "write", To: "read"},
+	}
+	tests := []struct {
+		name   string
+		filter Filter
+		want   int
+	}{
+		{"empty", Filter{}, 2},
+		{"user by email", Filter{User: "BOB@"}, 1},
+		{"user by ID", Filter{User: "u-alice"}, 1},
+		{"target by name", Filter{Target: "prod"}, 1},
+		{"permission before", Filter{Permission: airfocus.PermissionWrite}, 1},
+		{"permission after", Filter{Permission: airfocus.PermissionFull}, 1},
+		{"combined", Filter{User: "alice", Permission: airfocus.PermissionFull}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.filter.Apply(changes); len(got) != tt.want {
+				t.Errorf("Apply = %+v, want %d changes", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,199 @@
+// Package snapshot keeps snapshots of an Airfocus team on disk, one gzipped
+// JSON file per snapshot, so past states can be compared and served when
+// the API is unavailable.
+package snapshot
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/tibuski/goAirfocus/airfocus"
+)
+
+const (
+	filePrefix = "snapshot-"
+	fileSuffix = ".json.gz"
+	idLayout   = "20060102T150405Z" // Snapshot IDs are their UTC capture time
+)
+
+// ErrNotFound is returned when a requested snapshot does not exist
+var ErrNotFound = errors.New("snapshot not found")
+
+// Info describes a stored snapshot without loading it
+type Info struct {
+	ID      string    // Identifier, the capture time as 20060102T150405Z
+	TakenAt time.Time // When the snapshot was captured
+	Size    int64     // Compressed size in bytes
+}
+
+// Retention limits how many snapshots are kept. Zero values mean no limit.
+type Retention struct {
+	MaxAge   time.Duration // Snapshots older than this are deleted
+	MaxCount int           // At most this many of the newest snapshots are kept
+}
+
+// Store is a directory of snapshots
+type Store struct {
+	dir string
+	now func() time.Time // Clock, overridable for retention logic
+}
+
+// Open returns the store in dir, creating the directory if needed
+func Open(dir string) (*Store, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create snapshot directory: %w", err)
+	}
+	return &Store{dir: dir, now: time.Now}, nil
+}
+
+// Dir returns the directory the store keeps its files in
+func (s *Store) Dir() string {
+	return s.dir
+}
+
+// Save writes a snapshot to disk. The file is written under a temporary
+// name and renamed, so readers never see a partial snapshot.
+func (s *Store) Save(snap *airfocus.Snapshot) (Info, error) {
+	id := snap.TakenAt.UTC().Format(idLayout)
+	tmp, err := os.CreateTemp(s.dir, ".snapshot-*.tmp")
+	if err != nil {
+		return Info{}, fmt.Errorf("failed to create snapshot file: %w", err)
+	}
+	defer os.Remove(tmp.Name()) // No-op once renamed
+
+	gz := gzip.NewWriter(tmp)
+	if err := json.NewEncoder(gz).Encode(snap); err != nil {
+		tmp.Close()
+		return Info{}, fmt.Errorf("failed to write snapshot: %w", err)
+	}
+	if err := gz.Close(); err != nil {
+		tmp.Close()
+		return Info{}, fmt.Errorf("failed to write snapshot: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return Info{}, fmt.Errorf("failed to write snapshot: %w", err)
+	}
+	path := s.path(id)
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return Info{}, fmt.Errorf("failed to store snapshot: %w", err)
+	}
+
+	stat, err := os.Stat(path)
+	if err != nil {
+		return Info{}, fmt.Errorf("failed to store snapshot: %w", err)
+	}
+	return Info{ID: id, TakenAt: snap.TakenAt.UTC().Truncate(time.Second), Size: stat.Size()}, nil
+}
+
+// List returns the stored snapshots, oldest first
+func (s *Store) List() ([]Info, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list snapshots: %w", err)
+	}
+
+	var infos []Info
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, filePrefix) || !strings.HasSuffix(name, fileSuffix) {
+			continue
+		}
+		id := strings.TrimSuffix(strings.TrimPrefix(name, filePrefix), fileSuffix)
+		takenAt, err := time.Parse(idLayout, id)
+		if err != nil {
+			continue // Not one of ours
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue // Removed while listing
+		}
+		infos = append(infos, Info{ID: id, TakenAt: takenAt, Size: info.Size()})
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].TakenAt.Before(infos[j].TakenAt)
+	})
+	return infos, nil
+}
+
+// Load reads the snapshot with the given ID
+func (s *Store) Load(id string) (*airfocus.Snapshot, error) {
+	if _, err := time.Parse(idLayout, id); err != nil {
+		return nil, fmt.Errorf("snapshot %q: %w", id, ErrNotFound)
+	}
+	f, err := os.Open(s.path(id))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("snapshot %s: %w", id, ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open snapshot %s: %w", id, err)
+	}
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read snapshot %s: %w", id, err)
+	}
+	defer gz.Close()
+	var snap airfocus.Snapshot
+	if err := json.NewDecoder(gz).Decode(&snap); err != nil {
+		return nil, fmt.Errorf("failed to decode snapshot %s: %w", id, err)
+	}
+	return &snap, nil
+}
+
+// Latest reads the most recent snapshot
+func (s *Store) Latest() (*airfocus.Snapshot, error) {
+	infos, err := s.List()
+	if err != nil {
+		return nil, err
+	}
+	if len(infos) == 0 {
+		return nil, ErrNotFound
+	}
+	return s.Load(infos[len(infos)-1].ID)
+}
+
+// Fallback returns Latest as a fallback for airfocus.WithFallback
+func (s *Store) Fallback() airfocus.FallbackFunc {
+	return func(context.Context) (*airfocus.Snapshot, error) {
+		return s.Latest()
+	}
+}
+
+// Prune deletes the snapshots the retention does not keep and returns how
+// many were deleted. The newest snapshot is always kept.
+func (s *Store) Prune(retention Retention) (int, error) {
+	infos, err := s.List()
+	if err != nil {
+		return 0, err
+	}
+
+	deleted := 0
+	now := s.now()
+	for i, info := range infos {
+		newest := i == len(infos)-1
+		tooOld := retention.MaxAge > 0 && now.Sub(info.TakenAt) > retention.MaxAge
+		tooMany := retention.MaxCount > 0 && len(infos)-i > retention.MaxCount
+		if newest || !(tooOld || tooMany) {
+			continue
+		}
+		if err := os.Remove(s.path(info.ID)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return deleted, fmt.Errorf("failed to delete snapshot %s: %w", info.ID, err)
+		}
+		deleted++
+	}
+	return deleted, nil
+}
+
+// path returns the file name of the snapshot with the given ID
+func (s *Store) path(id string) string {
+	return filepath.Join(s.dir, filePrefix+id+fileSuffix)
+}
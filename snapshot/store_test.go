@@ -0,0 +1,100 @@
+package snapshot
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/tibuski/goAirfocus/airfocus"
+)
+
+func TestStore(t *testing.T) {
+	store, err := Open(t.TempDir())
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	if _, err := store.Latest(); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Latest of an empty store: %v, want ErrNotFound", err)
+	}
+
+	start := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+	for day := 0; day < 4; day++ {
+		snap := &airfocus.Snapshot{
+			TakenAt: start.AddDate(0, 0, day),
+			Users:   []airfocus.User{{UserID: "u-alice", FullName: "Alice"}},
+		}
+		snap.Team.Name = "Acme"
+		if _, err := store.Save(snap); err != nil {
+			t.Fatalf("Save: %v", err)
+		}
+	}
+
+	infos, err := store.List()
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(infos) != 4 || infos[0].ID != "20240101T090000Z" || infos[3].ID != "20240104T090000Z" {
+		t.Fatalf("List = %+v, want 4 snapshots oldest first", infos)
+	}
+
+	latest, err := store.Latest()
+	if err != nil {
+		t.Fatalf("Latest: %v", err)
+	}
+	if !latest.TakenAt.Equal(start.AddDate(0, 0, 3)) || latest.Team.Name != "Acme" || len(latest.Users) != 1 {
+		t.Errorf("Latest = %+v", latest)
+	}
+	for _, id := range []string{"20230101T000000Z", "../etc/passwd"} {
+		if _, err := store.Load(id); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Load(%q) error = %v, want ErrNotFound", id, err)
+		}
+	}
+}
+
+func TestPrune(t *testing.T) {
+	start := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name      string
+		retention Retention
+		now       time.Time
+		want      []string
+	}{
+		{"unlimited", Retention{}, start, []string{"20240101T090000Z", "20240102T090000Z", "20240103T090000Z"}},
+		{"max count", Retention{MaxCount: 2}, start, []string{"20240102T090000Z", "20240103T090000Z"}},
+		{"max age", Retention{MaxAge: 36 * time.Hour}, start.AddDate(0, 0, 3), []string{"20240103T090000Z"}},
+		{"keeps newest", Retention{MaxAge: time.Hour}, start.AddDate(1, 0, 0), []string{"20240103T090000Z"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store, err := Open(t.TempDir())
+			if err != nil {
+				t.Fatalf("Open: %v", err)
+			}
+			store.now = func() time.Time { return tt.now }
+			for day := 0; day < 3; day++ {
+				if _, err := store.Save(&airfocus.Snapshot{TakenAt: start.AddDate(0, 0, day)}); err != nil {
+					t.Fatalf("Save: %v", err)
+				}
+			}
+
+			if _, err := store.Prune(tt.retention); err != nil {
+				t.Fatalf("Prune: %v", err)
+			}
+			infos, _ := store.List()
+			var got []string
+			for _, info := range infos {
+				got = append(got, info.ID)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("kept %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("kept %v, want %v", got, tt.want)
+					break
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/tibuski/goAirfocus/airfocus"
+	"github.com/tibuski/goAirfocus/snapshot"
+)
+
+const (
+	defaultSnapshotInterval = time.Hour           // How often the server captures the team
+	defaultSnapshotMaxAge   = 90 * 24 * time.Hour // How long snapshots are kept
+)
+
+// snapshotConfig says where snapshots are stored, how often they are taken
+// and how long they are kept
+type snapshotConfig struct {
+	dir       string // Snapshot directory, empty when snapshots are disabled
+	interval  time.Duration
+	retention snapshot.Retention
+}
+
+// snapshotConfigFromEnv reads the snapshot settings: AIRFOCUS_SNAPSHOT_DIR
+// enables snapshots, AIRFOCUS_SNAPSHOT_INTERVAL, AIRFOCUS_SNAPSHOT_MAX_AGE
+// and AIRFOCUS_SNAPSHOT_MAX_COUNT tune them.
+func snapshotConfigFromEnv() (snapshotConfig, error) {
+	config := snapshotConfig{
+		dir:       os.Getenv("AIRFOCUS_SNAPSHOT_DIR"),
+		interval:  defaultSnapshotInterval,
+		retention: snapshot.Retention{MaxAge: defaultSnapshotMaxAge},
+	}
+	for name, target := range map[string]*time.Duration{
+		"AIRFOCUS_SNAPSHOT_INTERVAL": &config.interval,
+		"AIRFOCUS_SNAPSHOT_MAX_AGE":  &config.retention.MaxAge,
+	} {
+		value := os.Getenv(name)
+		if value == "" {
+			continue
+		}
+		d, err := time.ParseDuration(value)
+		if err != nil || d < 0 {
+			return snapshotConfig{}, fmt.Errorf("invalid %s %q: use a Go duration such as 24h", name, value)
+		}
+		*target = d
+	}
+	if config.interval == 0 {
+		return snapshotConfig{}, fmt.Errorf("AIRFOCUS_SNAPSHOT_INTERVAL must be positive")
+	}
+	if value := os.Getenv("AIRFOCUS_SNAPSHOT_MAX_COUNT"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			return snapshotConfig{}, fmt.Errorf("invalid AIRFOCUS_SNAPSHOT_MAX_COUNT %q", value)
+		}
+		config.retention.MaxCount = n
+	}
+	return config, nil
+}
+
+// takeSnapshot captures the team, stores the snapshot and deletes the
+// snapshots the retention no longer keeps
+func takeSnapshot(ctx context.Context, store *snapshot.Store, client *airfocus.Client, retention snapshot.Retention) (snapshot.Info, int, error) {
+	snap, err := client.Snapshot(ctx)
+	if err != nil {
+		return snapshot.Info{}, 0, fmt.Errorf("failed to capture snapshot: %w", err)
+	}
+	info, err := store.Save(snap)
+	if err != nil {
+		return snapshot.Info{}, 0, err
+	}
+	pruned, err := store.Prune(retention)
+	return info, pruned, err
+}
+
+// startSnapshotScheduler takes a snapshot now and then every interval until
+// ctx is cancelled. Failures are logged and retried at the next interval.
+func startSnapshotScheduler(ctx context.Context, store *snapshot.Store, client *airfocus.Client, config snapshotConfig) {
+	run := func() {
+		info, pruned, err := takeSnapshot(ctx, store, client, config.retention)
+		if err != nil {
+			log.Printf("Snapshot failed: %v", err)
+			return
+		}
+		log.Printf("Snapshot %s stored (%d bytes, %d old snapshots deleted)", info.ID, info.Size, pruned)
+	}
+
+	go func() {
+		run()
+		ticker := time.NewTicker(config.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				run()
+			}
+		}
+	}()
+}
@@ -0,0 +1,311 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tibuski/goAirfocus/oidc"
+)
+
+const (
+	ssoStateCookieName = "airfocus_sso_state"
+	ssoLoginTTL        = 10 * time.Minute // How long a started sign-in may take to come back
+)
+
+// SSO signs operators in through an OpenID Connect provider and gives them
+// a session with the API key configured on the server. Only operators whose
+// email domain or groups are on the allow-list are let in.
+type SSO struct {
+	provider       *oidc.Provider
+	apiKey         string
+	allowedDomains []string // Lower-case email domains, e.g. "example.com"
+	allowedGroups  []string // Group names as reported in the "groups" claim
+
+	// trustUnverifiedEmail lets emails in without an email_verified claim,
+	// for providers that only issue verified addresses but never say so
+	trustUnverifiedEmail bool
+
+	mu      sync.Mutex
+	pending map[string]pendingLogin // Started sign-ins by state
+	now     func() time.Time
+}
+
+// pendingLogin is a sign-in that has been sent to the provider
+type pendingLogin struct {
+	nonce    string
+	verifier string
+	started  time.Time
+}
+
+// NewSSO creates an SSO for the provider. At least one allowed domain or group is required.
+func NewSSO(provider *oidc.Provider, apiKey string, allowedDomains, allowedGroups []string) (*SSO, error) {
+	if apiKey == "" {
+		return nil, fmt.Errorf("an API key is required for SSO")
+	}
+	if len(allowedDomains) == 0 && len(allowedGroups) == 0 {
+		return nil, fmt.Errorf("SSO requires at least one allowed email domain or group")
+	}
+	domains := make([]string, len(allowedDomains))
+	for i, domain := range allowedDomains {
+		domains[i] = strings.ToLower(strings.TrimPrefix(domain, "@"))
+	}
+	return &SSO{
+		provider:       provider,
+		apiKey:         apiKey,
+		allowedDomains: domains,
+		allowedGroups:  allowedGroups,
+		pending:        make(map[string]pendingLogin),
+		now:            time.Now,
+	}, nil
+}
+
+// Allowed reports whether the signed in identity may use the tool: its
+// verified email is in an allowed domain, or it belongs to an allowed group.
+// An email the provider does not mark as verified only counts with
+// trustUnverifiedEmail, and never when it is marked unverified.
+func (s *SSO) Allowed(claims oidc.Claims) bool {
+	if s.emailTrusted(claims) {
+		if at := strings.LastIndex(claims.Email, "@"); at >= 0 {
+			domain := strings.ToLower(claims.Email[at+1:])
+			for _, allowed := range s.allowedDomains {
+				if domain == allowed {
+					return true
+				}
+			}
+		}
+	}
+	for _, group := range claims.Groups {
+		for _, allowed := range s.allowedGroups {
+			if group == allowed {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// emailTrusted reports whether the email of claims may be relied on: the
+// provider marks it as verified, or does not say and trustUnverifiedEmail is set
+func (s *SSO) emailTrusted(claims oidc.Claims) bool {
+	if claims.EmailVerified == nil {
+		return s.trustUnverifiedEmail
+	}
+	return *claims.EmailVerified
+}
+
+// begin records a new sign-in and returns its state and the provider URL to redirect to
+func (s *SSO) begin(loginHint string) (string, string, error) {
+	state, err := oidc.RandomString()
+	if err != nil {
+		return "", "", err
+	}
+	nonce, err := oidc.RandomString()
+	if err != nil {
+		return "", "", err
+	}
+	verifier, err := oidc.RandomString()
+	if err != nil {
+		return "", "", err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	for key, login := range s.pending {
+		if now.Sub(login.started) > ssoLoginTTL {
+			delete(s.pending, key)
+		}
+	}
+	s.pending[state] = pendingLogin{nonce: nonce, verifier: verifier, started: now}
+	return state, s.provider.AuthCodeURL(state, nonce, verifier, loginHint), nil
+}
+
+// finish consumes the sign-in with the given state; each state can be used once
+func (s *SSO) finish(state string) (pendingLogin, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	login, ok := s.pending[state]
+	delete(s.pending, state)
+	if !ok || s.now().Sub(login.started) > ssoLoginTTL {
+		return pendingLogin{}, false
+	}
+	return login, true
+}
+
+// handleSSOLogin sends the browser to the identity provider. The state is
+// also stored in a cookie so the callback only completes in the browser
+// that started the sign-in.
+func (s *Server) handleSSOLogin(w http.ResponseWriter, r *http.Request) {
+	if s.sso == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	state, authURL, err := s.sso.begin(r.URL.Query().Get("login_hint"))
+	if err != nil {
+		log.Printf("Failed to start SSO login: %v", err)
+		http.Error(w, "Failed to start sign-in", http.StatusInternalServerError)
+		return
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     ssoStateCookieName,
+		Value:    state,
+		Path:     "/auth/",
+		MaxAge:   int(ssoLoginTTL.Seconds()),
+		HttpOnly: true,
+		// Lax, because the provider redirects back with a cross-site navigation
+		SameSite: http.SameSiteLaxMode,
+		Secure:   r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https",
+	})
+	http.Redirect(w, r, authURL, http.StatusFound)
+}
+
+// handleSSOCallback completes the sign-in: it exchanges the code, verifies
+// the ID token, checks the allow-list and starts a session for the operator.
+func (s *Server) handleSSOCallback(w http.ResponseWriter, r *http.Request) {
+	if s.sso == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	q := r.URL.Query()
+	if errCode := q.Get("error"); errCode != "" {
+		log.Printf("SSO login rejected by provider: %s %s", errCode, q.Get("error_description"))
+		http.Error(w, "Sign-in was cancelled or rejected by the identity provider.", http.StatusUnauthorized)
+		return
+	}
+
+	state := q.Get("state")
+	cookie, err := r.Cookie(ssoStateCookieName)
+	if err != nil || state == "" || cookie.Value != state {
+		http.Error(w, "Sign-in expired or was started in another browser. Please try again.", http.StatusBadRequest)
+		return
+	}
+	http.SetCookie(w, &http.Cookie{Name: ssoStateCookieName, Path: "/auth/", MaxAge: -1})
+	login, ok := s.sso.finish(state)
+	if !ok {
+		http.Error(w, "Sign-in expired. Please try again.", http.StatusBadRequest)
+		return
+	}
+
+	rawIDToken, err := s.sso.provider.Exchange(r.Context(), q.Get("code"), login.verifier)
+	if err != nil {
+		log.Printf("SSO code exchange failed: %v", err)
+		http.Error(w, "Failed to complete sign-in.", http.StatusBadGateway)
+		return
+	}
+	claims, err := s.sso.provider.Verify(r.Context(), rawIDToken, login.nonce)
+	if err != nil {
+		log.Printf("SSO token verification failed: %v", err)
+		http.Error(w, "Failed to complete sign-in.", http.StatusUnauthorized)
+		return
+	}
+	if !s.sso.Allowed(claims) {
+		log.Printf("SSO login denied for %s (groups %v)", claims.Email, claims.Groups)
+		http.Error(w, "Your account is not allowed to use this tool.", http.StatusForbidden)
+		return
+	}
+
+	operator := &Operator{Subject: claims.Subject, Email: claims.Email, EmailVerified: s.sso.emailTrusted(claims), Name: claims.Name, Groups: claims.Groups}
+	id, err := s.sessions.Create(s.sso.apiKey, operator)
+	if err != nil {
+		log.Printf("Failed to start session: %v", err)
+		http.Error(w, "Failed to start session", http.StatusInternalServerError)
+		return
+	}
+	log.Printf("SSO login: %s", operator)
+	setSessionCookie(w, r, id, 0)
+
+	// The session cookie is SameSite=Strict, so browsers would not send it on a
+	// redirect that started at the provider. Navigate from our own page instead.
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write([]byte(`<!DOCTYPE html><html><head><meta http-equiv="refresh" content="0;url=/"></head><body><a href="/">Continue</a></body></html>`))
+}
+
+// envOrFile returns the value of the environment variable name, or the
+// trimmed contents of the file named by name+"_FILE" (e.g. a mounted secret)
+func envOrFile(name string) (string, error) {
+	if value := os.Getenv(name); value != "" {
+		return value, nil
+	}
+	path := os.Getenv(name + "_FILE")
+	if path == "" {
+		return "", nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s_FILE: %w", name, err)
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// splitList splits a comma-separated environment value, dropping empty entries
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// ssoFromEnv configures SSO from the environment. It returns nil when no
+// OIDC issuer is configured, in which case users log in with their own API
+// key. The server-configured key is only used behind OIDC sign-in, so it is
+// never handed out without an allowed identity.
+func ssoFromEnv(ctx context.Context) (*SSO, error) {
+	issuer := os.Getenv("AIRFOCUS_OIDC_ISSUER")
+	if issuer == "" {
+		return nil, nil
+	}
+	apiKey, err := envOrFile("AIRFOCUS_API_KEY")
+	if err != nil {
+		return nil, err
+	}
+	if apiKey == "" {
+		return nil, fmt.Errorf("AIRFOCUS_OIDC_ISSUER is set but neither AIRFOCUS_API_KEY nor AIRFOCUS_API_KEY_FILE is")
+	}
+
+	secret, err := envOrFile("AIRFOCUS_OIDC_CLIENT_SECRET")
+	if err != nil {
+		return nil, err
+	}
+	config := oidc.Config{
+		Issuer:       issuer,
+		ClientID:     os.Getenv("AIRFOCUS_OIDC_CLIENT_ID"),
+		ClientSecret: secret,
+		RedirectURL:  os.Getenv("AIRFOCUS_OIDC_REDIRECT_URL"),
+		Scopes:       []string{"openid", "email", "profile"},
+	}
+	if scopes := os.Getenv("AIRFOCUS_OIDC_SCOPES"); scopes != "" {
+		config.Scopes = strings.Fields(scopes)
+	}
+	if config.ClientID == "" || config.RedirectURL == "" {
+		return nil, fmt.Errorf("AIRFOCUS_OIDC_CLIENT_ID and AIRFOCUS_OIDC_REDIRECT_URL are required for SSO")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+	provider, err := oidc.Discover(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+	sso, err := NewSSO(provider, apiKey, splitList(os.Getenv("AIRFOCUS_ALLOWED_DOMAINS")), splitList(os.Getenv("AIRFOCUS_ALLOWED_GROUPS")))
+	if err != nil {
+		return nil, err
+	}
+	if trust := os.Getenv("AIRFOCUS_OIDC_TRUST_UNVERIFIED_EMAIL"); trust != "" {
+		if sso.trustUnverifiedEmail, err = strconv.ParseBool(trust); err != nil {
+			return nil, fmt.Errorf("AIRFOCUS_OIDC_TRUST_UNVERIFIED_EMAIL: invalid value %q: use true or false", trust)
+		}
+	}
+	return sso, nil
+}
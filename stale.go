@@ -0,0 +1,174 @@
+package main
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/tibuski/goAirfocus/airfocus"
+)
+
+// staleOwnerless is the cleanup category that assigns an owner to ownerless workspaces
+const staleOwnerless = "ownerless"
+
+// staleCategoryTitles names the categories of stale access on the Stale Access section
+var staleCategoryTitles = map[string]string{
+	airfocus.StaleDisabled: "Disabled users",
+	airfocus.StalePending:  "Pending invitations",
+	airfocus.StaleUnseated: "Users without a seat",
+	airfocus.StaleUnknown:  "Unknown users",
+}
+
+// staleCategory is one category of stale grants, as shown on the Stale Access section
+type staleCategory struct {
+	Name  string
+	Title string
+	Rows  []accessChangeRow
+
+	ShowUser bool // Always true; with Applied, lets the rows render with the access_changes template
+	Applied  bool
+	Digest   string // Identifies the listed grants, see staleDigest
+}
+
+// handleStaleAccessHTMX lists the permissions held by disabled, pending,
+// unseated and unknown users, and the workspaces without an active owner
+func (s *Server) handleStaleAccessHTMX(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	client, ok := s.sessionClient(w, r)
+	if !ok {
+		return
+	}
+	report, err := client.StaleAccess(r.Context())
+	if err != nil {
+		s.renderError(w, err, "Failed to find stale access")
+		return
+	}
+	users, err := client.ListUsers(r.Context())
+	if err != nil {
+		s.renderError(w, err, "Failed to retrieve users")
+		return
+	}
+
+	categories := make([]staleCategory, len(airfocus.StaleCategories))
+	for i, name := range airfocus.StaleCategories {
+		categories[i] = staleCategory{Name: name, Title: staleCategoryTitles[name], ShowUser: true, Digest: staleDigest(report, name)}
+		for _, grant := range report.InCategory(name) {
+			categories[i].Rows = append(categories[i].Rows, accessChangeRow{AccessChange: grant.AccessChange, UserName: grant.UserName})
+		}
+	}
+	var owners []airfocus.User
+	for _, user := range users {
+		if airfocus.StaleCategory(user) == "" {
+			owners = append(owners, user)
+		}
+	}
+
+	data := map[string]interface{}{
+		"Categories": categories,
+		"Grants":     len(report.Grants),
+		"Ownerless":  report.Ownerless,
+		"Owners":     owners,
+		"CanCleanup": s.role(r).Allows(RoleAdmin),
+		"Digest":     staleDigest(report, staleOwnerless),
+		"StaleSince": client.StaleSince(),
+	}
+	w.Header().Set("Content-Type", "text/html")
+	if err := s.templates.ExecuteTemplate(w, "stale_access_partial.html", data); err != nil {
+		log.Printf("Error executing template: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	}
+}
+
+// handleStaleCleanupHTMX cleans up one category of stale access: it revokes
+// every grant in the category, or for "ownerless" grants the chosen owner
+// "full" on every ownerless workspace. The report is computed again, and
+// cleaning up is refused if the category no longer holds the access that
+// was listed.
+func (s *Server) handleStaleCleanupHTMX(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	category := r.FormValue("category")
+	ownerID := r.FormValue("owner_id")
+	if _, ok := staleCategoryTitles[category]; !ok && category != staleOwnerless {
+		http.Error(w, "Unknown category", http.StatusBadRequest)
+		return
+	}
+	if category == staleOwnerless && ownerID == "" {
+		http.Error(w, "Owner is required", http.StatusBadRequest)
+		return
+	}
+
+	client, ok := s.sessionClient(w, r)
+	if !ok {
+		return
+	}
+	report, err := client.StaleAccess(r.Context())
+	if err != nil {
+		s.renderError(w, err, "Failed to find stale access")
+		return
+	}
+
+	userNames := make(map[string]string)
+	var changes []airfocus.AccessChange
+	if category == staleOwnerless {
+		owner, err := client.GetUser(r.Context(), ownerID)
+		if err != nil {
+			s.renderError(w, err, "Failed to retrieve owner")
+			return
+		}
+		if airfocus.StaleCategory(owner) != "" {
+			http.Error(w, owner.FullName+" is "+airfocus.StaleCategory(owner)+" and cannot own workspaces", http.StatusBadRequest)
+			return
+		}
+		userNames[owner.UserID] = owner.FullName
+		changes = report.AssignOwner(owner.UserID)
+	} else {
+		for _, grant := range report.InCategory(category) {
+			userNames[grant.UserID] = grant.UserName
+		}
+		changes = report.Revocations(category)
+	}
+	if r.FormValue("plan_digest") != staleDigest(report, category) {
+		s.renderError(w, errPlanChanged, "Stale access changed")
+		return
+	}
+
+	log.Printf("%s cleaning up stale access (%s): %d changes", s.actor(r), category, len(changes))
+
+	results := client.ApplyAccessChanges(r.Context(), changes)
+	rows := make([]accessChangeRow, len(results))
+	for i, result := range results {
+		rows[i] = resultRow(result, userNames[result.UserID])
+	}
+
+	data := map[string]interface{}{
+		"Rows":     rows,
+		"Applied":  true,
+		"Failed":   countFailed(rows),
+		"ShowUser": true,
+	}
+	w.Header().Set("Content-Type", "text/html")
+	if err := s.templates.ExecuteTemplate(w, "stale_cleanup_partial.html", data); err != nil {
+		log.Printf("Error executing template: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	}
+}
+
+// staleDigest identifies what cleaning up a category of report changes: the
+// grants it revokes, or for "ownerless" the workspaces that get an owner
+func staleDigest(report airfocus.StaleAccessReport, category string) string {
+	if category != staleOwnerless {
+		return planDigest(report.Revocations(category))
+	}
+	ids := make([]string, len(report.Ownerless))
+	for i, ws := range report.Ownerless {
+		ids[i] = ws.ID
+	}
+	return planDigest(ids)
+}